@@ -21,12 +21,19 @@ func main() {
 	// Parse CLI flags for configuration
 	cliArgs := cli.ParseConfigFlags()
 
+	// Check guardrail rules against example calls instead of starting the server
+	if cliArgs.TestGuardrails != "" {
+		os.Exit(cli.TestGuardrails(cliArgs.GuardrailsFile, cliArgs.TestGuardrails, os.Stdout))
+	}
+
 	// Load and validate configuration (env vars + CLI overrides)
 	cfg, err := config.LoadConfig(&config.CLIOverrides{
-		URI:       cliArgs.URI,
-		ReadOnly:  cliArgs.ReadOnly,
-		LogLevel:  cliArgs.LogLevel,
-		LogFormat: cliArgs.LogFormat,
+		URI:            cliArgs.URI,
+		ReadOnly:       cliArgs.ReadOnly,
+		LogLevel:       cliArgs.LogLevel,
+		LogFormat:      cliArgs.LogFormat,
+		ProfilesFile:   cliArgs.ProfilesFile,
+		GuardrailsFile: cliArgs.GuardrailsFile,
 	})
 	if err != nil {
 		// Can't use logger here yet, so just print to stderr
@@ -18,6 +18,7 @@ Usage:
 Options:
   -h, --help                          Show this help message
   -v, --version                       Show version information
+  --test-guardrails <file>            Check the example outcome calls in a JSON file against the guardrail rules and exit
   
 
 Required Environment Variables:
@@ -27,8 +28,21 @@ Required Environment Variables:
 Optional Environment Variables:
   URI             URI to Aura API 
   READ_ONLY       Enable read-only mode (default: true)
+  DRY_RUN         Run every outcome that makes changes as a dry run (default: false)
+  ELICITATION_FALLBACK  When the client cannot ask the user to confirm a destructive outcome: confirm, meaning a confirmation token, or refuse (default: confirm)
   LOG_LEVEL       Log level to use (default: Info )
   LOG_FORMAT      Log format to use (defaut: Text )
+  PROFILES_FILE   Path to a JSON file of named instance profiles
+  FLEET_CONCURRENCY  Instances to fetch at once for fleet wide outcomes (default: 8)
+  STATE_DIR       Directory for local state such as baselines
+  SPEC_DIR        Directory desired state spec files may be read from with spec_path ( disabled if not set )
+  REAPER_POLICY   Action on instances whose TTL has expired: pause or delete (default: pause)
+  REAPER_INTERVAL How often to check for expired instances (default: 5m)
+  IDENTITY        Who is using the server, recorded as the owner of new instances (default: OS user name)
+  PROTECTION_FILE Path to a JSON file of instances protected from deletion and overwrite
+  UNPROTECT_SECRET Secret that must be given to unprotect-instance ( unprotecting is disabled if not set )
+  ACCESS_CONTROL_FILE  Path to a JSON file of roles and the identities that have them
+  GUARDRAILS_FILE Path to a JSON file of CEL rules that outcome parameters must pass. Changes are picked up without a restart
 
 Examples:
   # Using environment variables
@@ -41,12 +55,15 @@ For more information, visit: https://github.com/neo4j/mcp
 
 // Args holds configuration values parsed from command-line flags
 type Args struct {
-	URI          string
-	ClientId     string
-	ClientSecret string
-	ReadOnly     string
-	LogLevel     string
-	LogFormat    string
+	URI            string
+	ClientId       string
+	ClientSecret   string
+	ReadOnly       string
+	LogLevel       string
+	LogFormat      string
+	ProfilesFile   string
+	GuardrailsFile string
+	TestGuardrails string
 }
 
 // ParseConfigFlags parses CLI flags and returns configuration values.
@@ -58,16 +75,22 @@ func ParseConfigFlags() *Args {
 	ClientSecret := flag.String("client-secret", "", "Client Secret for Aura API ")
 	LogLevel := flag.String("log-level", "", "Log level to use ( overrides LOG_LEVEL )")
 	LogFormat := flag.String("log-format", "", "Log level to use ( overrides LOG_FORMAT )")
+	ProfilesFile := flag.String("profiles-file", "", "Path to a JSON file of named instance profiles ( overrides PROFILES_FILE )")
+	GuardrailsFile := flag.String("guardrails-file", "", "Path to a JSON file of guardrail rules ( overrides GUARDRAILS_FILE )")
+	TestGuardrails := flag.String("test-guardrails", "", "Path to a JSON file of example outcome calls to check against the guardrail rules")
 
 	flag.Parse()
 
 	return &Args{
-		URI:          *URI,
-		ReadOnly:     *ReadOnly,
-		ClientId:     *ClientId,
-		ClientSecret: *ClientSecret,
-		LogLevel:     *LogLevel,
-		LogFormat:    *LogFormat,
+		URI:            *URI,
+		ReadOnly:       *ReadOnly,
+		ClientId:       *ClientId,
+		ClientSecret:   *ClientSecret,
+		LogLevel:       *LogLevel,
+		LogFormat:      *LogFormat,
+		ProfilesFile:   *ProfilesFile,
+		GuardrailsFile: *GuardrailsFile,
+		TestGuardrails: *TestGuardrails,
 	}
 }
 
@@ -94,7 +117,7 @@ func HandleArgs(version string) {
 			flags["version"] = true
 			i++
 		// Allow configuration flags to be parsed by the flag package
-		case "--uri", "--read-only", "--client-id", "--client-secret", "--log-level", "--log-format":
+		case "--uri", "--read-only", "--client-id", "--client-secret", "--log-level", "--log-format", "--profiles-file", "--guardrails-file", "--test-guardrails":
 			// Check if there's a value following the flag
 			if i+1 >= len(os.Args) {
 				err = fmt.Errorf("%s requires a value", arg)
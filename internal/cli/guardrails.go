@@ -0,0 +1,84 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/LackOfMorals/mcp4AuraAPI/internal/guardrails"
+)
+
+// guardrailCase is an example outcome call to check against the guardrail rules. Expect is
+// 'allow', or the name of the rule that should not allow the call. If it is empty the result
+// is shown but not checked
+type guardrailCase struct {
+	Name string `json:"name"`
+	guardrails.Input
+	Expect string `json:"expect,omitempty"`
+}
+
+// TestGuardrails checks the example calls in casesPath against the rules in rulesPath, or in
+// GUARDRAILS_FILE if rulesPath is empty, and writes the result of each to out. It returns the
+// exit code, which is 1 if the rules are not valid or any call did not get the expected result
+func TestGuardrails(rulesPath, casesPath string, out io.Writer) int {
+	if rulesPath == "" {
+		rulesPath = os.Getenv("GUARDRAILS_FILE")
+	}
+	if rulesPath == "" {
+		fmt.Fprintln(out, "Error: no guardrails file. Set GUARDRAILS_FILE or use --guardrails-file")
+		return 1
+	}
+
+	rules, err := guardrails.Load(rulesPath)
+	if err != nil {
+		fmt.Fprintf(out, "Error: %v\n", err)
+		return 1
+	}
+	fmt.Fprintf(out, "%d rules in %s are valid\n", len(rules.Rules()), rulesPath)
+
+	data, err := os.ReadFile(casesPath)
+	if err != nil {
+		fmt.Fprintf(out, "Error: failed to read test file: %v\n", err)
+		return 1
+	}
+	var cases []guardrailCase
+	if err := json.Unmarshal(data, &cases); err != nil {
+		fmt.Fprintf(out, "Error: failed to parse test file %s: %v\n", casesPath, err)
+		return 1
+	}
+
+	failed := 0
+	for i, c := range cases {
+		name := c.Name
+		if name == "" {
+			name = fmt.Sprintf("#%d", i+1)
+		}
+
+		result, got := "allowed", "allow"
+		if violation := rules.Check(c.Input); violation != nil {
+			result, got = violation.Error(), violation.Rule
+		}
+
+		status := "PASS"
+		switch {
+		case c.Expect == "":
+			status = "----"
+		case c.Expect != got:
+			status = "FAIL"
+			if c.Expect == "allow" {
+				result += ", expected it to be allowed"
+			} else {
+				result += fmt.Sprintf(", expected guardrail '%s' not to allow it", c.Expect)
+			}
+			failed++
+		}
+		fmt.Fprintf(out, "%s %s (%s): %s\n", status, name, c.Outcome, result)
+	}
+
+	fmt.Fprintf(out, "%d of %d tests failed\n", failed, len(cases))
+	if failed > 0 {
+		return 1
+	}
+	return 0
+}
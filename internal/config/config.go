@@ -1,23 +1,261 @@
 package config
 
 import (
+	"bytes"
+	"encoding/json"
 	"fmt"
 	"log"
 	"os"
+	"os/user"
+	"path"
+	"path/filepath"
 	"slices"
+	"sort"
 	"strconv"
+	"strings"
+	"time"
 
+	"github.com/LackOfMorals/mcp4AuraAPI/internal/guardrails"
 	"github.com/LackOfMorals/mcp4AuraAPI/internal/logger"
 )
 
 // Config holds the application configuration
 type Config struct {
-	URI          string // The URL of the Aura API. Default https://api.neo4j.io/v1
-	ClientId     string // Client Id to obtain an token to use with Aura API
-	ClientSecret string // Client Secret to obtain an token to use with Aura API
-	ReadOnly     bool   // Disables tools that would make changes.  True by default
-	LogLevel     string // Logging level to use.  Default  Info
-	LogFormat    string //  Log format to use. Default Text
+	URI                 string        // The URL of the Aura API. Default https://api.neo4j.io/v1
+	ClientId            string        // Client Id to obtain an token to use with Aura API
+	ClientSecret        string        // Client Secret to obtain an token to use with Aura API
+	ReadOnly            bool          // Disables tools that would make changes.  True by default
+	DryRun              bool          // Runs every outcome that makes changes as a dry run. False by default
+	ElicitationFallback string        // What to do with a destructive outcome when the client cannot ask the user to confirm it, confirm or refuse. Default confirm
+	LogLevel            string        // Logging level to use.  Default  Info
+	LogFormat           string        //  Log format to use. Default Text
+	ProfilesFile        string        // Path to a JSON file of named instance profiles. Optional
+	FleetConcurrency    int           // How many instances to fetch at once for fleet wide outcomes. Default 8
+	StateDir            string        // Directory for local state such as baselines. Default is mcp-aura-infra-mgr in the user config directory
+	SpecDir             string        // Directory desired state spec files may be read from. Reading spec files is disabled if not set
+	ReaperPolicy        string        // What to do with an instance when its TTL expires, pause or delete. Default pause
+	ReaperInterval      time.Duration // How often to check for expired instances. Default 5 minutes
+	Identity            string        // Who is calling the server, recorded as the owner of instances it creates. Default is the OS user name
+	ProtectionFile      string        // Path to a JSON file of instances protected from deletion and overwrite. Optional
+	UnprotectSecret     string        // Secret that must be given to unprotect-instance. Unprotecting is disabled if not set
+	AccessControlFile   string        // Path to a JSON file of roles and the identities that have them. Optional, everyone may run everything if not set
+	GuardrailsFile      string        // Path to a JSON file of CEL rules the parameters of every outcome must pass. Optional
+	Profiles            map[string]InstanceProfile
+	Protection          DeletionProtection
+	AccessControl       *AccessControl
+	Guardrails          *guardrails.Watcher
+}
+
+// AccessControl gives each identity a set of roles that say which outcomes it may run
+type AccessControl struct {
+	Roles        map[string]Role     `json:"roles"`
+	Identities   map[string][]string `json:"identities"`              // Role names for each identity
+	DefaultRoles []string            `json:"default_roles,omitempty"` // Roles for any identity not listed. None if empty
+}
+
+// Role is a set of outcomes, given by ID or by category, optionally limited to some tenants
+type Role struct {
+	Outcomes   []string `json:"outcomes,omitempty"`   // Outcome IDs, or '*' for every outcome
+	Categories []string `json:"categories,omitempty"` // Outcome categories such as 'instances'
+	Tenants    []string `json:"tenants,omitempty"`    // If set, the outcomes may only be run against these tenants
+}
+
+// Validate checks that every role an identity is given exists
+func (a *AccessControl) Validate() error {
+	for name, role := range a.Roles {
+		if len(role.Outcomes) == 0 && len(role.Categories) == 0 {
+			return fmt.Errorf("role '%s' does not allow any outcomes or categories", name)
+		}
+	}
+	check := func(roles []string, owner string) error {
+		for _, role := range roles {
+			if _, ok := a.Roles[role]; !ok {
+				return fmt.Errorf("%s has role '%s' which is not defined", owner, role)
+			}
+		}
+		return nil
+	}
+	for identity, roles := range a.Identities {
+		if err := check(roles, fmt.Sprintf("identity '%s'", identity)); err != nil {
+			return err
+		}
+	}
+	return check(a.DefaultRoles, "default_roles")
+}
+
+// LoadAccessControl reads roles and identities from a JSON file
+func LoadAccessControl(path string) (*AccessControl, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read access control file: %w", err)
+	}
+
+	accessControl := &AccessControl{}
+	if err := json.Unmarshal(data, accessControl); err != nil {
+		return nil, fmt.Errorf("failed to parse access control file %s: %w", path, err)
+	}
+	if err := accessControl.Validate(); err != nil {
+		return nil, fmt.Errorf("access control file %s is invalid: %w", path, err)
+	}
+	return accessControl, nil
+}
+
+// DeletionProtection lists the instances that cannot be deleted or overwritten. An instance
+// is protected if it matches any entry
+type DeletionProtection struct {
+	InstanceIds  []string `json:"instance_ids,omitempty"`
+	NamePatterns []string `json:"name_patterns,omitempty"` // Glob patterns such as 'prod-*'
+	Labels       []string `json:"labels,omitempty"`        // Labels such as 'env=prod', or a label name on its own to match any value
+}
+
+// Validate checks that every entry can be used
+func (p DeletionProtection) Validate() error {
+	for _, id := range p.InstanceIds {
+		if id == "" {
+			return fmt.Errorf("instance_ids contains an empty ID")
+		}
+	}
+	for _, pattern := range p.NamePatterns {
+		if _, err := path.Match(pattern, ""); err != nil || pattern == "" {
+			return fmt.Errorf("name pattern '%s' is not a valid glob pattern", pattern)
+		}
+	}
+	for _, label := range p.Labels {
+		if key, _, _ := strings.Cut(label, "="); strings.TrimSpace(key) == "" {
+			return fmt.Errorf("label '%s' must be a label name, optionally followed by =value", label)
+		}
+	}
+	return nil
+}
+
+// LoadProtection reads the deletion protection list from a JSON file
+func LoadProtection(path string) (DeletionProtection, error) {
+	var protection DeletionProtection
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return protection, fmt.Errorf("failed to read protection file: %w", err)
+	}
+	if err := json.Unmarshal(data, &protection); err != nil {
+		return protection, fmt.Errorf("failed to parse protection file %s: %w", path, err)
+	}
+	if err := protection.Validate(); err != nil {
+		return protection, fmt.Errorf("protection file %s is invalid: %w", path, err)
+	}
+	return protection, nil
+}
+
+// InstanceProfile is a named instance configuration that can be used when creating an instance
+// instead of supplying every parameter
+type InstanceProfile struct {
+	TenantId      string `json:"tenant_id"`
+	CloudProvider string `json:"cloud_provider"`
+	Region        string `json:"region"`
+	Memory        string `json:"memory"`
+	Type          string `json:"type"`
+	Version       string `json:"version,omitempty"`
+}
+
+// Validate checks that a profile has everything needed to create an instance
+func (p InstanceProfile) Validate() error {
+	required := []struct {
+		value string
+		name  string
+	}{
+		{p.TenantId, "tenant_id"},
+		{p.CloudProvider, "cloud_provider"},
+		{p.Region, "region"},
+		{p.Memory, "memory"},
+		{p.Type, "type"},
+	}
+
+	for _, v := range required {
+		if v.value == "" {
+			return fmt.Errorf("%s is required but was empty", v.name)
+		}
+	}
+
+	if !slices.Contains(ValidCloudProviders, p.CloudProvider) {
+		return fmt.Errorf("invalid cloud_provider '%s'. Valid values: %v", p.CloudProvider, ValidCloudProviders)
+	}
+
+	if !slices.Contains(ValidInstanceTypes, p.Type) {
+		return fmt.Errorf("invalid type '%s'. Valid values: %v", p.Type, ValidInstanceTypes)
+	}
+
+	if !slices.Contains(ValidMemorySizes, p.Memory) {
+		return fmt.Errorf("invalid memory '%s'. Valid values: %v", p.Memory, ValidMemorySizes)
+	}
+
+	if p.Version != "" && !slices.Contains(ValidVersions, p.Version) {
+		return fmt.Errorf("invalid version '%s'. Valid values: %v", p.Version, ValidVersions)
+	}
+
+	return nil
+}
+
+// ValidCloudProviders lists the cloud providers an instance can be created in
+var ValidCloudProviders = []string{"gcp", "aws", "azure"}
+
+// ValidElicitationFallbacks lists what can be done with a destructive outcome when the client
+// cannot ask the user to confirm it. confirm uses the confirmation token flow, which replaced the
+// confirm parameter destructive outcomes used to take
+var ValidElicitationFallbacks = []string{"confirm", "refuse"}
+
+// ValidReaperPolicies lists what can be done with an instance when its TTL expires
+var ValidReaperPolicies = []string{"pause", "delete"}
+
+// ValidMemorySizes lists the memory sizes an instance can have
+var ValidMemorySizes = []string{
+	"1GB", "2GB", "4GB", "8GB", "16GB", "24GB", "32GB", "48GB", "64GB", "128GB", "192GB", "256GB", "384GB", "512GB",
+}
+
+// ValidStorageSizes lists the storage sizes an instance can have
+var ValidStorageSizes = []string{
+	"2GB", "4GB", "8GB", "16GB", "32GB", "48GB", "64GB", "96GB", "128GB", "192GB", "256GB", "384GB", "512GB",
+	"768GB", "1024GB", "1536GB", "2048GB",
+}
+
+// ValidVersions lists the Neo4j versions an instance can be created with
+var ValidVersions = []string{"4", "5"}
+
+// ValidInstanceTypes lists the types of instance that can be created
+var ValidInstanceTypes = []string{"enterprise-db", "enterprise-ds", "professional-db", "professional-ds", "free-db", "business-critical"}
+
+// LoadProfiles reads named instance profiles from a JSON file. The file holds an object
+// keyed by profile name. Every profile is validated and the first problem found is returned.
+// Fields a profile does not have, such as storage which Aura sets from memory, are refused
+// rather than ignored
+func LoadProfiles(path string) (map[string]InstanceProfile, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read profiles file: %w", err)
+	}
+
+	profiles := make(map[string]InstanceProfile)
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&profiles); err != nil {
+		return nil, fmt.Errorf("failed to parse profiles file %s: %w", path, err)
+	}
+
+	// Check in name order so the same file always reports the same error
+	names := make([]string, 0, len(profiles))
+	for name := range profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		if name == "" {
+			return nil, fmt.Errorf("profiles file %s contains a profile with an empty name", path)
+		}
+		if err := profiles[name].Validate(); err != nil {
+			return nil, fmt.Errorf("profile '%s' in %s is invalid: %w", name, path, err)
+		}
+	}
+
+	return profiles, nil
 }
 
 // Validate validates the configuration and returns an error if invalid
@@ -45,10 +283,12 @@ func (c *Config) Validate() error {
 
 // CLIOverrides holds optional configuration values from CLI flags
 type CLIOverrides struct {
-	URI       string
-	ReadOnly  string
-	LogLevel  string
-	LogFormat string
+	URI            string
+	ReadOnly       string
+	LogLevel       string
+	LogFormat      string
+	ProfilesFile   string
+	GuardrailsFile string
 }
 
 // LoadConfig loads configuration from environment variables, applies CLI overrides, and validates.
@@ -63,7 +303,67 @@ func LoadConfig(cliOverrides *CLIOverrides) (*Config, error) {
 	logLevel := GetEnvWithDefault("LOG_LEVEL", "info")
 	logFormat := GetEnvWithDefault("LOG_FORMAT", "text")
 	readOnly := GetEnvWithDefault("READ_ONLY", "true")
+	dryRun := GetEnvWithDefault("DRY_RUN", "false")
+	elicitationFallback := GetEnvWithDefault("ELICITATION_FALLBACK", "confirm")
 	uri := GetEnvWithDefault("URI", "https://api.neo4j.io/v1")
+	profilesFile := GetEnv("PROFILES_FILE")
+	fleetConcurrency := ParseInt32(GetEnv("FLEET_CONCURRENCY"), 8)
+	stateDir := GetEnvWithDefault("STATE_DIR", defaultStateDir())
+	specDir := GetEnv("SPEC_DIR")
+	reaperPolicy := GetEnvWithDefault("REAPER_POLICY", "pause")
+	reaperInterval := GetEnvWithDefault("REAPER_INTERVAL", "5m")
+	identity := GetEnvWithDefault("IDENTITY", defaultIdentity())
+	protectionFile := GetEnv("PROTECTION_FILE")
+	unprotectSecret := GetEnv("UNPROTECT_SECRET")
+	accessControlFile := GetEnv("ACCESS_CONTROL_FILE")
+	guardrailsFile := GetEnv("GUARDRAILS_FILE")
+
+	// Apply CLI overrides
+	if cliOverrides != nil {
+		if cliOverrides.URI != "" {
+			uri = cliOverrides.URI
+		}
+		if cliOverrides.ReadOnly != "" {
+			readOnly = cliOverrides.ReadOnly
+		}
+		if cliOverrides.LogLevel != "" {
+			logLevel = cliOverrides.LogLevel
+		}
+		if cliOverrides.LogFormat != "" {
+			logFormat = cliOverrides.LogFormat
+		}
+		if cliOverrides.ProfilesFile != "" {
+			profilesFile = cliOverrides.ProfilesFile
+		}
+		if cliOverrides.GuardrailsFile != "" {
+			guardrailsFile = cliOverrides.GuardrailsFile
+		}
+	}
+
+	// Validate fleet concurrency and use default if invalid
+	if fleetConcurrency < 1 {
+		fmt.Fprintf(os.Stderr, "Warning: invalid FLEET_CONCURRENCY '%d', using default 8. Must be at least 1\n", fleetConcurrency)
+		fleetConcurrency = 8
+	}
+
+	// Validate elicitation fallback and use default if invalid
+	if !slices.Contains(ValidElicitationFallbacks, elicitationFallback) {
+		fmt.Fprintf(os.Stderr, "Warning: invalid ELICITATION_FALLBACK '%s', using default 'confirm'. Valid values: %v\n", elicitationFallback, ValidElicitationFallbacks)
+		elicitationFallback = "confirm"
+	}
+
+	// Validate reaper policy and use default if invalid
+	if !slices.Contains(ValidReaperPolicies, reaperPolicy) {
+		fmt.Fprintf(os.Stderr, "Warning: invalid REAPER_POLICY '%s', using default 'pause'. Valid values: %v\n", reaperPolicy, ValidReaperPolicies)
+		reaperPolicy = "pause"
+	}
+
+	// Validate reaper interval and use default if invalid
+	parsedReaperInterval, err := time.ParseDuration(reaperInterval)
+	if err != nil || parsedReaperInterval < time.Minute {
+		fmt.Fprintf(os.Stderr, "Warning: invalid REAPER_INTERVAL '%s', using default '5m'. Must be a duration of at least 1m\n", reaperInterval)
+		parsedReaperInterval = 5 * time.Minute
+	}
 
 	// Validate log level and use default if invalid
 	if !slices.Contains(logger.ValidLogLevels, logLevel) {
@@ -78,12 +378,26 @@ func LoadConfig(cliOverrides *CLIOverrides) (*Config, error) {
 	}
 
 	cfg := &Config{
-		URI:          uri,
-		ReadOnly:     ParseBool(readOnly, true),
-		LogLevel:     logLevel,
-		LogFormat:    logFormat,
-		ClientId:     clientId,
-		ClientSecret: clientSecret,
+		URI:                 uri,
+		ReadOnly:            ParseBool(readOnly, true),
+		DryRun:              ParseBool(dryRun, false),
+		ElicitationFallback: elicitationFallback,
+		LogLevel:            logLevel,
+		LogFormat:           logFormat,
+		ClientId:            clientId,
+		ClientSecret:        clientSecret,
+		ProfilesFile:        profilesFile,
+		Profiles:            map[string]InstanceProfile{},
+		FleetConcurrency:    int(fleetConcurrency),
+		StateDir:            stateDir,
+		SpecDir:             specDir,
+		ReaperPolicy:        reaperPolicy,
+		ReaperInterval:      parsedReaperInterval,
+		Identity:            identity,
+		ProtectionFile:      protectionFile,
+		UnprotectSecret:     unprotectSecret,
+		AccessControlFile:   accessControlFile,
+		GuardrailsFile:      guardrailsFile,
 	}
 
 	// Validate configuration
@@ -91,9 +405,64 @@ func LoadConfig(cliOverrides *CLIOverrides) (*Config, error) {
 		return nil, err
 	}
 
+	// Load instance profiles if a file was given. Invalid profiles stop the server from starting
+	if profilesFile != "" {
+		profiles, err := LoadProfiles(profilesFile)
+		if err != nil {
+			return nil, err
+		}
+		cfg.Profiles = profiles
+	}
+
+	// Load the deletion protection list if a file was given. An invalid list stops the server from starting
+	if protectionFile != "" {
+		protection, err := LoadProtection(protectionFile)
+		if err != nil {
+			return nil, err
+		}
+		cfg.Protection = protection
+	}
+
+	// Load roles if a file was given. Invalid roles stop the server from starting
+	if accessControlFile != "" {
+		accessControl, err := LoadAccessControl(accessControlFile)
+		if err != nil {
+			return nil, err
+		}
+		cfg.AccessControl = accessControl
+	}
+
+	// Load guardrails if a file was given. Invalid rules stop the server from starting, but
+	// later changes to the file are picked up while it runs
+	if guardrailsFile != "" {
+		watcher, err := guardrails.Watch(guardrailsFile)
+		if err != nil {
+			return nil, err
+		}
+		cfg.Guardrails = watcher
+	}
+
 	return cfg, nil
 }
 
+// defaultStateDir returns the directory used for local state when STATE_DIR is not set
+func defaultStateDir() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ".mcp-aura-infra-mgr"
+	}
+	return filepath.Join(dir, "mcp-aura-infra-mgr")
+}
+
+// defaultIdentity returns the identity used when IDENTITY is not set
+func defaultIdentity() string {
+	u, err := user.Current()
+	if err != nil || u.Username == "" {
+		return "unknown"
+	}
+	return u.Username
+}
+
 // GetEnv returns the value of an environment variable or empty string if not set
 func GetEnv(key string) string {
 	return os.Getenv(key)
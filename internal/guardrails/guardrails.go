@@ -0,0 +1,269 @@
+// Package guardrails checks the parameters of an outcome against rules written as CEL
+// expressions, such as "memory must be 16GB or less" or "regions must start with eu-", before
+// the outcome is run. Rules are read from a JSON file that is read again whenever it changes
+package guardrails
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/cel-go/cel"
+	"github.com/google/cel-go/common/types"
+	"github.com/google/cel-go/common/types/ref"
+	"github.com/google/cel-go/ext"
+)
+
+// Rule is a CEL expression that must be true for an outcome to be run
+type Rule struct {
+	Name       string   `json:"name"`
+	Outcomes   []string `json:"outcomes,omitempty"` // Outcome IDs the rule applies to. Every outcome if empty
+	Expression string   `json:"expression"`
+	Message    string   `json:"message,omitempty"` // Explains the rule to the caller when it is broken
+}
+
+// Input is what a rule can see about an outcome call
+type Input struct {
+	Outcome    string                 `json:"outcome"`
+	Parameters map[string]interface{} `json:"parameters"`
+	Identity   string                 `json:"identity"`
+	Tenant     string                 `json:"tenant"`   // The tenant the outcome acts on, or empty if it is not known
+	Instance   map[string]interface{} `json:"instance"` // The current details of the instance the outcome acts on, or empty if there is none
+}
+
+// Violation is a rule that does not allow an outcome call
+type Violation struct {
+	Rule    string
+	Message string
+}
+
+func (v *Violation) Error() string {
+	return fmt.Sprintf("guardrail '%s' does not allow this: %s", v.Rule, v.Message)
+}
+
+// compiledRule is a rule ready to be evaluated
+type compiledRule struct {
+	Rule
+	program cel.Program
+}
+
+// Guardrails is a set of rules, evaluated in the order they are given in the file
+type Guardrails struct {
+	rules []compiledRule
+}
+
+// newEnv returns the CEL environment rules are compiled in. These variables are available
+//
+//	outcome   the outcome ID, such as 'create-instance'
+//	params    the parameters of the call, with any profile already applied
+//	identity  who is calling
+//	tenant    the tenant the outcome acts on, or '' if it is not known
+//	instance  the current details of the instance the outcome acts on, or {} if there is none
+//
+// along with the CEL string extensions and gb(), which turns a size such as '16GB' into 16
+func newEnv() (*cel.Env, error) {
+	return cel.NewEnv(
+		cel.Variable("outcome", cel.StringType),
+		cel.Variable("params", cel.MapType(cel.StringType, cel.DynType)),
+		cel.Variable("identity", cel.StringType),
+		cel.Variable("tenant", cel.StringType),
+		cel.Variable("instance", cel.MapType(cel.StringType, cel.DynType)),
+		cel.CrossTypeNumericComparisons(true),
+		ext.Strings(),
+		cel.Function("gb",
+			cel.Overload("gb_string", []*cel.Type{cel.StringType}, cel.IntType,
+				cel.UnaryBinding(func(value ref.Val) ref.Val {
+					size := strings.TrimSuffix(strings.ToUpper(string(value.(types.String))), "GB")
+					gb, err := strconv.Atoi(size)
+					if err != nil {
+						return types.NewErr("gb: '%s' is not a size in GB such as '16GB'", value)
+					}
+					return types.Int(gb)
+				}),
+			),
+		),
+	)
+}
+
+// Parse compiles the rules in a guardrails file. Every rule must have a unique name and an
+// expression that gives a bool. The first problem found is returned
+func Parse(data []byte) (*Guardrails, error) {
+	var file struct {
+		Rules []Rule `json:"rules"`
+	}
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, err
+	}
+
+	env, err := newEnv()
+	if err != nil {
+		return nil, fmt.Errorf("failed to set up CEL: %w", err)
+	}
+
+	guardrails := &Guardrails{}
+	names := map[string]bool{}
+	for i, rule := range file.Rules {
+		if rule.Name == "" {
+			return nil, fmt.Errorf("rule %d has no name", i+1)
+		}
+		if names[rule.Name] {
+			return nil, fmt.Errorf("there is more than one rule named '%s'", rule.Name)
+		}
+		names[rule.Name] = true
+
+		ast, issues := env.Compile(rule.Expression)
+		if issues != nil && issues.Err() != nil {
+			return nil, fmt.Errorf("rule '%s' is not a valid CEL expression: %w", rule.Name, issues.Err())
+		}
+		if ast.OutputType() != cel.BoolType && ast.OutputType() != cel.DynType {
+			return nil, fmt.Errorf("rule '%s' must give a bool, not %s", rule.Name, ast.OutputType())
+		}
+		program, err := env.Program(ast)
+		if err != nil {
+			return nil, fmt.Errorf("rule '%s' cannot be run: %w", rule.Name, err)
+		}
+
+		if rule.Message == "" {
+			rule.Message = fmt.Sprintf("%s is not true", rule.Expression)
+		}
+		guardrails.rules = append(guardrails.rules, compiledRule{Rule: rule, program: program})
+	}
+
+	return guardrails, nil
+}
+
+// Load reads and compiles the rules in a guardrails file
+func Load(path string) (*Guardrails, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read guardrails file: %w", err)
+	}
+	guardrails, err := Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("guardrails file %s is invalid: %w", path, err)
+	}
+	return guardrails, nil
+}
+
+// Rules returns the rules, in the order they are evaluated
+func (g *Guardrails) Rules() []Rule {
+	rules := make([]Rule, 0, len(g.rules))
+	for _, rule := range g.rules {
+		rules = append(rules, rule.Rule)
+	}
+	return rules
+}
+
+// AppliesTo returns true if any rule applies to outcomeID
+func (g *Guardrails) AppliesTo(outcomeID string) bool {
+	return slices.ContainsFunc(g.rules, func(rule compiledRule) bool {
+		return rule.appliesTo(outcomeID)
+	})
+}
+
+func (r compiledRule) appliesTo(outcomeID string) bool {
+	return len(r.Outcomes) == 0 || slices.Contains(r.Outcomes, outcomeID)
+}
+
+// Check returns the first rule that does not allow the call, or nil if every rule allows it.
+// A rule that cannot be evaluated, such as one that reads a parameter that was not given
+// without checking for it with has(), does not allow the call
+func (g *Guardrails) Check(input Input) *Violation {
+	parameters := input.Parameters
+	if parameters == nil {
+		parameters = map[string]interface{}{}
+	}
+	instance := input.Instance
+	if instance == nil {
+		instance = map[string]interface{}{}
+	}
+	activation := map[string]interface{}{
+		"outcome":  input.Outcome,
+		"params":   parameters,
+		"identity": input.Identity,
+		"tenant":   input.Tenant,
+		"instance": instance,
+	}
+
+	for _, rule := range g.rules {
+		if !rule.appliesTo(input.Outcome) {
+			continue
+		}
+		result, _, err := rule.program.Eval(activation)
+		if err != nil {
+			return &Violation{Rule: rule.Name, Message: fmt.Sprintf("the rule could not be evaluated: %v", err)}
+		}
+		allowed, ok := result.Value().(bool)
+		if !ok {
+			return &Violation{Rule: rule.Name, Message: fmt.Sprintf("the rule gave %v, not a bool", result.Value())}
+		}
+		if !allowed {
+			return &Violation{Rule: rule.Name, Message: rule.Message}
+		}
+	}
+	return nil
+}
+
+// Watcher keeps the rules in a guardrails file up to date. The file is read again the first
+// time the rules are needed after it has changed. If the changed file is not valid the rules
+// from before are kept
+type Watcher struct {
+	path string
+
+	mu         sync.Mutex
+	guardrails *Guardrails
+	modTime    time.Time
+	size       int64
+}
+
+// Watch reads the rules in a guardrails file and returns a watcher that keeps them up to date.
+// An error is returned if the file cannot be read or is not valid
+func Watch(path string) (*Watcher, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read guardrails file: %w", err)
+	}
+	guardrails, err := Load(path)
+	if err != nil {
+		return nil, err
+	}
+	return &Watcher{path: path, guardrails: guardrails, modTime: info.ModTime(), size: info.Size()}, nil
+}
+
+// Path returns the path of the guardrails file
+func (w *Watcher) Path() string {
+	return w.path
+}
+
+// Current returns the rules, reading the file again first if it has changed
+func (w *Watcher) Current() *Guardrails {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	info, err := os.Stat(w.path)
+	if err != nil {
+		slog.Warn("Cannot read guardrails file, keeping the rules already loaded", "path", w.path, "error", err)
+		return w.guardrails
+	}
+	if info.ModTime().Equal(w.modTime) && info.Size() == w.size {
+		return w.guardrails
+	}
+
+	// Only try each version of the file once, so an invalid file is not reported on every call
+	w.modTime, w.size = info.ModTime(), info.Size()
+	guardrails, err := Load(w.path)
+	if err != nil {
+		slog.Error("Guardrails file changed but is not valid, keeping the rules already loaded", "path", w.path, "error", err)
+		return w.guardrails
+	}
+
+	slog.Info("Reloaded guardrails", "path", w.path, "rules", len(guardrails.rules))
+	w.guardrails = guardrails
+	return w.guardrails
+}
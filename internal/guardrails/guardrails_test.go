@@ -0,0 +1,204 @@
+package guardrails
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name      string
+		file      string
+		wantRules int
+		wantErr   string
+	}{
+		{name: "no rules", file: `{"rules": []}`},
+		{name: "valid rules", file: `{"rules": [
+			{"name": "max-memory", "outcomes": ["create-instance"], "expression": "gb(params.memory) <= 16"},
+			{"name": "eu-only", "expression": "!has(params.region) || params.region.startsWith('eu-')"}
+		]}`, wantRules: 2},
+		{name: "dynamic result", file: `{"rules": [{"name": "flag", "expression": "params.allowed"}]}`, wantRules: 1},
+		{name: "not json", file: `rules:`, wantErr: "invalid character"},
+		{name: "missing name", file: `{"rules": [{"expression": "true"}]}`, wantErr: "rule 1 has no name"},
+		{name: "duplicate name", file: `{"rules": [{"name": "a", "expression": "true"}, {"name": "a", "expression": "false"}]}`, wantErr: "more than one rule named 'a'"},
+		{name: "invalid expression", file: `{"rules": [{"name": "a", "expression": "params.memory <="}]}`, wantErr: "not a valid CEL expression"},
+		{name: "unknown variable", file: `{"rules": [{"name": "a", "expression": "nope == 1"}]}`, wantErr: "not a valid CEL expression"},
+		{name: "not a bool", file: `{"rules": [{"name": "a", "expression": "outcome + 'x'"}]}`, wantErr: "must give a bool"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			guardrails, err := Parse([]byte(tt.file))
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Parse() error = %v, want one containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+			if got := len(guardrails.Rules()); got != tt.wantRules {
+				t.Fatalf("Parse() gave %d rules, want %d", got, tt.wantRules)
+			}
+		})
+	}
+}
+
+func TestCheck(t *testing.T) {
+	guardrails, err := Parse([]byte(`{"rules": [
+		{"name": "max-memory", "outcomes": ["create-instance", "update-instance"], "expression": "!has(params.memory) || gb(params.memory) <= 16 || tenant == 'big'", "message": "Memory must be 16GB or less"},
+		{"name": "eu-only", "outcomes": ["create-instance"], "expression": "params.region.startsWith('eu-')"},
+		{"name": "keep-prod", "outcomes": ["delete-instance"], "expression": "!has(instance.labels) || !has(instance.labels.env) || instance.labels.env != 'prod' || identity == 'admin'"}
+	]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name        string
+		input       Input
+		wantRule    string // Empty if the call is allowed
+		wantMessage string
+	}{
+		{name: "allowed", input: Input{Outcome: "create-instance", Parameters: map[string]interface{}{"memory": "8GB", "region": "eu-west-1"}}},
+		{name: "too much memory", input: Input{Outcome: "create-instance", Parameters: map[string]interface{}{"memory": "32GB", "region": "eu-west-1"}}, wantRule: "max-memory", wantMessage: "Memory must be 16GB or less"},
+		{name: "lower case size", input: Input{Outcome: "update-instance", Parameters: map[string]interface{}{"memory": "32gb"}}, wantRule: "max-memory"},
+		{name: "allowed by tenant", input: Input{Outcome: "create-instance", Tenant: "big", Parameters: map[string]interface{}{"memory": "32GB", "region": "eu-west-1"}}},
+		{name: "first rule broken is reported", input: Input{Outcome: "create-instance", Parameters: map[string]interface{}{"memory": "32GB", "region": "us-east-1"}}, wantRule: "max-memory"},
+		{name: "default message", input: Input{Outcome: "create-instance", Parameters: map[string]interface{}{"region": "us-east-1"}}, wantRule: "eu-only", wantMessage: "params.region.startsWith('eu-') is not true"},
+		{name: "missing parameter cannot be evaluated", input: Input{Outcome: "create-instance", Parameters: map[string]interface{}{}}, wantRule: "eu-only", wantMessage: "could not be evaluated"},
+		{name: "invalid size cannot be evaluated", input: Input{Outcome: "update-instance", Parameters: map[string]interface{}{"memory": "lots"}}, wantRule: "max-memory", wantMessage: "could not be evaluated"},
+		{name: "rule for another outcome", input: Input{Outcome: "pause-instance", Parameters: map[string]interface{}{"memory": "64GB"}}},
+		{name: "nil parameters and instance", input: Input{Outcome: "delete-instance"}},
+		{name: "protected instance", input: Input{Outcome: "delete-instance", Identity: "dev", Instance: map[string]interface{}{"labels": map[string]interface{}{"env": "prod"}}}, wantRule: "keep-prod"},
+		{name: "protected instance by admin", input: Input{Outcome: "delete-instance", Identity: "admin", Instance: map[string]interface{}{"labels": map[string]interface{}{"env": "prod"}}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			violation := guardrails.Check(tt.input)
+			if tt.wantRule == "" {
+				if violation != nil {
+					t.Fatalf("Check() = %v, want it to be allowed", violation)
+				}
+				return
+			}
+			if violation == nil || violation.Rule != tt.wantRule {
+				t.Fatalf("Check() = %v, want rule '%s' not to allow it", violation, tt.wantRule)
+			}
+			if !strings.Contains(violation.Message, tt.wantMessage) {
+				t.Fatalf("Check() message = %q, want one containing %q", violation.Message, tt.wantMessage)
+			}
+		})
+	}
+}
+
+func TestAppliesTo(t *testing.T) {
+	guardrails, err := Parse([]byte(`{"rules": [{"name": "a", "outcomes": ["create-instance"], "expression": "true"}]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	everything, err := Parse([]byte(`{"rules": [{"name": "a", "expression": "true"}]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name       string
+		guardrails *Guardrails
+		outcome    string
+		want       bool
+	}{
+		{name: "listed outcome", guardrails: guardrails, outcome: "create-instance", want: true},
+		{name: "other outcome", guardrails: guardrails, outcome: "delete-instance", want: false},
+		{name: "rule for every outcome", guardrails: everything, outcome: "delete-instance", want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.guardrails.AppliesTo(tt.outcome); got != tt.want {
+				t.Fatalf("AppliesTo(%s) = %t, want %t", tt.outcome, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWatcherReload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "guardrails.json")
+	write := func(content string) {
+		t.Helper()
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	write(`{"rules": [{"name": "first", "expression": "true"}]}`)
+	watcher, err := Watch(path)
+	if err != nil {
+		t.Fatalf("Watch() error = %v", err)
+	}
+
+	// Each step changes the size of the file so the change is seen even if the modification
+	// time has not moved on
+	steps := []struct {
+		name      string
+		content   string // Written before Current is called. Left alone if empty
+		remove    bool
+		wantRules []string
+	}{
+		{name: "unchanged", wantRules: []string{"first"}},
+		{name: "valid change is loaded", content: `{"rules": [{"name": "second", "expression": "true"}, {"name": "third", "expression": "false"}]}`, wantRules: []string{"second", "third"}},
+		{name: "invalid change is ignored", content: `{"rules": [{"name": "broken", "expression": "params.memory <="}]}`, wantRules: []string{"second", "third"}},
+		{name: "invalid file is not read again", wantRules: []string{"second", "third"}},
+		{name: "fixed file is loaded", content: `{"rules": [{"name": "fourth", "expression": "true"}]}`, wantRules: []string{"fourth"}},
+		{name: "removed file keeps the rules", remove: true, wantRules: []string{"fourth"}},
+	}
+
+	for _, step := range steps {
+		if step.content != "" {
+			write(step.content)
+		}
+		if step.remove {
+			if err := os.Remove(path); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		var got []string
+		for _, rule := range watcher.Current().Rules() {
+			got = append(got, rule.Name)
+		}
+		if strings.Join(got, ",") != strings.Join(step.wantRules, ",") {
+			t.Fatalf("%s: rules = %v, want %v", step.name, got, step.wantRules)
+		}
+	}
+}
+
+func TestWatchInvalidFile(t *testing.T) {
+	dir := t.TempDir()
+	invalid := filepath.Join(dir, "invalid.json")
+	if err := os.WriteFile(invalid, []byte(`{"rules": [{"name": "a"}]}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		path    string
+		wantErr string
+	}{
+		{name: "missing file", path: filepath.Join(dir, "missing.json"), wantErr: "failed to read guardrails file"},
+		{name: "invalid rules", path: invalid, wantErr: "is invalid"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Watch(tt.path)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("Watch() error = %v, want one containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,162 @@
+// access_control.go decides which outcomes the caller may run, using the roles given to their
+// identity in ACCESS_CONTROL_FILE. If there is no file everyone may run every outcome
+
+package server
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/LackOfMorals/mcp4AuraAPI/internal/config"
+)
+
+// tenantParameterNames are the parameters that name the tenant an outcome acts on
+var tenantParameterNames = []string{"tenantId", "tenant_id", "target_tenant_id"}
+
+type serverCallerKey struct{}
+
+// withServerCaller returns a context that marks outcomes run with it as run by the server
+// itself, such as by the TTL reaper, rather than by a caller. Roles do not apply to these
+func withServerCaller(ctx context.Context) context.Context {
+	return context.WithValue(ctx, serverCallerKey{}, true)
+}
+
+// isServerCaller returns true if outcomes run with ctx are run by the server itself
+func isServerCaller(ctx context.Context) bool {
+	server, _ := ctx.Value(serverCallerKey{}).(bool)
+	return server
+}
+
+// callerRoles returns the roles of the caller, and false if there is no access control
+func callerRoles(ctx context.Context, deps *Dependencies) ([]config.Role, bool) {
+	if deps.Config == nil || deps.Config.AccessControl == nil || isServerCaller(ctx) {
+		return nil, false
+	}
+	accessControl := deps.Config.AccessControl
+
+	names, ok := accessControl.Identities[callerIdentity(ctx, deps)]
+	if !ok {
+		names = accessControl.DefaultRoles
+	}
+
+	roles := make([]config.Role, 0, len(names))
+	for _, name := range names {
+		roles = append(roles, accessControl.Roles[name])
+	}
+	return roles, true
+}
+
+// roleAllowsOutcome returns true if role includes outcome, ignoring any tenants it is limited to
+func roleAllowsOutcome(role config.Role, outcome *Outcome) bool {
+	if slices.Contains(role.Outcomes, "*") || slices.Contains(role.Outcomes, outcome.ID) {
+		return true
+	}
+	category, _ := outcome.Metadata["category"].(string)
+	return category != "" && slices.Contains(role.Categories, category)
+}
+
+// mayListOutcome returns true if the caller has a role that includes outcome in any tenant
+func mayListOutcome(ctx context.Context, outcome *Outcome, deps *Dependencies) bool {
+	roles, enforced := callerRoles(ctx, deps)
+	if !enforced {
+		return true
+	}
+	return slices.ContainsFunc(roles, func(role config.Role) bool {
+		return roleAllowsOutcome(role, outcome)
+	})
+}
+
+// checkAccess returns an error if the caller may not run outcome with these parameters. A role
+// limited to some tenants only allows calls where every tenant the call names, or that an instance
+// it names is in, is known to be one of them
+func checkAccess(ctx context.Context, outcome *Outcome, parameters map[string]interface{}, deps *Dependencies) error {
+	roles, enforced := callerRoles(ctx, deps)
+	if !enforced {
+		return nil
+	}
+	identity := callerIdentity(ctx, deps)
+
+	var tenantIDs []string
+	tenantsKnown := false
+	lookedUp := false
+	for _, role := range roles {
+		if !roleAllowsOutcome(role, outcome) {
+			continue
+		}
+		if len(role.Tenants) == 0 {
+			return nil
+		}
+		if !lookedUp {
+			tenantIDs, tenantsKnown = outcomeTenants(deps, parameters)
+			lookedUp = true
+		}
+		if tenantsKnown && !slices.ContainsFunc(tenantIDs, func(tenantID string) bool {
+			return !slices.Contains(role.Tenants, tenantID)
+		}) {
+			return nil
+		}
+	}
+
+	if lookedUp && !tenantsKnown {
+		return fmt.Errorf("access denied: '%s' may only run '%s' against some tenants, and the tenant of every tenant and instance parameter could not be worked out. Supply a tenant ID or an instance ID", identity, outcome.ID)
+	}
+	if lookedUp {
+		return fmt.Errorf("access denied: '%s' may not run '%s' in tenants %s", identity, outcome.ID, strings.Join(tenantIDs, ", "))
+	}
+	return fmt.Errorf("access denied: '%s' may not run '%s'", identity, outcome.ID)
+}
+
+// outcomeTenants works out every tenant an outcome will act on, from its tenant parameters and the
+// tenants of the instances it names, such as both the source and the target of an overwrite. It
+// returns false if there are none or the tenant of any instance cannot be found
+func outcomeTenants(deps *Dependencies, parameters map[string]interface{}) ([]string, bool) {
+	var tenantIDs []string
+	add := func(tenantID string) {
+		if !slices.Contains(tenantIDs, tenantID) {
+			tenantIDs = append(tenantIDs, tenantID)
+		}
+	}
+
+	for _, name := range tenantParameterNames {
+		if tenantID, _ := parameters[name].(string); tenantID != "" {
+			add(tenantID)
+		}
+	}
+
+	// create-instance can take its tenant from a profile
+	if profileName, _ := parameters["profile"].(string); profileName != "" && len(tenantIDs) == 0 && deps.Config != nil {
+		if profile, ok := deps.Config.Profiles[profileName]; ok {
+			add(profile.TenantId)
+		}
+	}
+
+	instanceIDs := []string{}
+	for _, name := range instanceParameterNames {
+		if instanceID, _ := parameters[name].(string); instanceID != "" {
+			instanceIDs = append(instanceIDs, instanceID)
+		}
+	}
+	// compare-instances names its instances in a list
+	if ids, ok := parameters["instance_ids"].([]interface{}); ok {
+		for _, id := range ids {
+			if instanceID, _ := id.(string); instanceID != "" {
+				instanceIDs = append(instanceIDs, instanceID)
+			}
+		}
+	}
+
+	if len(instanceIDs) > 0 && deps.AClient == nil {
+		return nil, false
+	}
+	for _, instanceID := range instanceIDs {
+		info, err := deps.AClient.Instances.Get(instanceID)
+		if err != nil {
+			return nil, false
+		}
+		add(info.Data.TenantId)
+	}
+
+	return tenantIDs, len(tenantIDs) > 0
+}
@@ -0,0 +1,172 @@
+package server
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	aura "github.com/LackOfMorals/aura-client"
+	"github.com/LackOfMorals/mcp4AuraAPI/internal/config"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// newAccessControlDependencies returns dependencies with instance a1 in tenant t1 and b1 in
+// tenant t2, and a caller given the named roles
+func newAccessControlDependencies(t *testing.T, roles map[string]config.Role, callerRoles ...string) *Dependencies {
+	t.Helper()
+	deps := newTestDependencies(t, newFakeInstances(
+		fakeInstance{GetInstanceData: aura.GetInstanceData{Id: "a1", Name: "dev", Status: "running", TenantId: "t1"}},
+		fakeInstance{GetInstanceData: aura.GetInstanceData{Id: "b1", Name: "prod", Status: "running", TenantId: "t2"}},
+	))
+	deps.Config.Profiles = map[string]config.InstanceProfile{
+		"dev-small":  {TenantId: "t1"},
+		"prod-small": {TenantId: "t2"},
+	}
+	if roles != nil {
+		deps.Config.AccessControl = &config.AccessControl{
+			Roles:      roles,
+			Identities: map[string][]string{"tester": callerRoles},
+		}
+	}
+	return deps
+}
+
+func TestCheckAccess(t *testing.T) {
+	roles := map[string]config.Role{
+		"viewer":   {Outcomes: []string{"list-instances", "get-instance-details"}},
+		"dev":      {Categories: []string{"instances"}, Tenants: []string{"t1"}},
+		"prod":     {Categories: []string{"instances"}, Tenants: []string{"t2"}},
+		"everyone": {Outcomes: []string{"*"}},
+	}
+
+	tests := []struct {
+		name       string
+		roles      []string // nil for no access control
+		outcome    string
+		parameters map[string]interface{}
+		server     bool
+		wantErr    string
+	}{
+		{name: "no access control", outcome: "delete-instance", parameters: map[string]interface{}{"instance_id": "b1"}},
+		{name: "role without tenants", roles: []string{"everyone"}, outcome: "delete-instance", parameters: map[string]interface{}{"instance_id": "b1"}},
+		{name: "outcome not in role", roles: []string{"viewer"}, outcome: "delete-instance", parameters: map[string]interface{}{"instance_id": "a1"}, wantErr: "may not run 'delete-instance'"},
+		{name: "no roles", roles: []string{}, outcome: "list-instances", wantErr: "may not run 'list-instances'"},
+		{name: "tenant parameter in role", roles: []string{"dev"}, outcome: "list-instances", parameters: map[string]interface{}{"tenant_id": "t1"}},
+		{name: "tenant parameter outside role", roles: []string{"dev"}, outcome: "list-instances", parameters: map[string]interface{}{"tenant_id": "t2"}, wantErr: "in tenants t2"},
+		{name: "no tenant", roles: []string{"dev"}, outcome: "list-instances", parameters: map[string]interface{}{}, wantErr: "could not be worked out"},
+		{name: "instance in role tenant", roles: []string{"dev"}, outcome: "pause-instance", parameters: map[string]interface{}{"instance_id": "a1"}},
+		{name: "instance outside role tenant", roles: []string{"dev"}, outcome: "pause-instance", parameters: map[string]interface{}{"instance_id": "b1"}, wantErr: "in tenants t2"},
+		{name: "unknown instance", roles: []string{"dev"}, outcome: "pause-instance", parameters: map[string]interface{}{"instance_id": "zz"}, wantErr: "could not be worked out"},
+		{name: "profile tenant in role", roles: []string{"dev"}, outcome: "create-instance", parameters: map[string]interface{}{"name": "x", "profile": "dev-small"}},
+		{name: "profile tenant outside role", roles: []string{"dev"}, outcome: "create-instance", parameters: map[string]interface{}{"name": "x", "profile": "prod-small"}, wantErr: "in tenants t2"},
+		{name: "tenant parameter overrides profile", roles: []string{"dev"}, outcome: "create-instance", parameters: map[string]interface{}{"name": "x", "profile": "prod-small", "tenantId": "t1"}},
+		{name: "overwrite within role tenant", roles: []string{"dev"}, outcome: "overwrite-instance", parameters: map[string]interface{}{"target_instance_id": "a1", "source_snapshot_id": "s1"}},
+		{name: "overwrite from source in another tenant", roles: []string{"dev"}, outcome: "overwrite-instance", parameters: map[string]interface{}{"target_instance_id": "a1", "source_instance_id": "b1"}, wantErr: "in tenants t1, t2"},
+		{name: "clone from source in another tenant", roles: []string{"dev"}, outcome: "clone-instance", parameters: map[string]interface{}{"source_instance_id": "b1", "name": "x", "tenantId": "t1"}, wantErr: "in tenants t1, t2"},
+		{name: "migrate source in another tenant", roles: []string{"dev"}, outcome: "migrate-instance", parameters: map[string]interface{}{"source_instance_id": "b1", "target_tenant_id": "t1", "target_region": "eu"}, wantErr: "in tenants t1, t2"},
+		{name: "tenants split across roles", roles: []string{"dev", "prod"}, outcome: "clone-instance", parameters: map[string]interface{}{"source_instance_id": "b1", "name": "x", "tenantId": "t1"}, wantErr: "in tenants t1, t2"},
+		{name: "compare across tenants", roles: []string{"dev"}, outcome: "compare-instances", parameters: map[string]interface{}{"instance_ids": []interface{}{"a1", "b1"}}, wantErr: "in tenants t1, t2"},
+		{name: "second role allows", roles: []string{"prod", "dev"}, outcome: "pause-instance", parameters: map[string]interface{}{"instance_id": "a1"}},
+		{name: "run by the server", roles: []string{}, outcome: "delete-instance", parameters: map[string]interface{}{"instance_id": "b1"}, server: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var deps *Dependencies
+			if tt.roles == nil {
+				deps = newAccessControlDependencies(t, nil)
+			} else {
+				deps = newAccessControlDependencies(t, roles, tt.roles...)
+			}
+			outcome, err := deps.OutComes.GetOutcome(tt.outcome)
+			if err != nil {
+				t.Fatal(err)
+			}
+			ctx := context.Background()
+			if tt.server {
+				ctx = withServerCaller(ctx)
+			}
+
+			err = checkAccess(ctx, outcome, tt.parameters, deps)
+			if tt.wantErr == "" && err != nil {
+				t.Fatalf("checkAccess() error = %v, want nil", err)
+			}
+			if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Fatalf("checkAccess() error = %v, want one containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestOutcomeTenants(t *testing.T) {
+	tests := []struct {
+		name       string
+		parameters map[string]interface{}
+		want       []string
+		wantKnown  bool
+	}{
+		{name: "nothing", parameters: map[string]interface{}{}},
+		{name: "tenant parameter", parameters: map[string]interface{}{"tenant_id": "t1"}, want: []string{"t1"}, wantKnown: true},
+		{name: "every tenant parameter", parameters: map[string]interface{}{"tenantId": "t1", "target_tenant_id": "t3"}, want: []string{"t1", "t3"}, wantKnown: true},
+		{name: "profile", parameters: map[string]interface{}{"profile": "prod-small"}, want: []string{"t2"}, wantKnown: true},
+		{name: "unknown profile", parameters: map[string]interface{}{"profile": "nope"}},
+		{name: "instance", parameters: map[string]interface{}{"instance_id": "b1"}, want: []string{"t2"}, wantKnown: true},
+		{name: "same tenant once", parameters: map[string]interface{}{"target_instance_id": "a1", "source_instance_id": "a1", "tenant_id": "t1"}, want: []string{"t1"}, wantKnown: true},
+		{name: "tenant and instance", parameters: map[string]interface{}{"tenantId": "t1", "source_instance_id": "b1"}, want: []string{"t1", "t2"}, wantKnown: true},
+		{name: "instance list", parameters: map[string]interface{}{"instance_ids": []interface{}{"b1", "a1"}}, want: []string{"t2", "t1"}, wantKnown: true},
+		{name: "one unknown instance", parameters: map[string]interface{}{"target_instance_id": "a1", "source_instance_id": "zz"}},
+	}
+
+	deps := newAccessControlDependencies(t, nil)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, known := outcomeTenants(deps, tt.parameters)
+			if known != tt.wantKnown || strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Fatalf("outcomeTenants() = %v, %t, want %v, %t", got, known, tt.want, tt.wantKnown)
+			}
+		})
+	}
+}
+
+func TestOutcomeDetailsOnlyForAllowedOutcomes(t *testing.T) {
+	roles := map[string]config.Role{
+		"viewer": {Outcomes: []string{"list-instances"}},
+		"dev":    {Categories: []string{"instances"}, Tenants: []string{"t1"}},
+	}
+
+	tests := []struct {
+		name      string
+		roles     []string
+		outcome   string
+		wantError bool
+	}{
+		{name: "outcome in role", roles: []string{"viewer"}, outcome: "list-instances"},
+		{name: "outcome not in role", roles: []string{"viewer"}, outcome: "delete-instance", wantError: true},
+		{name: "outcome in role limited to tenants", roles: []string{"dev"}, outcome: "delete-instance"},
+		{name: "unknown outcome", roles: []string{"dev"}, outcome: "nope", wantError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			deps := newAccessControlDependencies(t, roles, tt.roles...)
+			request := mcp.CallToolRequest{}
+			request.Params.Arguments = map[string]interface{}{"Outcome_id": tt.outcome}
+
+			result, err := GetOutcomeDetailsHandler(deps)(context.Background(), request)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if result.IsError != tt.wantError {
+				t.Fatalf("IsError = %t, want %t: %s", result.IsError, tt.wantError, toolResultText(result))
+			}
+			if tt.wantError && !strings.Contains(toolResultText(result), "not found") {
+				t.Fatalf("error = %s, want the outcome to be reported as not found", toolResultText(result))
+			}
+
+			listed, _ := ListOutcomesHandler(deps)(context.Background(), request)
+			if strings.Contains(toolResultText(listed), `"`+tt.outcome+`"`) == tt.wantError {
+				t.Fatalf("list-outcomes and get-outcome-details disagree about '%s'", tt.outcome)
+			}
+		})
+	}
+}
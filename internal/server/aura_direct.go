@@ -0,0 +1,181 @@
+// aura_direct.go calls the Aura API endpoints that the Aura API client does not support yet,
+// such as creating encryption keys and GDS sessions. It gets its own token with the same
+// client credentials. Move outcomes over to the client as it gains support for them
+
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	aura "github.com/LackOfMorals/aura-client"
+)
+
+// auraRequester makes a call to the Aura API. body, if not nil, is sent as JSON and the
+// response is decoded into result, if not nil. Paths are relative to the API version, such as
+// '/customer-managed-keys'. Errors from the API are returned as *aura.APIError
+type auraRequester interface {
+	Request(ctx context.Context, method, path string, body, result interface{}) error
+}
+
+// directAuraClient is an auraRequester that calls the Aura API over HTTP
+type directAuraClient struct {
+	baseURL      string // Such as https://api.neo4j.io/v1
+	tokenURL     string
+	clientID     string
+	clientSecret string
+	httpClient   *http.Client
+
+	mu        sync.Mutex
+	token     string
+	tokenType string
+	expiresAt time.Time
+}
+
+// newDirectAuraClient creates a client for the Aura API at uri, such as https://api.neo4j.io/v1.
+// Tokens come from /oauth/token on the same host
+func newDirectAuraClient(uri, clientID, clientSecret string, timeout time.Duration) (*directAuraClient, error) {
+	parsed, err := url.Parse(uri)
+	if err != nil || parsed.Scheme == "" || parsed.Host == "" {
+		return nil, fmt.Errorf("invalid Aura API URI '%s'", uri)
+	}
+
+	return &directAuraClient{
+		baseURL:      strings.TrimSuffix(uri, "/"),
+		tokenURL:     parsed.Scheme + "://" + parsed.Host + "/oauth/token",
+		clientID:     clientID,
+		clientSecret: clientSecret,
+		httpClient:   &http.Client{Timeout: timeout},
+	}, nil
+}
+
+// Request implements auraRequester
+func (c *directAuraClient) Request(ctx context.Context, method, path string, body, result interface{}) error {
+	authorization, err := c.authorization(ctx)
+	if err != nil {
+		return err
+	}
+
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("failed to serialize request: %w", err)
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, reader)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", authorization)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read response: %w", err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return apiError(resp.StatusCode, data)
+	}
+
+	if result == nil || len(data) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(data, result); err != nil {
+		return fmt.Errorf("failed to parse response: %w", err)
+	}
+	return nil
+}
+
+// authorization returns the Authorization header, getting a new token if the last one has expired
+func (c *directAuraClient) authorization(ctx context.Context) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.token != "" && time.Now().Before(c.expiresAt) {
+		return c.tokenType + " " + c.token, nil
+	}
+
+	form := url.Values{"grant_type": {"client_credentials"}}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.tokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.SetBasicAuth(c.clientID, c.clientSecret)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to obtain a token: %w", err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", fmt.Errorf("failed to obtain a token: %w", err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return "", apiError(resp.StatusCode, data)
+	}
+
+	var token struct {
+		TokenType   string `json:"token_type"`
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int64  `json:"expires_in"`
+	}
+	if err := json.Unmarshal(data, &token); err != nil {
+		return "", fmt.Errorf("failed to parse token response: %w", err)
+	}
+
+	// Renew a minute early so a token never expires part way through a call
+	c.token = token.AccessToken
+	c.tokenType = token.TokenType
+	c.expiresAt = time.Now().Add(time.Duration(token.ExpiresIn)*time.Second - time.Minute)
+	return c.tokenType + " " + c.token, nil
+}
+
+// resourcePath returns the path of a single resource in collection, such as
+// '/customer-managed-keys/<id>'. An ID that could change which endpoint is called, such as
+// '../instances/<id>', is refused rather than escaped
+func resourcePath(collection, id string) (string, error) {
+	if strings.Contains(id, "/") || strings.Contains(id, "..") {
+		return "", fmt.Errorf("'%s' is not a valid ID", id)
+	}
+	return collection + "/" + url.PathEscape(id), nil
+}
+
+// apiError turns an error response from the Aura API into the error type the Aura API client uses
+func apiError(statusCode int, body []byte) error {
+	apiErr := &aura.APIError{
+		StatusCode: statusCode,
+		Message:    http.StatusText(statusCode),
+	}
+
+	var response struct {
+		Message string                `json:"message"`
+		Errors  []aura.APIErrorDetail `json:"errors"`
+	}
+	if err := json.Unmarshal(body, &response); err == nil {
+		if response.Message != "" {
+			apiErr.Message = response.Message
+		}
+		apiErr.Details = response.Errors
+	}
+	return apiErr
+}
@@ -0,0 +1,284 @@
+// =============================================================================
+// These are all of the outcomes that compare instances with each other
+// =============================================================================
+
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// registerCompareInstancesOutcome registers the compare-instances outcome
+func (r *OutcomeRegistry) registerCompareInstancesOutcome() {
+	r.Outcomes["compare-instances"] = &Outcome{
+		ID:          "compare-instances",
+		Name:        "Compare Instances",
+		Description: "Compare two or more Neo4j Aura database instances field by field using the details from get-instance-details. The first instance is the reference, for example the source of an overwrite or migration, and every other instance is compared with it. Each difference is marked with whether it matters for compatibility, for example when the other instance is smaller than the reference. Version and encryption are read from the Aura API directly. If they cannot be read they are listed in not_compared with the reason.",
+		Type:        OutcomesTypeRead,
+		ReadOnly:    true,
+		Parameters: []OutcomeParameter{
+			{
+				Name:        "instance_ids",
+				Type:        "array",
+				Description: "The IDs of the instances to compare. The first is the reference the others are compared with",
+				Required:    true,
+			},
+		},
+		Metadata: map[string]interface{}{
+			"category": "instances",
+		},
+		Handler: executeCompareInstances,
+	}
+}
+
+// instanceFieldDifference is a single field that differs between two instances
+type instanceFieldDifference struct {
+	Field     string `json:"field"`
+	Reference string `json:"reference"`
+	Other     string `json:"other"`
+	Matters   bool   `json:"matters_for_compatibility"`
+	Reason    string `json:"reason,omitempty"`
+}
+
+// directInstanceDetails holds the instance fields that the Aura API client does not report so
+// are read from the Aura API directly
+type directInstanceDetails struct {
+	Version              string `json:"version,omitempty"`
+	CustomerManagedKeyId string `json:"customer_managed_key_id,omitempty"`
+}
+
+// comparedInstance is an instance as reported by compare-instances
+type comparedInstance struct {
+	instanceDetails
+	directInstanceDetails
+}
+
+// executeCompareInstances implements the compare-instances outcome
+func executeCompareInstances(ctx context.Context, parameters map[string]interface{}, deps *Dependencies) (*mcp.CallToolResult, error) {
+	if deps.AClient == nil {
+		return mcp.NewToolResultError("Aura API Client is not initialized"), nil
+	}
+
+	// Validate and extract required parameter
+	ids := stringListParameter(parameters["instance_ids"])
+	if len(ids) < 2 {
+		return mcp.NewToolResultError("'instance_ids' parameter is required and must contain at least two instance IDs"), nil
+	}
+
+	details, failures := fetchInstanceDetails(ctx, deps, ids)
+	if len(failures) > 0 {
+		problems := []string{}
+		for _, id := range ids {
+			if err, failed := failures[id]; failed {
+				problems = append(problems, fmt.Sprintf("%s: %v", id, err))
+			}
+		}
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to retrieve instance details for: %s. The instances may not exist or you may not have access to them.", strings.Join(problems, "; "))), nil
+	}
+
+	reference := newInstanceDetails(details[ids[0]])
+	direct, directErr := fetchDirectInstanceDetails(ctx, deps, ids)
+
+	type comparison struct {
+		InstanceId   string                    `json:"instance_id"`
+		InstanceName string                    `json:"instance_name"`
+		Compatible   bool                      `json:"compatible"`
+		Differences  []instanceFieldDifference `json:"differences"`
+	}
+
+	type compareResult struct {
+		Reference         comparedInstance `json:"reference"`
+		Comparisons       []comparison     `json:"comparisons"`
+		NotCompared       []string         `json:"not_compared"`
+		NotComparedReason string           `json:"not_compared_reason,omitempty"`
+	}
+
+	result := compareResult{
+		Reference:   comparedInstance{reference, direct[ids[0]]},
+		Comparisons: []comparison{},
+		NotCompared: []string{},
+	}
+	if directErr != nil {
+		result.NotCompared = []string{"version", "encryption"}
+		result.NotComparedReason = fmt.Sprintf("Failed to read them from the Aura API: %v", directErr)
+	}
+
+	for _, id := range ids[1:] {
+		other := newInstanceDetails(details[id])
+		differences := compareInstanceDetails(reference, other)
+		if directErr == nil {
+			differences = append(differences, compareDirectInstanceDetails(direct[ids[0]], direct[id])...)
+		}
+
+		compatible := true
+		for _, d := range differences {
+			if d.Matters {
+				compatible = false
+			}
+		}
+
+		result.Comparisons = append(result.Comparisons, comparison{
+			InstanceId:   other.Id,
+			InstanceName: other.Name,
+			Compatible:   compatible,
+			Differences:  differences,
+		})
+	}
+
+	jsonData, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to serialize results: %v", err)), nil
+	}
+
+	return mcp.NewToolResultText(string(jsonData)), nil
+}
+
+// compareInstanceDetails returns the fields that differ between the reference instance and another.
+// A difference matters for compatibility if data from the reference may not fit or work in the other
+func compareInstanceDetails(reference, other instanceDetails) []instanceFieldDifference {
+	differences := []instanceFieldDifference{}
+
+	referenceStorage, otherStorage := "", ""
+	if reference.Storage != nil {
+		referenceStorage = *reference.Storage
+	}
+	if other.Storage != nil {
+		otherStorage = *other.Storage
+	}
+
+	if reference.Type != other.Type {
+		differences = append(differences, instanceFieldDifference{
+			Field: "type", Reference: reference.Type, Other: other.Type,
+			Matters: true, Reason: "instance types differ so features and limits may not match",
+		})
+	}
+
+	if reference.Memory != other.Memory {
+		d := instanceFieldDifference{Field: "memory", Reference: reference.Memory, Other: other.Memory}
+		if sizeInGB(other.Memory) < sizeInGB(reference.Memory) {
+			d.Matters = true
+			d.Reason = "other instance has less memory than the reference"
+		}
+		differences = append(differences, d)
+	}
+
+	if referenceStorage != otherStorage {
+		d := instanceFieldDifference{Field: "storage", Reference: referenceStorage, Other: otherStorage}
+		if sizeInGB(otherStorage) < sizeInGB(referenceStorage) {
+			d.Matters = true
+			d.Reason = "other instance has less storage than the reference so the data may not fit"
+		}
+		differences = append(differences, d)
+	}
+
+	for _, f := range []struct {
+		field            string
+		reference, other string
+	}{
+		{"cloud_provider", reference.CloudProvider, other.CloudProvider},
+		{"region", reference.Region, other.Region},
+		{"tenant_id", reference.TenantId, other.TenantId},
+		{"status", reference.Status, other.Status},
+	} {
+		if f.reference != f.other {
+			differences = append(differences, instanceFieldDifference{Field: f.field, Reference: f.reference, Other: f.other})
+		}
+	}
+
+	return differences
+}
+
+// fetchDirectInstanceDetails reads the version and encryption key of each instance from the Aura
+// API directly. The first instance that cannot be read stops the others
+func fetchDirectInstanceDetails(ctx context.Context, deps *Dependencies, ids []string) (map[string]directInstanceDetails, error) {
+	if deps.AuraAPI == nil {
+		return nil, fmt.Errorf("Aura API Client is not initialized")
+	}
+
+	details := make(map[string]directInstanceDetails, len(ids))
+	for _, id := range ids {
+		path, err := resourcePath("/instances", id)
+		if err != nil {
+			return nil, err
+		}
+		var response struct {
+			Data directInstanceDetails `json:"data"`
+		}
+		if err := deps.AuraAPI.Request(ctx, http.MethodGet, path, nil, &response); err != nil {
+			return nil, fmt.Errorf("%s: %w", id, err)
+		}
+		details[id] = response.Data
+	}
+	return details, nil
+}
+
+// compareDirectInstanceDetails returns the differences in version and encryption between the
+// reference instance and another. An older version, or losing the reference's encryption key,
+// matters for compatibility
+func compareDirectInstanceDetails(reference, other directInstanceDetails) []instanceFieldDifference {
+	differences := []instanceFieldDifference{}
+
+	if reference.Version != other.Version {
+		d := instanceFieldDifference{Field: "version", Reference: reference.Version, Other: other.Version}
+		if majorVersion(other.Version) < majorVersion(reference.Version) {
+			d.Matters = true
+			d.Reason = "other instance runs an older version of Neo4j than the reference"
+		}
+		differences = append(differences, d)
+	}
+
+	if reference.CustomerManagedKeyId != other.CustomerManagedKeyId {
+		d := instanceFieldDifference{Field: "customer_managed_key_id", Reference: reference.CustomerManagedKeyId, Other: other.CustomerManagedKeyId}
+		if reference.CustomerManagedKeyId != "" && other.CustomerManagedKeyId == "" {
+			d.Matters = true
+			d.Reason = "reference is encrypted with a customer managed key but the other instance is not"
+		}
+		differences = append(differences, d)
+	}
+
+	return differences
+}
+
+// majorVersion returns the major version of a Neo4j version such as '5' or '5.26'. Returns 0 if
+// the version is not understood
+func majorVersion(version string) int {
+	major, _, _ := strings.Cut(version, ".")
+	value, err := strconv.Atoi(major)
+	if err != nil {
+		return 0
+	}
+	return value
+}
+
+// stringListParameter returns a parameter that should be a list of strings. A JSON array or a
+// comma separated string are accepted. Empty entries are dropped
+func stringListParameter(value interface{}) []string {
+	var items []string
+	switch v := value.(type) {
+	case []interface{}:
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				items = append(items, s)
+			}
+		}
+	case []string:
+		items = v
+	case string:
+		items = strings.Split(v, ",")
+	}
+
+	result := []string{}
+	for _, item := range items {
+		if item = strings.TrimSpace(item); item != "" {
+			result = append(result, item)
+		}
+	}
+	return result
+}
@@ -0,0 +1,78 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	aura "github.com/LackOfMorals/aura-client"
+)
+
+func TestCompareInstancesVersionAndEncryption(t *testing.T) {
+	tests := []struct {
+		name           string
+		reference      string // Response to GET /instances/a1
+		other          string // Response to GET /instances/b1
+		noDirect       bool   // The Aura API cannot be called directly
+		wantFields     []string
+		wantCompatible bool
+	}{
+		{name: "same", reference: `{"data":{"version":"5","customer_managed_key_id":"k1"}}`, other: `{"data":{"version":"5","customer_managed_key_id":"k1"}}`, wantCompatible: true},
+		{name: "newer version", reference: `{"data":{"version":"4"}}`, other: `{"data":{"version":"5"}}`, wantFields: []string{"version"}, wantCompatible: true},
+		{name: "older version", reference: `{"data":{"version":"5"}}`, other: `{"data":{"version":"4"}}`, wantFields: []string{"version"}},
+		{name: "other key", reference: `{"data":{"version":"5","customer_managed_key_id":"k1"}}`, other: `{"data":{"version":"5","customer_managed_key_id":"k2"}}`, wantFields: []string{"customer_managed_key_id"}, wantCompatible: true},
+		{name: "not encrypted", reference: `{"data":{"version":"5","customer_managed_key_id":"k1"}}`, other: `{"data":{"version":"5"}}`, wantFields: []string{"customer_managed_key_id"}},
+		{name: "encrypted", reference: `{"data":{"version":"5"}}`, other: `{"data":{"version":"5","customer_managed_key_id":"k1"}}`, wantFields: []string{"customer_managed_key_id"}, wantCompatible: true},
+		{name: "not read", noDirect: true, wantCompatible: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			deps := newTestDependencies(t, newFakeInstances(
+				fakeInstance{GetInstanceData: aura.GetInstanceData{Id: "a1", Name: "source", Status: "running", TenantId: "t1", Memory: "8GB"}},
+				fakeInstance{GetInstanceData: aura.GetInstanceData{Id: "b1", Name: "target", Status: "running", TenantId: "t1", Memory: "8GB"}},
+			))
+			if !tt.noDirect {
+				deps.AuraAPI = &fakeRequester{responses: map[string]string{
+					"GET /instances/a1": tt.reference,
+					"GET /instances/b1": tt.other,
+				}}
+			}
+
+			result, err := executeCompareInstances(context.Background(), map[string]interface{}{"instance_ids": []interface{}{"a1", "b1"}}, deps)
+			if err != nil || result.IsError {
+				t.Fatalf("result = %s, %v", toolResultText(result), err)
+			}
+			var compared struct {
+				Reference struct {
+					Version string `json:"version"`
+				} `json:"reference"`
+				Comparisons []struct {
+					Compatible  bool                      `json:"compatible"`
+					Differences []instanceFieldDifference `json:"differences"`
+				} `json:"comparisons"`
+				NotCompared []string `json:"not_compared"`
+			}
+			if err := json.Unmarshal([]byte(toolResultText(result)), &compared); err != nil || len(compared.Comparisons) != 1 {
+				t.Fatalf("failed to parse %s: %v", toolResultText(result), err)
+			}
+
+			var fields []string
+			for _, d := range compared.Comparisons[0].Differences {
+				fields = append(fields, d.Field)
+			}
+			if len(fields) != len(tt.wantFields) || (len(fields) == 1 && fields[0] != tt.wantFields[0]) {
+				t.Fatalf("differences = %v, want %v", fields, tt.wantFields)
+			}
+			if compared.Comparisons[0].Compatible != tt.wantCompatible {
+				t.Fatalf("compatible = %t, want %t", compared.Comparisons[0].Compatible, tt.wantCompatible)
+			}
+			if wantNotCompared := tt.noDirect; (len(compared.NotCompared) == 2) != wantNotCompared {
+				t.Fatalf("not_compared = %v, want version and encryption listed %t", compared.NotCompared, wantNotCompared)
+			}
+			if !tt.noDirect && compared.Reference.Version == "" {
+				t.Fatalf("reference = %s, want its version reported", toolResultText(result))
+			}
+		})
+	}
+}
@@ -0,0 +1,157 @@
+// confirmation.go holds the confirmation tokens for destructive outcomes. The first call of a
+// destructive outcome is run as a dry run and returns a token bound to the outcome and its exact
+// parameters. Only a second call with the same parameters that presents the token makes changes
+
+package server
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"sync"
+	"time"
+)
+
+// confirmationTokenTTL is how long a confirmation token can be used for
+const confirmationTokenTTL = 5 * time.Minute
+
+// confirmationTokenParameter is added to every destructive outcome
+var confirmationTokenParameter = OutcomeParameter{
+	Name:        "confirmation_token",
+	Type:        "string",
+	Description: "The token returned by the first call of this outcome. Leave out on the first call to see what would be changed and get a token, then call again with exactly the same parameters plus the token to make the change",
+	Required:    false,
+}
+
+// confirmationToken is a token that has been issued but not yet used
+type confirmationToken struct {
+	Token     string    `json:"confirmation_token"`
+	OutcomeId string    `json:"-"`
+	Hash      string    `json:"-"`
+	ExpiresAt time.Time `json:"confirmation_expires_at"`
+}
+
+// confirmationStore keeps the confirmation tokens that have been issued. Tokens are only kept in
+// memory so none survive a restart
+type confirmationStore struct {
+	mu     sync.Mutex
+	tokens map[string]confirmationToken
+}
+
+// newConfirmationStore creates an empty store
+func newConfirmationStore() *confirmationStore {
+	return &confirmationStore{
+		tokens: make(map[string]confirmationToken),
+	}
+}
+
+// Issue creates a token for running outcomeID with exactly these parameters
+func (c *confirmationStore) Issue(outcomeID string, parameters map[string]interface{}) (confirmationToken, error) {
+	hash, err := confirmationHash(outcomeID, parameters)
+	if err != nil {
+		return confirmationToken{}, err
+	}
+
+	random := make([]byte, 16)
+	if _, err := rand.Read(random); err != nil {
+		return confirmationToken{}, fmt.Errorf("failed to create a confirmation token: %w", err)
+	}
+
+	token := confirmationToken{
+		Token:     "ct-" + hex.EncodeToString(random),
+		OutcomeId: outcomeID,
+		Hash:      hash,
+		ExpiresAt: time.Now().UTC().Add(confirmationTokenTTL),
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	// Forget tokens that can no longer be used
+	now := time.Now()
+	for key, t := range c.tokens {
+		if now.After(t.ExpiresAt) {
+			delete(c.tokens, key)
+		}
+	}
+
+	c.tokens[token.Token] = token
+	return token, nil
+}
+
+// Redeem checks that token was issued for running outcomeID with exactly these parameters and
+// has not expired. A token can only be presented once, whether or not it is accepted
+func (c *confirmationStore) Redeem(token, outcomeID string, parameters map[string]interface{}) error {
+	c.mu.Lock()
+	issued, ok := c.tokens[token]
+	delete(c.tokens, token)
+	c.mu.Unlock()
+
+	if !ok {
+		return fmt.Errorf("the confirmation token is not valid or has already been used")
+	}
+	if time.Now().After(issued.ExpiresAt) {
+		return fmt.Errorf("the confirmation token expired at %s", issued.ExpiresAt.Format(time.RFC3339))
+	}
+	if issued.OutcomeId != outcomeID {
+		return fmt.Errorf("the confirmation token was issued for '%s', not '%s'", issued.OutcomeId, outcomeID)
+	}
+
+	hash, err := confirmationHash(outcomeID, parameters)
+	if err != nil {
+		return err
+	}
+	if hash != issued.Hash {
+		return fmt.Errorf("the parameters are not the same as when the confirmation token was issued")
+	}
+	return nil
+}
+
+// confirmationHash returns a hash of an outcome ID and its parameters. Map keys are sorted when
+// encoded so the same parameters always give the same hash
+func confirmationHash(outcomeID string, parameters map[string]interface{}) (string, error) {
+	data, err := json.Marshal(struct {
+		OutcomeId  string                 `json:"outcome_id"`
+		Parameters map[string]interface{} `json:"parameters"`
+	}{outcomeID, parameters})
+	if err != nil {
+		return "", fmt.Errorf("failed to read parameters: %w", err)
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+type confirmedKey struct{}
+
+// withConfirmation returns a context that marks destructive outcomes run with it as confirmed.
+// It is used once a token has been accepted, so that outcomes run by a confirmed outcome do not
+// need their own, and by the TTL reaper
+func withConfirmation(ctx context.Context) context.Context {
+	return context.WithValue(ctx, confirmedKey{}, true)
+}
+
+// isConfirmed returns true if destructive outcomes run with ctx have been confirmed
+func isConfirmed(ctx context.Context) bool {
+	confirmed, _ := ctx.Value(confirmedKey{}).(bool)
+	return confirmed
+}
+
+// isDestructive returns true if an outcome is marked as destructive in its metadata
+func isDestructive(outcome *Outcome) bool {
+	destructive, _ := outcome.Metadata["destructive"].(bool)
+	return destructive
+}
+
+// withoutParameter returns parameters without name, leaving the original unchanged
+func withoutParameter(parameters map[string]interface{}, name string) map[string]interface{} {
+	if _, ok := parameters[name]; !ok {
+		return parameters
+	}
+	result := maps.Clone(parameters)
+	delete(result, name)
+	return result
+}
@@ -0,0 +1,158 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	aura "github.com/LackOfMorals/aura-client"
+)
+
+func TestConfirmationStoreRedeem(t *testing.T) {
+	parameters := map[string]interface{}{"instance_id": "a1", "nested": map[string]interface{}{"b": 2, "a": 1}}
+
+	tests := []struct {
+		name       string
+		outcomeID  string
+		parameters map[string]interface{}
+		expire     bool
+		token      string // Used in place of the issued token if set
+		wantErr    string
+	}{
+		{name: "same outcome and parameters", outcomeID: "delete-instance", parameters: parameters},
+		{name: "parameters in another order", outcomeID: "delete-instance", parameters: map[string]interface{}{"nested": map[string]interface{}{"a": 1, "b": 2}, "instance_id": "a1"}},
+		{name: "different parameters", outcomeID: "delete-instance", parameters: map[string]interface{}{"instance_id": "a2"}, wantErr: "parameters are not the same"},
+		{name: "extra parameter", outcomeID: "delete-instance", parameters: map[string]interface{}{"instance_id": "a1", "nested": parameters["nested"], "more": true}, wantErr: "parameters are not the same"},
+		{name: "different outcome", outcomeID: "overwrite-instance", parameters: parameters, wantErr: "issued for 'delete-instance'"},
+		{name: "expired", outcomeID: "delete-instance", parameters: parameters, expire: true, wantErr: "expired"},
+		{name: "unknown token", outcomeID: "delete-instance", parameters: parameters, token: "ct-unknown", wantErr: "not valid"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := newConfirmationStore()
+			issued, err := store.Issue("delete-instance", parameters)
+			if err != nil {
+				t.Fatalf("Issue() error = %v", err)
+			}
+			if tt.expire {
+				entry := store.tokens[issued.Token]
+				entry.ExpiresAt = time.Now().Add(-time.Second)
+				store.tokens[issued.Token] = entry
+			}
+			token := issued.Token
+			if tt.token != "" {
+				token = tt.token
+			}
+
+			err = store.Redeem(token, tt.outcomeID, tt.parameters)
+			if tt.wantErr == "" && err != nil {
+				t.Fatalf("Redeem() error = %v, want nil", err)
+			}
+			if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Fatalf("Redeem() error = %v, want one containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestConfirmationTokenSingleUse(t *testing.T) {
+	parameters := map[string]interface{}{"instance_id": "a1"}
+
+	tests := []struct {
+		name             string
+		firstParameters  map[string]interface{}
+		firstShouldWork  bool
+		secondParameters map[string]interface{}
+	}{
+		{name: "accepted token", firstParameters: parameters, firstShouldWork: true, secondParameters: parameters},
+		{name: "rejected token", firstParameters: map[string]interface{}{"instance_id": "a2"}, secondParameters: parameters},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := newConfirmationStore()
+			issued, err := store.Issue("delete-instance", parameters)
+			if err != nil {
+				t.Fatalf("Issue() error = %v", err)
+			}
+
+			err = store.Redeem(issued.Token, "delete-instance", tt.firstParameters)
+			if (err == nil) != tt.firstShouldWork {
+				t.Fatalf("first Redeem() error = %v, want success %t", err, tt.firstShouldWork)
+			}
+
+			// A token can only be presented once, whether or not it was accepted
+			err = store.Redeem(issued.Token, "delete-instance", tt.secondParameters)
+			if err == nil || !strings.Contains(err.Error(), "already been used") {
+				t.Fatalf("second Redeem() error = %v, want it to say the token was used", err)
+			}
+		})
+	}
+}
+
+func TestExecuteOutcomeConfirmationToken(t *testing.T) {
+	instances := newFakeInstances(fakeInstance{GetInstanceData: aura.GetInstanceData{Id: "a1", Name: "dev", Status: "running", TenantId: "t1"}})
+	deps := newTestDependencies(t, instances)
+	ctx := context.Background()
+	parameters := map[string]interface{}{"instance_id": "a1"}
+
+	// The first call only issues a token
+	result, err := deps.OutComes.ExecuteOutcome(ctx, "delete-instance", parameters, deps)
+	if err != nil || result.IsError {
+		t.Fatalf("first call = %v, %v", toolResultText(result), err)
+	}
+	if len(instances.Changes()) != 0 {
+		t.Fatalf("first call made changes: %v", instances.Changes())
+	}
+	var summary struct {
+		Token    string        `json:"confirmation_token"`
+		APICalls []auraAPICall `json:"api_calls"`
+	}
+	if err := json.Unmarshal([]byte(toolResultText(result)), &summary); err != nil {
+		t.Fatalf("failed to parse the first call: %v", err)
+	}
+	if summary.Token == "" {
+		t.Fatalf("first call did not return a confirmation token: %s", toolResultText(result))
+	}
+	if len(summary.APICalls) != 1 || summary.APICalls[0].Method != "DELETE" || summary.APICalls[0].Path != "/instances/a1" {
+		t.Fatalf("first call api_calls = %+v, want DELETE /instances/a1", summary.APICalls)
+	}
+
+	// The cases run in order. The first presents the token, so the second finds it used
+	tests := []struct {
+		name       string
+		parameters map[string]interface{}
+		wantError  bool
+	}{
+		{name: "token with other parameters", parameters: map[string]interface{}{"instance_id": "a2", "confirmation_token": summary.Token}, wantError: true},
+		{name: "token already presented", parameters: map[string]interface{}{"instance_id": "a1", "confirmation_token": summary.Token}, wantError: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := deps.OutComes.ExecuteOutcome(ctx, "delete-instance", tt.parameters, deps)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if result.IsError != tt.wantError {
+				t.Fatalf("IsError = %t, want %t: %s", result.IsError, tt.wantError, toolResultText(result))
+			}
+			if len(instances.Changes()) != 0 {
+				t.Fatalf("changes = %v, want none", instances.Changes())
+			}
+		})
+	}
+
+	// A new token with the same parameters goes ahead
+	result, _ = deps.OutComes.ExecuteOutcome(ctx, "delete-instance", parameters, deps)
+	_ = json.Unmarshal([]byte(toolResultText(result)), &summary)
+	result, err = deps.OutComes.ExecuteOutcome(ctx, "delete-instance", map[string]interface{}{"instance_id": "a1", "confirmation_token": summary.Token}, deps)
+	if err != nil || result.IsError {
+		t.Fatalf("confirmed call = %v, %v", toolResultText(result), err)
+	}
+	if changes := instances.Changes(); len(changes) != 1 || changes[0] != "DELETE a1" {
+		t.Fatalf("changes = %v, want [DELETE a1]", changes)
+	}
+}
@@ -0,0 +1,540 @@
+// =============================================================================
+// These are the outcomes that reconcile the instances in Aura with a declared
+// desired state
+//
+// The desired state is read from a YAML or JSON spec. plan-desired-state compares it
+// with the instances in Aura and returns the actions needed along with a plan ID.
+// apply-desired-state takes the same spec and the plan ID, checks that the plan is
+// unchanged and then carries out the actions using the existing outcomes
+// =============================================================================
+
+package server
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"gopkg.in/yaml.v3"
+)
+
+// desiredStateSpec is the declared state of the instances in one or more tenants
+type desiredStateSpec struct {
+	TenantId    string            `yaml:"tenant_id"`    // Default tenant for instances that do not give one
+	AllowDelete bool              `yaml:"allow_delete"` // Instances in a managed tenant that are not in the spec are only deleted if this is true
+	Instances   []desiredInstance `yaml:"instances"`
+}
+
+// desiredInstance is the declared state of a single instance. Instances are matched by tenant and name
+type desiredInstance struct {
+	Name          string `yaml:"name"`
+	Profile       string `yaml:"profile"`
+	TenantId      string `yaml:"tenant_id"`
+	CloudProvider string `yaml:"cloud_provider"`
+	Region        string `yaml:"region"`
+	Memory        string `yaml:"memory"`
+	Type          string `yaml:"type"`
+	Version       string `yaml:"version"`
+	State         string `yaml:"state"` // 'running' or 'paused'. Default running
+}
+
+// desiredStateAction is a single step needed to reach the desired state
+type desiredStateAction struct {
+	Action     string                 `json:"action"`
+	OutcomeId  string                 `json:"outcome_id,omitempty"`
+	InstanceId string                 `json:"instance_id,omitempty"`
+	Name       string                 `json:"name"`
+	TenantId   string                 `json:"tenant_id"`
+	Parameters map[string]interface{} `json:"parameters,omitempty"`
+	Reason     string                 `json:"reason"`
+}
+
+// desiredStatePlan is the result of comparing a spec with the instances in Aura
+type desiredStatePlan struct {
+	PlanId  string               `json:"plan_id"`
+	Actions []desiredStateAction `json:"actions"`
+	Manual  []desiredStateAction `json:"manual_changes_needed,omitempty"`
+	Skipped []desiredStateAction `json:"skipped,omitempty"`
+}
+
+// desiredStateSpecParameters are the parameters shared by plan-desired-state and apply-desired-state
+var desiredStateSpecParameters = []OutcomeParameter{
+	{
+		Name:        "spec_path",
+		Type:        "string",
+		Description: "Path to a YAML or JSON file holding the desired state, relative to the server's SPEC_DIR. Either this or 'spec' must be supplied",
+		Required:    false,
+	},
+	{
+		Name:        "spec",
+		Type:        "object",
+		Description: "The desired state given inline: { tenant_id, allow_delete, instances: [ { name, profile, tenant_id, cloud_provider, region, memory, type, version, state } ] }. state is 'running' or 'paused'",
+		Required:    false,
+	},
+}
+
+// registerPlanDesiredStateOutcome registers the plan-desired-state outcome
+func (r *OutcomeRegistry) registerPlanDesiredStateOutcome() {
+	r.Outcomes["plan-desired-state"] = &Outcome{
+		ID:          "plan-desired-state",
+		Name:        "Plan Desired State",
+		Description: "Compare a declared desired state of instances with the instances in Aura and return the create, update, pause, resume and delete actions needed to reconcile them, along with a plan ID to give to apply-desired-state. Nothing is changed. Instances are matched by tenant and name. Instances in a managed tenant that are not in the spec are only deleted if the spec sets allow_delete to true. Changes to cloud provider, region or type cannot be applied and are reported as manual changes.",
+		Type:        OutcomesTypeRead,
+		ReadOnly:    true,
+		Parameters:  desiredStateSpecParameters,
+		Metadata: map[string]interface{}{
+			"category": "desired-state",
+		},
+		Handler: executePlanDesiredState,
+	}
+}
+
+// executePlanDesiredState implements the plan-desired-state outcome
+func executePlanDesiredState(ctx context.Context, parameters map[string]interface{}, deps *Dependencies) (*mcp.CallToolResult, error) {
+	if deps.AClient == nil {
+		return mcp.NewToolResultError("Aura API Client is not initialized"), nil
+	}
+
+	spec, err := loadDesiredStateSpec(deps, parameters)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	plan, err := planDesiredState(ctx, deps, spec)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	jsonData, err := json.MarshalIndent(plan, "", "  ")
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to serialize plan: %v", err)), nil
+	}
+
+	return mcp.NewToolResultText(string(jsonData)), nil
+}
+
+// registerApplyDesiredStateOutcome registers the apply-desired-state outcome
+func (r *OutcomeRegistry) registerApplyDesiredStateOutcome() {
+	r.Outcomes["apply-desired-state"] = &Outcome{
+		ID:          "apply-desired-state",
+		Name:        "Apply Desired State",
+		Description: "Carry out a plan made by plan-desired-state. The same spec and the plan ID from plan-desired-state must be supplied. The plan is made again and if it no longer matches the plan ID, because the spec or the instances have changed, nothing is done. Actions are carried out in order using the existing outcomes and stop at the first failure.",
+		Type:        OutcomesTypeUpdate,
+		ReadOnly:    false,
+		Parameters: append(slices.Clone(desiredStateSpecParameters), OutcomeParameter{
+			Name:        "plan_id",
+			Type:        "string",
+			Description: "The plan ID returned by plan-desired-state for the plan that was approved",
+			Required:    true,
+		}),
+		Metadata: map[string]interface{}{
+			"category":    "desired-state",
+			"destructive": true,
+			"warning":     "Applying a plan can delete instances if the spec sets allow_delete to true. Review the plan from plan-desired-state first.",
+		},
+		Handler: executeApplyDesiredState,
+	}
+}
+
+// executeApplyDesiredState implements the apply-desired-state outcome
+func executeApplyDesiredState(ctx context.Context, parameters map[string]interface{}, deps *Dependencies) (*mcp.CallToolResult, error) {
+	if deps.AClient == nil {
+		return mcp.NewToolResultError("Aura API Client is not initialized"), nil
+	}
+
+	planID, ok := parameters["plan_id"].(string)
+	if !ok || planID == "" {
+		return mcp.NewToolResultError("'plan_id' parameter is required. Run plan-desired-state first and review the plan"), nil
+	}
+
+	spec, err := loadDesiredStateSpec(deps, parameters)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	plan, err := planDesiredState(ctx, deps, spec)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	if plan.PlanId != planID {
+		return mcp.NewToolResultError(fmt.Sprintf("The plan has changed since it was approved ( now %s ). The spec or the instances in Aura are different. Run plan-desired-state again and review the new plan.", plan.PlanId)), nil
+	}
+
+	type actionResult struct {
+		desiredStateAction
+		Success bool   `json:"success"`
+		Result  string `json:"result"`
+	}
+
+	type applyResult struct {
+		Success bool           `json:"success"`
+		Message string         `json:"message"`
+		PlanId  string         `json:"plan_id"`
+		Results []actionResult `json:"results"`
+		NotRun  int            `json:"not_run"`
+	}
+
+	result := applyResult{
+		Success: true,
+		PlanId:  planID,
+		Results: []actionResult{},
+	}
+
+	// Carry out each action through the registry so the usual checks, such as read-only, apply
+	for i, action := range plan.Actions {
+		outcomeResult, err := deps.OutComes.ExecuteOutcome(ctx, action.OutcomeId, action.Parameters, deps)
+		if err == nil && outcomeResult == nil {
+			err = fmt.Errorf("no result returned")
+		}
+
+		ar := actionResult{desiredStateAction: action, Success: err == nil && !outcomeResult.IsError}
+		if err != nil {
+			ar.Result = err.Error()
+		} else {
+			ar.Result = toolResultText(outcomeResult)
+		}
+		result.Results = append(result.Results, ar)
+
+		if !ar.Success {
+			result.Success = false
+			result.NotRun = len(plan.Actions) - i - 1
+			break
+		}
+	}
+
+	if result.Success {
+		result.Message = fmt.Sprintf("Applied %d action(s)", len(result.Results))
+	} else {
+		result.Message = fmt.Sprintf("Stopped after a failed action. %d action(s) were not run. Run plan-desired-state again to see what is left", result.NotRun)
+	}
+
+	jsonData, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to serialize results: %v", err)), nil
+	}
+
+	if !result.Success {
+		return mcp.NewToolResultError(string(jsonData)), nil
+	}
+
+	return mcp.NewToolResultText(string(jsonData)), nil
+}
+
+// readSpecFile reads a spec file from within specDir. The path is taken as relative to specDir,
+// and is refused if it, or any symbolic link in it, leads outside specDir
+func readSpecFile(specDir, specPath string) ([]byte, error) {
+	if specDir == "" {
+		return nil, fmt.Errorf("Reading spec files is disabled as the server has no SPEC_DIR. Supply the spec inline with 'spec'")
+	}
+
+	dir, err := filepath.EvalSymlinks(specDir)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to read SPEC_DIR: %v", err)
+	}
+	dir, err = filepath.Abs(dir)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to read SPEC_DIR: %v", err)
+	}
+
+	// Check the path before and after following links, so nothing is learnt about files outside
+	within := func(path string) bool {
+		rel, err := filepath.Rel(dir, path)
+		return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+	}
+	outside := fmt.Errorf("'spec_path' must be a file within SPEC_DIR")
+	if filepath.IsAbs(specPath) {
+		return nil, outside
+	}
+	path := filepath.Join(dir, filepath.Clean(specPath))
+	if !within(path) {
+		return nil, outside
+	}
+	path, err = filepath.EvalSymlinks(path)
+	if err != nil {
+		return nil, fmt.Errorf("Spec file '%s' not found in SPEC_DIR", specPath)
+	}
+	if !within(path) {
+		return nil, outside
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to read spec file '%s'", specPath)
+	}
+	return data, nil
+}
+
+// loadDesiredStateSpec reads the spec from the 'spec_path' or 'spec' parameter and checks it
+func loadDesiredStateSpec(deps *Dependencies, parameters map[string]interface{}) (*desiredStateSpec, error) {
+	var data []byte
+
+	specPath, _ := parameters["spec_path"].(string)
+	switch inline := parameters["spec"].(type) {
+	case nil:
+		if specPath == "" {
+			return nil, fmt.Errorf("Either 'spec_path' or 'spec' must be supplied")
+		}
+		specDir := ""
+		if deps.Config != nil {
+			specDir = deps.Config.SpecDir
+		}
+		fileData, err := readSpecFile(specDir, specPath)
+		if err != nil {
+			return nil, err
+		}
+		data = fileData
+	case string:
+		data = []byte(inline)
+	default:
+		jsonData, err := json.Marshal(inline)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to read 'spec': %v", err)
+		}
+		data = jsonData
+	}
+
+	// YAML is a superset of JSON so this reads both
+	spec := &desiredStateSpec{}
+	if err := yaml.Unmarshal(data, spec); err != nil {
+		return nil, fmt.Errorf("Failed to parse spec: %v", err)
+	}
+
+	seen := map[string]bool{}
+	for i := range spec.Instances {
+		inst := &spec.Instances[i]
+		if inst.Name == "" {
+			return nil, fmt.Errorf("Instance %d in the spec has no name", i+1)
+		}
+		if inst.State == "" {
+			inst.State = "running"
+		}
+		if inst.State != "running" && inst.State != "paused" {
+			return nil, fmt.Errorf("Instance '%s' in the spec has state '%s'. Must be 'running' or 'paused'", inst.Name, inst.State)
+		}
+		if inst.TenantId == "" {
+			inst.TenantId = spec.TenantId
+		}
+
+		key := inst.TenantId + "/" + inst.Name
+		if seen[key] {
+			return nil, fmt.Errorf("Instance '%s' appears more than once in the spec", inst.Name)
+		}
+		seen[key] = true
+	}
+
+	return spec, nil
+}
+
+// planDesiredState works out the actions needed to move the instances in Aura to the desired state
+func planDesiredState(ctx context.Context, deps *Dependencies, spec *desiredStateSpec) (*desiredStatePlan, error) {
+	instances, err := deps.AClient.Instances.List()
+	if err != nil {
+		return nil, fmt.Errorf("Failed to list instances: %v", err)
+	}
+
+	// Resolve the tenant of each desired instance, which may come from its profile
+	for i := range spec.Instances {
+		inst := &spec.Instances[i]
+		if inst.TenantId != "" || inst.Profile == "" {
+			continue
+		}
+		if deps.Config != nil {
+			if profile, ok := deps.Config.Profiles[inst.Profile]; ok {
+				inst.TenantId = profile.TenantId
+			}
+		}
+	}
+
+	// Index the existing instances by tenant and name
+	existing := map[string][]string{}
+	managedTenants := map[string]bool{}
+	for _, inst := range spec.Instances {
+		if inst.TenantId != "" {
+			managedTenants[inst.TenantId] = true
+		}
+	}
+	if spec.TenantId != "" {
+		managedTenants[spec.TenantId] = true
+	}
+
+	ids := []string{}
+	for _, inst := range instances.Data {
+		key := inst.TenantId + "/" + inst.Name
+		existing[key] = append(existing[key], inst.Id)
+		if managedTenants[inst.TenantId] {
+			ids = append(ids, inst.Id)
+		}
+	}
+
+	details, failures := fetchInstanceDetails(ctx, deps, ids)
+	for _, id := range ids {
+		if err, failed := failures[id]; failed {
+			return nil, fmt.Errorf("Failed to retrieve details of instance %s: %v. Try again", id, err)
+		}
+	}
+
+	plan := &desiredStatePlan{
+		Actions: []desiredStateAction{},
+	}
+	deletes := []desiredStateAction{}
+	wanted := map[string]bool{}
+
+	for _, inst := range spec.Instances {
+		key := inst.TenantId + "/" + inst.Name
+		wanted[key] = true
+
+		matches := existing[key]
+		if len(matches) > 1 {
+			return nil, fmt.Errorf("More than one instance is named '%s' in tenant '%s' ( %v ). Rename them so they can be told apart", inst.Name, inst.TenantId, matches)
+		}
+
+		// Missing instances are created
+		if len(matches) == 0 {
+			params := map[string]interface{}{"name": inst.Name}
+			for k, v := range map[string]string{
+				"profile":        inst.Profile,
+				"tenantId":       inst.TenantId,
+				"cloud_provider": inst.CloudProvider,
+				"region":         inst.Region,
+				"memory":         inst.Memory,
+				"type":           inst.Type,
+				"version":        inst.Version,
+			} {
+				if v != "" {
+					params[k] = v
+				}
+			}
+			reason := "instance does not exist"
+			if inst.State == "paused" {
+				reason += ". It will be paused by a later plan once it is running"
+			}
+			plan.Actions = append(plan.Actions, desiredStateAction{
+				Action: "create", OutcomeId: "create-instance", Name: inst.Name, TenantId: inst.TenantId,
+				Parameters: params, Reason: reason,
+			})
+			continue
+		}
+
+		current, ok := details[matches[0]]
+		if !ok {
+			return nil, fmt.Errorf("Failed to retrieve details of instance %s", matches[0])
+		}
+
+		// Fill in anything the spec leaves to the profile
+		wantedCloud, wantedRegion, wantedMemory, wantedType := inst.CloudProvider, inst.Region, inst.Memory, inst.Type
+		if inst.Profile != "" && deps.Config != nil {
+			if profile, ok := deps.Config.Profiles[inst.Profile]; ok {
+				wantedCloud = firstNonEmpty(wantedCloud, profile.CloudProvider)
+				wantedRegion = firstNonEmpty(wantedRegion, profile.Region)
+				wantedMemory = firstNonEmpty(wantedMemory, profile.Memory)
+				wantedType = firstNonEmpty(wantedType, profile.Type)
+			}
+		}
+
+		// These cannot be changed on an existing instance
+		for _, f := range []struct{ field, wanted, current string }{
+			{"cloud_provider", wantedCloud, current.CloudProvider},
+			{"region", wantedRegion, current.Region},
+			{"type", wantedType, current.Type},
+		} {
+			if f.wanted != "" && f.wanted != f.current {
+				plan.Manual = append(plan.Manual, desiredStateAction{
+					Action: "manual", InstanceId: current.Id, Name: inst.Name, TenantId: inst.TenantId,
+					Reason: fmt.Sprintf("%s is '%s' but the spec wants '%s'. This cannot be changed in place; migrate the instance instead", f.field, f.current, f.wanted),
+				})
+			}
+		}
+
+		// Instances that are changing state are left until they settle
+		if current.Status != "running" && current.Status != "paused" {
+			plan.Skipped = append(plan.Skipped, desiredStateAction{
+				Action: "skip", InstanceId: current.Id, Name: inst.Name, TenantId: inst.TenantId,
+				Reason: fmt.Sprintf("instance status is '%s'. Plan again once it is running or paused", current.Status),
+			})
+			continue
+		}
+
+		if wantedMemory != "" && wantedMemory != current.Memory {
+			plan.Actions = append(plan.Actions, desiredStateAction{
+				Action: "update", OutcomeId: "update-instance", InstanceId: current.Id, Name: inst.Name, TenantId: inst.TenantId,
+				Parameters: map[string]interface{}{"instance_id": current.Id, "memory": wantedMemory},
+				Reason:     fmt.Sprintf("memory is '%s' but the spec wants '%s'", current.Memory, wantedMemory),
+			})
+		}
+
+		if inst.State != current.Status {
+			action, outcome := "pause", "pause-instance"
+			if inst.State == "running" {
+				action, outcome = "resume", "resume-instance"
+			}
+			plan.Actions = append(plan.Actions, desiredStateAction{
+				Action: action, OutcomeId: outcome, InstanceId: current.Id, Name: inst.Name, TenantId: inst.TenantId,
+				Parameters: map[string]interface{}{"instance_id": current.Id},
+				Reason:     fmt.Sprintf("instance is '%s' but the spec wants '%s'", current.Status, inst.State),
+			})
+		}
+	}
+
+	// Instances in a managed tenant that are not in the spec
+	for _, id := range ids {
+		current, ok := details[id]
+		if !ok || wanted[current.TenantId+"/"+current.Name] {
+			continue
+		}
+		if !spec.AllowDelete {
+			plan.Skipped = append(plan.Skipped, desiredStateAction{
+				Action: "skip", InstanceId: current.Id, Name: current.Name, TenantId: current.TenantId,
+				Reason: "instance is not in the spec. It is kept because the spec does not set allow_delete",
+			})
+			continue
+		}
+		deletes = append(deletes, desiredStateAction{
+			Action: "delete", OutcomeId: "delete-instance", InstanceId: current.Id, Name: current.Name, TenantId: current.TenantId,
+			Parameters: map[string]interface{}{"instance_id": current.Id},
+			Reason:     "instance is not in the spec and the spec sets allow_delete",
+		})
+	}
+
+	// Deletes go last so nothing is removed until everything else has been done
+	sort.Slice(deletes, func(i, j int) bool { return deletes[i].InstanceId < deletes[j].InstanceId })
+	plan.Actions = append(plan.Actions, deletes...)
+
+	// The plan ID is a hash of the actions so apply can tell if anything changed
+	actionData, err := json.Marshal(plan.Actions)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to serialize plan: %v", err)
+	}
+	sum := sha256.Sum256(actionData)
+	plan.PlanId = hex.EncodeToString(sum[:8])
+
+	return plan, nil
+}
+
+// firstNonEmpty returns the first of the values that is not empty
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// toolResultText returns the text content of a tool result
+func toolResultText(result *mcp.CallToolResult) string {
+	for _, content := range result.Content {
+		if text, ok := content.(mcp.TextContent); ok {
+			return text.Text
+		}
+	}
+	return ""
+}
@@ -0,0 +1,92 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	aura "github.com/LackOfMorals/aura-client"
+	"github.com/LackOfMorals/mcp4AuraAPI/internal/config"
+)
+
+func TestApplyDesiredStateAllowDelete(t *testing.T) {
+	tests := []struct {
+		name        string
+		allowDelete bool
+		protected   bool   // The instance not in the spec is protected by name
+		planID      string // Used in place of the plan ID from plan-desired-state if set
+		wantChanges []string
+		wantErr     string
+	}{
+		{name: "kept without allow_delete"},
+		{name: "deleted with allow_delete", allowDelete: true, wantChanges: []string{"DELETE a2"}},
+		{name: "protected", allowDelete: true, protected: true, wantErr: "Deletion refused"},
+		{name: "plan changed", allowDelete: true, planID: "0000000000000000", wantErr: "The plan has changed"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			instances := newFakeInstances(
+				fakeInstance{GetInstanceData: aura.GetInstanceData{Id: "a1", Name: "keep", Status: "running", TenantId: "t1", Memory: "8GB"}},
+				fakeInstance{GetInstanceData: aura.GetInstanceData{Id: "a2", Name: "extra", Status: "running", TenantId: "t1", Memory: "8GB"}},
+				fakeInstance{GetInstanceData: aura.GetInstanceData{Id: "b1", Name: "other-tenant", Status: "running", TenantId: "t2", Memory: "8GB"}},
+			)
+			deps := newTestDependencies(t, instances)
+			if tt.protected {
+				deps.Config.Protection = config.DeletionProtection{NamePatterns: []string{"extra"}}
+			}
+			ctx := context.Background()
+			spec := map[string]interface{}{
+				"tenant_id":    "t1",
+				"allow_delete": tt.allowDelete,
+				"instances":    []interface{}{map[string]interface{}{"name": "keep"}},
+			}
+
+			result, err := deps.OutComes.ExecuteOutcome(ctx, "plan-desired-state", map[string]interface{}{"spec": spec}, deps)
+			if err != nil || result.IsError {
+				t.Fatalf("plan = %s, %v", toolResultText(result), err)
+			}
+			var plan desiredStatePlan
+			if err := json.Unmarshal([]byte(toolResultText(result)), &plan); err != nil {
+				t.Fatalf("failed to parse the plan %s: %v", toolResultText(result), err)
+			}
+			if tt.allowDelete != (len(plan.Actions) == 1) || (!tt.allowDelete && len(plan.Skipped) != 1) {
+				t.Fatalf("plan = %s, want 'extra' deleted %t and 'other-tenant' left alone", toolResultText(result), tt.allowDelete)
+			}
+
+			planID := plan.PlanId
+			if tt.planID != "" {
+				planID = tt.planID
+			}
+			parameters := map[string]interface{}{"spec": spec, "plan_id": planID}
+
+			// Applying a plan is destructive, so the first call only returns a token
+			result, err = deps.OutComes.ExecuteOutcome(ctx, "apply-desired-state", parameters, deps)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var summary struct {
+				Token string `json:"confirmation_token"`
+			}
+			_ = json.Unmarshal([]byte(toolResultText(result)), &summary)
+			if summary.Token != "" {
+				parameters["confirmation_token"] = summary.Token
+				result, err = deps.OutComes.ExecuteOutcome(ctx, "apply-desired-state", parameters, deps)
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			if tt.wantErr != "" && (!result.IsError || !strings.Contains(toolResultText(result), tt.wantErr)) {
+				t.Fatalf("apply = %s, want an error containing %q", toolResultText(result), tt.wantErr)
+			}
+			if tt.wantErr == "" && result.IsError {
+				t.Fatalf("apply = %s, want it applied", toolResultText(result))
+			}
+			if strings.Join(instances.Changes(), ",") != strings.Join(tt.wantChanges, ",") {
+				t.Fatalf("changes = %v, want %v", instances.Changes(), tt.wantChanges)
+			}
+		})
+	}
+}
@@ -0,0 +1,300 @@
+// =============================================================================
+// These are the outcomes that detect changes made to instances outside of this server
+//
+// record-baseline saves the details of every instance to a local file. detect-drift
+// compares the instances in Aura with a saved baseline
+// =============================================================================
+
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"time"
+
+	"github.com/LackOfMorals/mcp4AuraAPI/internal/state"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// validBaselineName limits baseline names to those that are safe to use as a file name
+var validBaselineName = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// fleetBaseline is the saved details of every instance at a point in time
+type fleetBaseline struct {
+	Name       string            `json:"name"`
+	RecordedAt time.Time         `json:"recorded_at"`
+	Instances  []instanceDetails `json:"instances"`
+}
+
+// baselineParameter is the parameter shared by record-baseline and detect-drift
+var baselineParameter = OutcomeParameter{
+	Name:        "baseline",
+	Type:        "string",
+	Description: "Name of the baseline. Letters, numbers, '-' and '_' only",
+	Required:    false,
+	Default:     "default",
+}
+
+// registerRecordBaselineOutcome registers the record-baseline outcome
+func (r *OutcomeRegistry) registerRecordBaselineOutcome() {
+	r.Outcomes["record-baseline"] = &Outcome{
+		ID:          "record-baseline",
+		Name:        "Record Baseline",
+		Description: "Save the current details of every Neo4j Aura database instance to a local baseline file so that changes made later can be found with detect-drift. The same fields as get-instance-details are saved. Replaces any existing baseline with the same name. Nothing in Aura is changed.",
+		Type:        OutcomesTypeCreate,
+		ReadOnly:    true,
+		Parameters:  []OutcomeParameter{baselineParameter},
+		Metadata: map[string]interface{}{
+			"category":      "drift",
+			"local_changes": true,
+		},
+		Handler: executeRecordBaseline,
+	}
+}
+
+// executeRecordBaseline implements the record-baseline outcome
+func executeRecordBaseline(ctx context.Context, parameters map[string]interface{}, deps *Dependencies) (*mcp.CallToolResult, error) {
+	if deps.AClient == nil {
+		return mcp.NewToolResultError("Aura API Client is not initialized"), nil
+	}
+
+	path, name, err := baselinePath(deps, parameters)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	current, err := currentFleetDetails(ctx, deps)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	if dry := dryRunFromContext(ctx); dry != nil {
+		dry.localChange("save baseline '%s' with %d instance(s), replacing any existing baseline with the same name", name, len(current))
+		return dry.result(), nil
+	}
+
+	baseline := fleetBaseline{
+		Name:       name,
+		RecordedAt: time.Now().UTC(),
+		Instances:  current,
+	}
+
+	if err := state.Save(path, baseline); err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to save baseline: %v", err)), nil
+	}
+
+	// Format the response
+	type recordResult struct {
+		Success    bool      `json:"success"`
+		Message    string    `json:"message"`
+		Baseline   string    `json:"baseline"`
+		RecordedAt time.Time `json:"recorded_at"`
+		Instances  int       `json:"instances"`
+	}
+
+	result := recordResult{
+		Success:    true,
+		Message:    fmt.Sprintf("Baseline '%s' recorded with %d instance(s)", name, len(current)),
+		Baseline:   name,
+		RecordedAt: baseline.RecordedAt,
+		Instances:  len(current),
+	}
+
+	jsonData, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to serialize results: %v", err)), nil
+	}
+
+	return mcp.NewToolResultText(string(jsonData)), nil
+}
+
+// registerDetectDriftOutcome registers the detect-drift outcome
+func (r *OutcomeRegistry) registerDetectDriftOutcome() {
+	r.Outcomes["detect-drift"] = &Outcome{
+		ID:          "detect-drift",
+		Name:        "Detect Drift",
+		Description: "Compare the Neo4j Aura database instances with a baseline saved by record-baseline. Reports instances that have been added or removed, and instances that have been resized, renamed, changed status or otherwise changed since the baseline was recorded.",
+		Type:        OutcomesTypeRead,
+		ReadOnly:    true,
+		Parameters:  []OutcomeParameter{baselineParameter},
+		Metadata: map[string]interface{}{
+			"category": "drift",
+		},
+		Handler: executeDetectDrift,
+	}
+}
+
+// executeDetectDrift implements the detect-drift outcome
+func executeDetectDrift(ctx context.Context, parameters map[string]interface{}, deps *Dependencies) (*mcp.CallToolResult, error) {
+	if deps.AClient == nil {
+		return mcp.NewToolResultError("Aura API Client is not initialized"), nil
+	}
+
+	path, name, err := baselinePath(deps, parameters)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	var baseline fleetBaseline
+	if err := state.Load(path, &baseline); err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to load baseline: %v", err)), nil
+	}
+	if baseline.RecordedAt.IsZero() {
+		return mcp.NewToolResultError(fmt.Sprintf("Baseline '%s' has not been recorded. Use record-baseline first.", name)), nil
+	}
+
+	current, err := currentFleetDetails(ctx, deps)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	type fieldChange struct {
+		Field  string `json:"field"`
+		Before string `json:"before"`
+		After  string `json:"after"`
+	}
+
+	type changedInstance struct {
+		Id      string        `json:"id"`
+		Name    string        `json:"name"`
+		Kinds   []string      `json:"kinds"`
+		Changes []fieldChange `json:"changes"`
+	}
+
+	type instanceRef struct {
+		Id   string `json:"id"`
+		Name string `json:"name"`
+	}
+
+	type driftResult struct {
+		Baseline           string            `json:"baseline"`
+		BaselineRecordedAt time.Time         `json:"baseline_recorded_at"`
+		Drifted            bool              `json:"drifted"`
+		Added              []instanceRef     `json:"added"`
+		Removed            []instanceRef     `json:"removed"`
+		Changed            []changedInstance `json:"changed"`
+	}
+
+	result := driftResult{
+		Baseline:           name,
+		BaselineRecordedAt: baseline.RecordedAt,
+		Added:              []instanceRef{},
+		Removed:            []instanceRef{},
+		Changed:            []changedInstance{},
+	}
+
+	before := map[string]instanceDetails{}
+	for _, inst := range baseline.Instances {
+		before[inst.Id] = inst
+	}
+
+	after := map[string]bool{}
+	for _, now := range current {
+		after[now.Id] = true
+
+		then, existed := before[now.Id]
+		if !existed {
+			result.Added = append(result.Added, instanceRef{Id: now.Id, Name: now.Name})
+			continue
+		}
+
+		changed := changedInstance{Id: now.Id, Name: now.Name, Kinds: []string{}, Changes: []fieldChange{}}
+		for _, f := range []struct {
+			field, kind, before, after string
+		}{
+			{"name", "renamed", then.Name, now.Name},
+			{"status", "status_changed", then.Status, now.Status},
+			{"memory", "resized", then.Memory, now.Memory},
+			{"storage", "resized", derefString(then.Storage), derefString(now.Storage)},
+			{"type", "changed", then.Type, now.Type},
+			{"region", "changed", then.Region, now.Region},
+			{"cloud_provider", "changed", then.CloudProvider, now.CloudProvider},
+			{"tenant_id", "changed", then.TenantId, now.TenantId},
+			{"connection_url", "changed", then.ConnectionUrl, now.ConnectionUrl},
+		} {
+			if f.before == f.after {
+				continue
+			}
+			changed.Changes = append(changed.Changes, fieldChange{Field: f.field, Before: f.before, After: f.after})
+			if len(changed.Kinds) == 0 || changed.Kinds[len(changed.Kinds)-1] != f.kind {
+				changed.Kinds = append(changed.Kinds, f.kind)
+			}
+		}
+
+		if len(changed.Changes) > 0 {
+			result.Changed = append(result.Changed, changed)
+		}
+	}
+
+	for _, then := range baseline.Instances {
+		if !after[then.Id] {
+			result.Removed = append(result.Removed, instanceRef{Id: then.Id, Name: then.Name})
+		}
+	}
+
+	result.Drifted = len(result.Added)+len(result.Removed)+len(result.Changed) > 0
+
+	jsonData, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to serialize results: %v", err)), nil
+	}
+
+	return mcp.NewToolResultText(string(jsonData)), nil
+}
+
+// baselinePath returns the file a named baseline is kept in, and the name
+func baselinePath(deps *Dependencies, parameters map[string]interface{}) (string, string, error) {
+	name, _ := parameters["baseline"].(string)
+	if name == "" {
+		name = "default"
+	}
+
+	if !validBaselineName.MatchString(name) {
+		return "", "", fmt.Errorf("Invalid baseline name '%s'. Use letters, numbers, '-' and '_' only", name)
+	}
+
+	if deps.Config == nil || deps.Config.StateDir == "" {
+		return "", "", fmt.Errorf("No state directory is configured. Set STATE_DIR to use baselines")
+	}
+
+	return filepath.Join(deps.Config.StateDir, "baselines", name+".json"), name, nil
+}
+
+// currentFleetDetails returns the get-instance-details fields of every instance, ordered by ID.
+// Unlike fleet-inventory every instance must be fetched, as a missing one would look like a removal
+func currentFleetDetails(ctx context.Context, deps *Dependencies) ([]instanceDetails, error) {
+	instances, err := deps.AClient.Instances.List()
+	if err != nil {
+		return nil, fmt.Errorf("Failed to list instances: %v", err)
+	}
+
+	ids := make([]string, 0, len(instances.Data))
+	for _, inst := range instances.Data {
+		ids = append(ids, inst.Id)
+	}
+	sort.Strings(ids)
+
+	details, failures := fetchInstanceDetails(ctx, deps, ids)
+
+	result := make([]instanceDetails, 0, len(ids))
+	for _, id := range ids {
+		if err, failed := failures[id]; failed {
+			return nil, fmt.Errorf("Failed to retrieve details of instance %s: %v. Try again", id, err)
+		}
+		result = append(result, newInstanceDetails(details[id]))
+	}
+
+	return result, nil
+}
+
+// derefString returns the value of a string pointer, or an empty string if it is nil
+func derefString(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
@@ -0,0 +1,53 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	aura "github.com/LackOfMorals/aura-client"
+)
+
+func TestRecordBaselineDryRun(t *testing.T) {
+	tests := []struct {
+		name        string
+		parameters  map[string]interface{}
+		configDry   bool // DRY_RUN is set
+		wantDryRun  bool
+		wantWritten bool
+	}{
+		{name: "recorded", parameters: map[string]interface{}{}, wantWritten: true},
+		{name: "dry_run parameter", parameters: map[string]interface{}{"dry_run": true}, wantDryRun: true},
+		{name: "DRY_RUN", parameters: map[string]interface{}{}, configDry: true, wantDryRun: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			deps := newTestDependencies(t, newFakeInstances(
+				fakeInstance{GetInstanceData: aura.GetInstanceData{Id: "a1", Name: "dev", Status: "running", TenantId: "t1"}},
+			))
+			deps.Config.DryRun = tt.configDry
+
+			result, err := deps.OutComes.ExecuteOutcome(context.Background(), "record-baseline", tt.parameters, deps)
+			if err != nil || result.IsError {
+				t.Fatalf("result = %s, %v", toolResultText(result), err)
+			}
+
+			var summary struct {
+				DryRun       bool     `json:"dry_run"`
+				LocalChanges []string `json:"local_changes"`
+			}
+			_ = json.Unmarshal([]byte(toolResultText(result)), &summary)
+			if summary.DryRun != tt.wantDryRun || (tt.wantDryRun && len(summary.LocalChanges) != 1) {
+				t.Fatalf("result = %s, want a dry run %t that reports the baseline it would save", toolResultText(result), tt.wantDryRun)
+			}
+
+			_, err = os.Stat(filepath.Join(deps.Config.StateDir, "baselines", "default.json"))
+			if written := err == nil; written != tt.wantWritten {
+				t.Fatalf("baseline written = %t, want %t", written, tt.wantWritten)
+			}
+		})
+	}
+}
@@ -0,0 +1,265 @@
+// dry_run.go lets any outcome that makes changes be run as a dry run. The parameters are
+// checked and the target is looked up as usual, but the Aura API calls that would make a
+// change are recorded instead of being sent, and are returned to the caller
+
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	aura "github.com/LackOfMorals/aura-client"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// errDryRun is returned by the Aura API client in place of making a change during a dry run
+var errDryRun = errors.New("not sent as this is a dry run")
+
+// auraAPICall is an Aura API call that would have been made
+type auraAPICall struct {
+	Method string      `json:"method"`
+	Path   string      `json:"path"`
+	Body   interface{} `json:"body,omitempty"`
+}
+
+// dryRun collects what a dry run would have done
+type dryRun struct {
+	mu           sync.Mutex
+	calls        []auraAPICall
+	localChanges []string
+	stops        int
+}
+
+type dryRunKey struct{}
+
+// makesChanges returns true if an outcome changes anything so can be run as a dry run. That is
+// every outcome that is not read-only, and read-only outcomes that only change the local state
+// of this server, which are marked with "local_changes" in their metadata
+func makesChanges(outcome *Outcome) bool {
+	localChanges, _ := outcome.Metadata["local_changes"].(bool)
+	return !outcome.ReadOnly || localChanges
+}
+
+// withDryRun returns a context that marks everything run with it as part of a dry run
+func withDryRun(ctx context.Context, dry *dryRun) context.Context {
+	return context.WithValue(ctx, dryRunKey{}, dry)
+}
+
+// dryRunFromContext returns the dry run in progress, or nil if this is not a dry run
+func dryRunFromContext(ctx context.Context) *dryRun {
+	dry, _ := ctx.Value(dryRunKey{}).(*dryRun)
+	return dry
+}
+
+// apiCall records an Aura API call that would be made
+func (d *dryRun) apiCall(method, path string, body interface{}) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.calls = append(d.calls, auraAPICall{Method: method, Path: path, Body: body})
+}
+
+// localChange records a change that would be made to the local state of this server
+func (d *dryRun) localChange(format string, args ...interface{}) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.localChanges = append(d.localChanges, fmt.Sprintf(format, args...))
+}
+
+// stop records an API call that was stopped by the client and returns the error to give the caller
+func (d *dryRun) stop(method, path string, body interface{}) error {
+	d.apiCall(method, path, body)
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.stops++
+	return errDryRun
+}
+
+// stopCount returns how many API calls the client has stopped so far
+func (d *dryRun) stopCount() int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.stops
+}
+
+// result is returned by an outcome that has recorded what it would do. It is replaced by the
+// summary of the whole dry run
+func (d *dryRun) result() *mcp.CallToolResult {
+	return mcp.NewToolResultText("Dry run recorded")
+}
+
+// summary reports everything the dry run would have done, along with the token to confirm it if there is one
+func (d *dryRun) summary(outcomeID string, confirmation *confirmationToken) (*mcp.CallToolResult, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	type dryRunSummary struct {
+		DryRun       bool          `json:"dry_run"`
+		OutcomeId    string        `json:"outcome_id"`
+		Message      string        `json:"message"`
+		APICalls     []auraAPICall `json:"api_calls"`
+		LocalChanges []string      `json:"local_changes,omitempty"`
+		*confirmationToken
+	}
+
+	result := dryRunSummary{
+		DryRun:       true,
+		OutcomeId:    outcomeID,
+		Message:      "Dry run. The parameters are valid and nothing has been changed. These are the Aura API calls that would be made, in order",
+		APICalls:     append([]auraAPICall{}, d.calls...),
+		LocalChanges: d.localChanges,
+	}
+	if len(result.APICalls) == 0 {
+		result.Message = "Dry run. The parameters are valid and nothing has been changed. No Aura API calls would be made"
+	}
+	if confirmation != nil {
+		result.DryRun = false
+		result.confirmationToken = confirmation
+		result.Message = fmt.Sprintf("Nothing has been changed yet. Check the Aura API calls below, which will be made in order. To go ahead, call '%s' again with exactly the same parameters plus 'confirmation_token'. The token can only be used once and expires at %s", outcomeID, confirmation.ExpiresAt.Format(time.RFC3339))
+	}
+
+	jsonData, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to serialize results: %v", err)), nil
+	}
+
+	return mcp.NewToolResultText(string(jsonData)), nil
+}
+
+// client returns a copy of client that reads from Aura as usual but records and stops every
+// call that would make a change. This makes sure nothing is changed even by an outcome that
+// does not check for a dry run itself
+func (d *dryRun) client(client *aura.AuraAPIClient) *aura.AuraAPIClient {
+	if client == nil {
+		return nil
+	}
+	dryClient := *client
+	dryClient.Instances = dryRunInstances{InstanceService: client.Instances, dry: d}
+	dryClient.Snapshots = dryRunSnapshots{SnapshotService: client.Snapshots, dry: d}
+	return &dryClient
+}
+
+// dryRunInstances stops every instance call that makes a change
+type dryRunInstances struct {
+	aura.InstanceService
+	dry *dryRun
+}
+
+func (i dryRunInstances) Create(instanceRequest *aura.CreateInstanceConfigData) (*aura.CreateInstanceResponse, error) {
+	return nil, i.dry.stop("POST", "/instances", instanceRequest)
+}
+
+func (i dryRunInstances) Delete(instanceID string) (*aura.GetInstanceResponse, error) {
+	return nil, i.dry.stop("DELETE", "/instances/"+instanceID, nil)
+}
+
+func (i dryRunInstances) Pause(instanceID string) (*aura.GetInstanceResponse, error) {
+	return nil, i.dry.stop("POST", "/instances/"+instanceID+"/pause", nil)
+}
+
+func (i dryRunInstances) Resume(instanceID string) (*aura.GetInstanceResponse, error) {
+	return nil, i.dry.stop("POST", "/instances/"+instanceID+"/resume", nil)
+}
+
+func (i dryRunInstances) Update(instanceID string, instanceRequest *aura.UpdateInstanceData) (*aura.GetInstanceResponse, error) {
+	return nil, i.dry.stop("PATCH", "/instances/"+instanceID, instanceRequest)
+}
+
+func (i dryRunInstances) Overwrite(instanceID string, sourceInstanceID string, sourceSnapshotID string) (*aura.OverwriteInstanceResponse, error) {
+	return nil, i.dry.stop("POST", "/instances/"+instanceID+"/overwrite", overwriteBody(sourceInstanceID, sourceSnapshotID))
+}
+
+// dryRunSnapshots stops every snapshot call that makes a change
+type dryRunSnapshots struct {
+	aura.SnapshotService
+	dry *dryRun
+}
+
+func (s dryRunSnapshots) Create(instanceID string) (*aura.CreateSnapshotResponse, error) {
+	return nil, s.dry.stop("POST", "/instances/"+instanceID+"/snapshots", nil)
+}
+
+// requester returns an auraRequester that makes GET calls as usual but records and stops every
+// other call
+func (d *dryRun) requester(requester auraRequester) auraRequester {
+	if requester == nil {
+		return nil
+	}
+	return dryRunRequester{auraRequester: requester, dry: d}
+}
+
+// dryRunRequester stops every direct Aura API call that is not a GET
+type dryRunRequester struct {
+	auraRequester
+	dry *dryRun
+}
+
+func (r dryRunRequester) Request(ctx context.Context, method, path string, body, result interface{}) error {
+	if method == http.MethodGet {
+		return r.auraRequester.Request(ctx, method, path, body, result)
+	}
+	return r.dry.stop(method, path, body)
+}
+
+// overwriteBody is the body of an overwrite request
+func overwriteBody(sourceInstanceID, sourceSnapshotID string) map[string]string {
+	body := map[string]string{"source_instance_id": sourceInstanceID}
+	if sourceSnapshotID != "" {
+		body["source_snapshot_id"] = sourceSnapshotID
+	}
+	return body
+}
+
+// dryRunParameter is added to every outcome that makes changes
+var dryRunParameter = OutcomeParameter{
+	Name:        "dry_run",
+	Type:        "boolean",
+	Description: "Check the parameters and return the Aura API calls that would be made without changing anything",
+	Required:    false,
+	Default:     false,
+}
+
+// executeDryRun runs the handler of an outcome as part of a dry run. Outcomes run by another
+// outcome, such as those run by apply-desired-state, add to the dry run of the outcome that
+// ran them and the summary is only returned by the first. If confirm is true and the dry run
+// succeeds, a confirmation token for the same parameters is returned with the summary
+func (r *OutcomeRegistry) executeDryRun(ctx context.Context, outcome *Outcome, parameters map[string]interface{}, deps *Dependencies, confirm bool) (*mcp.CallToolResult, error) {
+	dry := dryRunFromContext(ctx)
+	nested := dry != nil
+	if !nested {
+		dry = &dryRun{}
+		ctx = withDryRun(ctx, dry)
+	}
+
+	dryDeps := *deps
+	dryDeps.AClient = dry.client(deps.AClient)
+	dryDeps.AuraAPI = dry.requester(deps.AuraAPI)
+
+	stops := dry.stopCount()
+	result, err := outcome.Handler(ctx, parameters, &dryDeps)
+	if err != nil {
+		return nil, err
+	}
+
+	// An error that was not caused by stopping a call means the parameters or target are not valid
+	if result == nil || (result.IsError && dry.stopCount() == stops) {
+		return result, nil
+	}
+
+	if nested {
+		return dry.result(), nil
+	}
+	if !confirm {
+		return dry.summary(outcome.ID, nil)
+	}
+
+	confirmation, err := r.confirmations.Issue(outcome.ID, parameters)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	return dry.summary(outcome.ID, &confirmation)
+}
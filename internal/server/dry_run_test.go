@@ -0,0 +1,167 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+
+	aura "github.com/LackOfMorals/aura-client"
+)
+
+func TestDryRunClient(t *testing.T) {
+	tests := []struct {
+		name     string
+		call     func(client *aura.AuraAPIClient) error
+		wantCall string // Method and path of the call that is stopped. Empty if the call is sent
+	}{
+		{name: "list instances", call: func(c *aura.AuraAPIClient) error { _, err := c.Instances.List(); return err }},
+		{name: "get instance", call: func(c *aura.AuraAPIClient) error { _, err := c.Instances.Get("a1"); return err }},
+		{name: "list snapshots", call: func(c *aura.AuraAPIClient) error { _, err := c.Snapshots.List("a1", ""); return err }},
+		{name: "create instance", call: func(c *aura.AuraAPIClient) error {
+			_, err := c.Instances.Create(&aura.CreateInstanceConfigData{Name: "x"})
+			return err
+		}, wantCall: "POST /instances"},
+		{name: "delete instance", call: func(c *aura.AuraAPIClient) error { _, err := c.Instances.Delete("a1"); return err }, wantCall: "DELETE /instances/a1"},
+		{name: "pause instance", call: func(c *aura.AuraAPIClient) error { _, err := c.Instances.Pause("a1"); return err }, wantCall: "POST /instances/a1/pause"},
+		{name: "resume instance", call: func(c *aura.AuraAPIClient) error { _, err := c.Instances.Resume("a1"); return err }, wantCall: "POST /instances/a1/resume"},
+		{name: "update instance", call: func(c *aura.AuraAPIClient) error {
+			_, err := c.Instances.Update("a1", &aura.UpdateInstanceData{Name: "y"})
+			return err
+		}, wantCall: "PATCH /instances/a1"},
+		{name: "overwrite instance", call: func(c *aura.AuraAPIClient) error { _, err := c.Instances.Overwrite("a1", "b1", ""); return err }, wantCall: "POST /instances/a1/overwrite"},
+		{name: "create snapshot", call: func(c *aura.AuraAPIClient) error { _, err := c.Snapshots.Create("a1"); return err }, wantCall: "POST /instances/a1/snapshots"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			instances := newFakeInstances(
+				fakeInstance{GetInstanceData: aura.GetInstanceData{Id: "a1", Name: "dev", Status: "running", TenantId: "t1"}},
+				fakeInstance{GetInstanceData: aura.GetInstanceData{Id: "b1", Name: "prod", Status: "running", TenantId: "t1"}},
+			)
+			snapshots := &fakeSnapshots{}
+			dry := &dryRun{}
+			client := dry.client(&aura.AuraAPIClient{Instances: instances, Snapshots: snapshots})
+
+			err := tt.call(client)
+			if tt.wantCall == "" {
+				if err != nil {
+					t.Fatalf("call error = %v, want it to be sent", err)
+				}
+				if len(dry.calls) != 0 {
+					t.Fatalf("recorded calls = %+v, want none", dry.calls)
+				}
+				return
+			}
+
+			if !errors.Is(err, errDryRun) {
+				t.Fatalf("call error = %v, want errDryRun", err)
+			}
+			if len(dry.calls) != 1 || dry.calls[0].Method+" "+dry.calls[0].Path != tt.wantCall {
+				t.Fatalf("recorded calls = %+v, want %s", dry.calls, tt.wantCall)
+			}
+			if dry.stopCount() != 1 {
+				t.Fatalf("stopCount() = %d, want 1", dry.stopCount())
+			}
+			if len(instances.Changes()) != 0 || snapshots.created != 0 {
+				t.Fatalf("the call reached the Aura API: %v", instances.Changes())
+			}
+		})
+	}
+}
+
+func TestDryRunRequester(t *testing.T) {
+	tests := []struct {
+		method   string
+		path     string
+		wantSent bool
+	}{
+		{method: http.MethodGet, path: "/customer-managed-keys", wantSent: true},
+		{method: http.MethodPost, path: "/customer-managed-keys"},
+		{method: http.MethodPatch, path: "/instances/a1"},
+		{method: http.MethodDelete, path: "/customer-managed-keys/k1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.method, func(t *testing.T) {
+			requester := &fakeRequester{}
+			dry := &dryRun{}
+
+			err := dry.requester(requester).Request(context.Background(), tt.method, tt.path, nil, nil)
+			sent := len(requester.calls) == 1
+			if sent != tt.wantSent {
+				t.Fatalf("sent = %t, want %t", sent, tt.wantSent)
+			}
+			if tt.wantSent {
+				if err != nil || len(dry.calls) != 0 {
+					t.Fatalf("Request() error = %v, recorded calls = %+v, want it sent and not recorded", err, dry.calls)
+				}
+				return
+			}
+			if !errors.Is(err, errDryRun) || len(dry.calls) != 1 || dry.calls[0].Path != tt.path {
+				t.Fatalf("Request() error = %v, recorded calls = %+v, want it stopped and recorded", err, dry.calls)
+			}
+		})
+	}
+}
+
+func TestExecuteOutcomeDryRun(t *testing.T) {
+	tests := []struct {
+		name       string
+		outcome    string
+		parameters map[string]interface{}
+		wantCalls  []string // Empty if the parameters are not valid
+	}{
+		{name: "pause", outcome: "pause-instance", parameters: map[string]interface{}{"instance_id": "a1"}, wantCalls: []string{"POST /instances/a1/pause"}},
+		{name: "delete", outcome: "delete-instance", parameters: map[string]interface{}{"instance_id": "a1"}, wantCalls: []string{"DELETE /instances/a1"}},
+		{name: "overwrite", outcome: "overwrite-instance", parameters: map[string]interface{}{"target_instance_id": "a1", "source_instance_id": "b1"}, wantCalls: []string{"POST /instances/a1/overwrite"}},
+		{name: "unknown instance", outcome: "delete-instance", parameters: map[string]interface{}{"instance_id": "zz"}},
+		{name: "not in a state to change", outcome: "resume-instance", parameters: map[string]interface{}{"instance_id": "a1"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			instances := newFakeInstances(
+				fakeInstance{GetInstanceData: aura.GetInstanceData{Id: "a1", Name: "dev", Status: "running", TenantId: "t1"}},
+				fakeInstance{GetInstanceData: aura.GetInstanceData{Id: "b1", Name: "prod", Status: "running", TenantId: "t1"}},
+			)
+			deps := newTestDependencies(t, instances)
+			parameters := map[string]interface{}{"dry_run": true}
+			for key, value := range tt.parameters {
+				parameters[key] = value
+			}
+
+			result, err := deps.OutComes.ExecuteOutcome(context.Background(), tt.outcome, parameters, deps)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(instances.Changes()) != 0 {
+				t.Fatalf("changes = %v, want none from a dry run", instances.Changes())
+			}
+			if len(tt.wantCalls) == 0 {
+				if !result.IsError {
+					t.Fatalf("result = %s, want an error", toolResultText(result))
+				}
+				return
+			}
+
+			var summary struct {
+				DryRun   bool          `json:"dry_run"`
+				Token    string        `json:"confirmation_token"`
+				APICalls []auraAPICall `json:"api_calls"`
+			}
+			if err := json.Unmarshal([]byte(toolResultText(result)), &summary); err != nil {
+				t.Fatalf("failed to parse the summary %s: %v", toolResultText(result), err)
+			}
+			var got []string
+			for _, call := range summary.APICalls {
+				got = append(got, call.Method+" "+call.Path)
+			}
+			if !summary.DryRun || summary.Token != "" || strings.Join(got, ",") != strings.Join(tt.wantCalls, ",") {
+				t.Fatalf("summary = %+v, want a dry run without a token that would call %v", summary, tt.wantCalls)
+			}
+		})
+	}
+}
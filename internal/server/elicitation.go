@@ -0,0 +1,138 @@
+// elicitation.go asks the user, through their MCP client, to confirm a destructive outcome
+// before it is run, so that the decision is made by a person rather than the model
+
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// instanceParameterNames are the parameters that name the instance a destructive outcome acts on
+var instanceParameterNames = []string{"instance_id", "target_instance_id", "source_instance_id"}
+
+// needsUserConfirmation returns true if the user should be asked before running outcome. Dry
+// runs change nothing, and nothing can be changed in read-only mode, so neither need it
+func needsUserConfirmation(outcome *Outcome, parameters map[string]interface{}, deps *Dependencies) bool {
+	if !isDestructive(outcome) {
+		return false
+	}
+	if dryRun, _ := parameters["dry_run"].(bool); dryRun {
+		return false
+	}
+	if deps.Config != nil && (deps.Config.DryRun || deps.Config.ReadOnly) {
+		return false
+	}
+	return true
+}
+
+// clientSupportsElicitation returns true if the client said it can ask the user for input
+func clientSupportsElicitation(ctx context.Context) bool {
+	session, ok := server.ClientSessionFromContext(ctx).(server.SessionWithClientInfo)
+	if !ok {
+		return false
+	}
+	return session.GetClientCapabilities().Elicitation != nil
+}
+
+// confirmWithUser asks the user to accept a destructive outcome. If they accept, the returned
+// context marks it as confirmed so no confirmation token is needed. If the client cannot ask,
+// the ELICITATION_FALLBACK setting decides whether to carry on with a confirmation token, or to
+// refuse. The confirmation token replaces the confirm parameter that destructive outcomes used
+// to take, so the confirm fallback means the token flow. A result is returned if the outcome
+// must not be run
+func confirmWithUser(ctx context.Context, outcome *Outcome, parameters map[string]interface{}, deps *Dependencies) (context.Context, *mcp.CallToolResult) {
+	fallback := func() (context.Context, *mcp.CallToolResult) {
+		if deps.Config != nil && deps.Config.ElicitationFallback == "refuse" {
+			return ctx, mcp.NewToolResultError(fmt.Sprintf("'%s' must be confirmed by the user but their MCP client cannot ask them. Nothing has been changed", outcome.ID))
+		}
+		return ctx, nil
+	}
+
+	mcpServer := server.ServerFromContext(ctx)
+	if mcpServer == nil || !clientSupportsElicitation(ctx) {
+		return fallback()
+	}
+
+	// Check the parameters and find out what would be done before asking
+	dry := &dryRun{}
+	result, err := deps.OutComes.ExecuteOutcome(withDryRun(ctx, dry), outcome.ID, parameters, deps)
+	if err != nil {
+		return ctx, mcp.NewToolResultError(err.Error())
+	}
+	if result.IsError {
+		return ctx, result
+	}
+
+	answer, err := mcpServer.RequestElicitation(ctx, mcp.ElicitationRequest{
+		Params: mcp.ElicitationParams{
+			Message: confirmationMessage(outcome, parameters, dry, deps),
+			RequestedSchema: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"confirm": map[string]interface{}{
+						"type":        "boolean",
+						"title":       "Go ahead",
+						"description": "Tick to confirm you want this to happen",
+					},
+				},
+				"required": []string{"confirm"},
+			},
+		},
+	})
+	if errors.Is(err, server.ErrElicitationNotSupported) {
+		return fallback()
+	}
+	if err != nil {
+		return ctx, mcp.NewToolResultError(fmt.Sprintf("Failed to ask the user to confirm '%s': %v. Nothing has been changed", outcome.ID, err))
+	}
+
+	content, _ := answer.Content.(map[string]interface{})
+	if answer.Action != mcp.ElicitationResponseActionAccept || content["confirm"] != true {
+		return ctx, mcp.NewToolResultError(fmt.Sprintf("The user did not confirm '%s'. Nothing has been changed", outcome.ID))
+	}
+
+	return withConfirmation(ctx), nil
+}
+
+// confirmationMessage describes a destructive outcome for the user to confirm
+func confirmationMessage(outcome *Outcome, parameters map[string]interface{}, dry *dryRun, deps *Dependencies) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s\n", outcome.Name)
+
+	for _, name := range instanceParameterNames {
+		instanceID, _ := parameters[name].(string)
+		if instanceID == "" {
+			continue
+		}
+		label := strings.ReplaceAll(strings.TrimSuffix(name, "_id"), "_", " ")
+		instanceName := "unknown"
+		if deps.AClient != nil {
+			if info, err := deps.AClient.Instances.Get(instanceID); err == nil {
+				instanceName = info.Data.Name
+			}
+		}
+		fmt.Fprintf(&b, "%s: '%s' (ID: %s)\n", label, instanceName, instanceID)
+	}
+
+	if warning, ok := outcome.Metadata["warning"].(string); ok {
+		fmt.Fprintf(&b, "\nWARNING: %s\n", warning)
+	}
+
+	dry.mu.Lock()
+	defer dry.mu.Unlock()
+	if len(dry.calls) > 0 {
+		b.WriteString("\nThese Aura API calls will be made:\n")
+		for _, call := range dry.calls {
+			fmt.Fprintf(&b, "  %s %s\n", call.Method, call.Path)
+		}
+	}
+
+	b.WriteString("\nDo you want to go ahead?")
+	return b.String()
+}
@@ -0,0 +1,331 @@
+// =============================================================================
+// These are all of the customer managed encryption key ( CMEK ) related outcomes
+//
+// The Aura API client only supports listing encryption keys at this time, so keys
+// are created and deleted, and instances that use one are created, by calling the
+// Aura API directly; see aura_direct.go
+// =============================================================================
+
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+
+	aura "github.com/LackOfMorals/aura-client"
+	"github.com/LackOfMorals/mcp4AuraAPI/internal/config"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// registerListEncryptionKeysOutcome registers the list-encryption-keys outcome
+func (r *OutcomeRegistry) registerListEncryptionKeysOutcome() {
+	r.Outcomes["list-encryption-keys"] = &Outcome{
+		ID:          "list-encryption-keys",
+		Name:        "List Encryption Keys",
+		Description: "Retrieve a list of the customer managed encryption keys ( CMEK ) that can be used with Neo4j Aura instances. Optionally filter to a single tenant. Returns the name, ID and tenant ID of each key.",
+		Type:        OutcomesTypeList,
+		ReadOnly:    true,
+		Parameters: []OutcomeParameter{
+			{
+				Name:        "tenant_id",
+				Type:        "string",
+				Description: "Only return keys that belong to this tenant",
+				Required:    false,
+			},
+		},
+		Metadata: map[string]interface{}{
+			"category": "encryption-keys",
+		},
+		Handler: executeListEncryptionKeys,
+	}
+}
+
+// executeListEncryptionKeys implements the list-encryption-keys outcome
+func executeListEncryptionKeys(ctx context.Context, parameters map[string]interface{}, deps *Dependencies) (*mcp.CallToolResult, error) {
+	if deps.AClient == nil {
+		return mcp.NewToolResultError("Aura API Client is not initialized"), nil
+	}
+
+	tenantID, _ := parameters["tenant_id"].(string)
+
+	// Get the list of keys
+	keys, err := deps.AClient.Cmek.List(tenantID)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to list encryption keys: %v", err)), nil
+	}
+
+	if len(keys.Data) == 0 {
+		return mcp.NewToolResultText("No encryption keys found or user does not have access to any encryption keys."), nil
+	}
+
+	jsonData, err := json.Marshal(keys.Data)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to serialize results: %v", err)), nil
+	}
+
+	return mcp.NewToolResultText(string(jsonData)), nil
+}
+
+// registerGetEncryptionKeyOutcome registers the get-encryption-key outcome
+func (r *OutcomeRegistry) registerGetEncryptionKeyOutcome() {
+	r.Outcomes["get-encryption-key"] = &Outcome{
+		ID:          "get-encryption-key",
+		Name:        "Get Encryption Key",
+		Description: "Retrieve the details of a single customer managed encryption key ( CMEK ).",
+		Type:        OutcomesTypeRead,
+		ReadOnly:    true,
+		Parameters: []OutcomeParameter{
+			{
+				Name:        "key_id",
+				Type:        "string",
+				Description: "The ID of the encryption key to retrieve details for",
+				Required:    true,
+			},
+			{
+				Name:        "tenant_id",
+				Type:        "string",
+				Description: "The ID of the tenant the key belongs to. Speeds up the lookup when supplied",
+				Required:    false,
+			},
+		},
+		Metadata: map[string]interface{}{
+			"category": "encryption-keys",
+		},
+		Handler: executeGetEncryptionKey,
+	}
+}
+
+// executeGetEncryptionKey implements the get-encryption-key outcome
+func executeGetEncryptionKey(ctx context.Context, parameters map[string]interface{}, deps *Dependencies) (*mcp.CallToolResult, error) {
+	if deps.AClient == nil {
+		return mcp.NewToolResultError("Aura API Client is not initialized"), nil
+	}
+
+	// Validate and extract parameters
+	keyID, ok := parameters["key_id"].(string)
+	if !ok || keyID == "" {
+		return mcp.NewToolResultError("'key_id' parameter is required and must be a non-empty string"), nil
+	}
+
+	tenantID, _ := parameters["tenant_id"].(string)
+
+	// The Aura API client only lists keys so search the list for the one that is wanted
+	keys, err := deps.AClient.Cmek.List(tenantID)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to list encryption keys: %v", err)), nil
+	}
+
+	for _, key := range keys.Data {
+		if key.Id != keyID {
+			continue
+		}
+
+		jsonData, err := json.MarshalIndent(key, "", "  ")
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("Failed to serialize encryption key details: %v", err)), nil
+		}
+
+		return mcp.NewToolResultText(string(jsonData)), nil
+	}
+
+	return mcp.NewToolResultError(fmt.Sprintf("Encryption key '%s' not found. The key may not exist or you may not have access to it.", keyID)), nil
+}
+
+// encryptionKey is a customer managed encryption key as returned by the Aura API
+type encryptionKey struct {
+	Id            string `json:"id"`
+	Name          string `json:"name"`
+	TenantId      string `json:"tenant_id"`
+	Status        string `json:"status,omitempty"`
+	Created       string `json:"created,omitempty"`
+	CloudProvider string `json:"cloud_provider,omitempty"`
+	KeyId         string `json:"key_id,omitempty"`
+	Region        string `json:"region,omitempty"`
+	Type          string `json:"type,omitempty"`
+}
+
+// registerCreateEncryptionKeyOutcome registers the create-encryption-key outcome
+func (r *OutcomeRegistry) registerCreateEncryptionKeyOutcome() {
+	r.Outcomes["create-encryption-key"] = &Outcome{
+		ID:          "create-encryption-key",
+		Name:        "Create Encryption Key",
+		Description: "Add a customer managed encryption key ( CMEK ) held in the cloud provider's key management service to a tenant, so that instances can be created that use it. The key starts as 'pending' until Aura can use it; see get-encryption-key.",
+		Type:        OutcomesTypeCreate,
+		ReadOnly:    false,
+		Parameters: []OutcomeParameter{
+			{
+				Name:        "tenant_id",
+				Type:        "string",
+				Description: "The ID of the tenant to add the key to",
+				Required:    true,
+			},
+			{
+				Name:        "name",
+				Type:        "string",
+				Description: "Name for the key in Aura",
+				Required:    true,
+			},
+			{
+				Name:        "kms_key_id",
+				Type:        "string",
+				Description: "The ID of the key in the cloud provider's key management service, such as an AWS KMS key ARN, an Azure Key Vault key URL or a GCP KMS key resource name",
+				Required:    true,
+			},
+			{
+				Name:        "cloud_provider",
+				Type:        "string",
+				Description: "Cloud provider of the key: 'gcp', 'aws', or 'azure'",
+				Required:    true,
+			},
+			{
+				Name:        "region",
+				Type:        "string",
+				Description: "The region of the instances that will use the key",
+				Required:    true,
+			},
+			{
+				Name:        "instance_type",
+				Type:        "string",
+				Description: "The type of the instances that will use the key, such as 'enterprise-db'",
+				Required:    true,
+			},
+		},
+		Metadata: map[string]interface{}{
+			"category": "encryption-keys",
+		},
+		Handler: executeCreateEncryptionKey,
+	}
+}
+
+// executeCreateEncryptionKey implements the create-encryption-key outcome
+func executeCreateEncryptionKey(ctx context.Context, parameters map[string]interface{}, deps *Dependencies) (*mcp.CallToolResult, error) {
+	if deps.AuraAPI == nil {
+		return mcp.NewToolResultError("Aura API Client is not initialized"), nil
+	}
+
+	// Validate and extract required parameters
+	request := map[string]string{}
+	for _, name := range []string{"tenant_id", "name", "kms_key_id", "cloud_provider", "region", "instance_type"} {
+		value, ok := parameters[name].(string)
+		if !ok || value == "" {
+			return mcp.NewToolResultError(fmt.Sprintf("'%s' parameter is required and must be a non-empty string", name)), nil
+		}
+		request[name] = value
+	}
+
+	// The Aura API calls the ID of the key in the key management service key_id
+	request["key_id"] = request["kms_key_id"]
+	delete(request, "kms_key_id")
+
+	if !slices.Contains(config.ValidCloudProviders, request["cloud_provider"]) {
+		return mcp.NewToolResultError(fmt.Sprintf("Invalid cloud_provider '%s'. Must be one of: 'gcp', 'aws', 'azure'", request["cloud_provider"])), nil
+	}
+
+	var response struct {
+		Data encryptionKey `json:"data"`
+	}
+	if err := deps.AuraAPI.Request(ctx, http.MethodPost, "/customer-managed-keys", request, &response); err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to create encryption key: %v", err)), nil
+	}
+
+	jsonData, err := json.MarshalIndent(response.Data, "", "  ")
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to serialize results: %v", err)), nil
+	}
+
+	return mcp.NewToolResultText(string(jsonData)), nil
+}
+
+// registerDeleteEncryptionKeyOutcome registers the delete-encryption-key outcome
+func (r *OutcomeRegistry) registerDeleteEncryptionKeyOutcome() {
+	r.Outcomes["delete-encryption-key"] = &Outcome{
+		ID:          "delete-encryption-key",
+		Name:        "Delete Encryption Key",
+		Description: "Remove a customer managed encryption key ( CMEK ) from Aura. The key itself is left in the cloud provider's key management service.",
+		Type:        OutcomesTypeDelete,
+		ReadOnly:    false,
+		Parameters: []OutcomeParameter{
+			{
+				Name:        "key_id",
+				Type:        "string",
+				Description: "The Aura ID of the encryption key to delete, as returned by list-encryption-keys",
+				Required:    true,
+			},
+		},
+		Metadata: map[string]interface{}{
+			"category":    "encryption-keys",
+			"destructive": true,
+			"warning":     "Instances that use this key can no longer be created, and Aura may not be able to decrypt existing instances that use it.",
+		},
+		Handler: executeDeleteEncryptionKey,
+	}
+}
+
+// executeDeleteEncryptionKey implements the delete-encryption-key outcome
+func executeDeleteEncryptionKey(ctx context.Context, parameters map[string]interface{}, deps *Dependencies) (*mcp.CallToolResult, error) {
+	if deps.AuraAPI == nil {
+		return mcp.NewToolResultError("Aura API Client is not initialized"), nil
+	}
+
+	keyID, ok := parameters["key_id"].(string)
+	if !ok || keyID == "" {
+		return mcp.NewToolResultError("'key_id' parameter is required and must be a non-empty string"), nil
+	}
+	keyPath, err := resourcePath("/customer-managed-keys", keyID)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Invalid 'key_id': %v", err)), nil
+	}
+
+	// Get the key first to check it exists and report what was deleted
+	var key struct {
+		Data encryptionKey `json:"data"`
+	}
+	if err := deps.AuraAPI.Request(ctx, http.MethodGet, keyPath, nil, &key); err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to retrieve encryption key before deletion: %v. The key may not exist or you may not have access to it.", err)), nil
+	}
+
+	if err := deps.AuraAPI.Request(ctx, http.MethodDelete, keyPath, nil, nil); err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to delete encryption key: %v", err)), nil
+	}
+
+	result := struct {
+		Success     bool   `json:"success"`
+		Message     string `json:"message"`
+		DeletedID   string `json:"deleted_id"`
+		DeletedName string `json:"deleted_name"`
+	}{
+		Success:     true,
+		Message:     fmt.Sprintf("Encryption key '%s' (ID: %s) has been deleted from Aura", key.Data.Name, keyID),
+		DeletedID:   keyID,
+		DeletedName: key.Data.Name,
+	}
+
+	jsonData, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to serialize results: %v", err)), nil
+	}
+
+	return mcp.NewToolResultText(string(jsonData)), nil
+}
+
+// createInstanceWithKey creates an instance encrypted with a customer managed key. The Aura API
+// client cannot set the key so the instance is created by calling the Aura API directly
+func createInstanceWithKey(ctx context.Context, deps *Dependencies, definition *aura.CreateInstanceConfigData, keyID string) (*aura.CreateInstanceResponse, error) {
+	if deps.AuraAPI == nil {
+		return nil, fmt.Errorf("Aura API Client is not initialized")
+	}
+
+	request := struct {
+		*aura.CreateInstanceConfigData
+		CustomerManagedKeyId string `json:"customer_managed_key_id"`
+	}{definition, keyID}
+
+	var response aura.CreateInstanceResponse
+	if err := deps.AuraAPI.Request(ctx, http.MethodPost, "/instances", request, &response); err != nil {
+		return nil, err
+	}
+	return &response, nil
+}
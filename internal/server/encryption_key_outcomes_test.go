@@ -0,0 +1,71 @@
+package server
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestResourcePath(t *testing.T) {
+	tests := []struct {
+		id       string
+		wantPath string // Empty if the ID is refused
+	}{
+		{id: "key-1", wantPath: "/customer-managed-keys/key-1"},
+		{id: "key 1", wantPath: "/customer-managed-keys/key%201"},
+		{id: "key?x=1", wantPath: "/customer-managed-keys/key%3Fx=1"},
+		{id: "../instances/a1"},
+		{id: "a/b"},
+		{id: ".."},
+		{id: "key..1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.id, func(t *testing.T) {
+			path, err := resourcePath("/customer-managed-keys", tt.id)
+			if tt.wantPath == "" {
+				if err == nil {
+					t.Fatalf("resourcePath(%q) = %s, want it refused", tt.id, path)
+				}
+				return
+			}
+			if err != nil || path != tt.wantPath {
+				t.Fatalf("resourcePath(%q) = %s, %v, want %s", tt.id, path, err, tt.wantPath)
+			}
+		})
+	}
+}
+
+func TestDeleteEncryptionKeyPath(t *testing.T) {
+	tests := []struct {
+		name      string
+		keyID     string
+		wantCalls []string // Empty if the ID is refused
+	}{
+		{name: "key", keyID: "key-1", wantCalls: []string{"GET /customer-managed-keys/key-1", "DELETE /customer-managed-keys/key-1"}},
+		{name: "escaped", keyID: "key 1", wantCalls: []string{"GET /customer-managed-keys/key%201", "DELETE /customer-managed-keys/key%201"}},
+		{name: "another endpoint", keyID: "../instances/a1"},
+		{name: "slash", keyID: "keys/key-1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			requester := &fakeRequester{}
+			deps := &Dependencies{AuraAPI: requester}
+
+			result, err := executeDeleteEncryptionKey(context.Background(), map[string]interface{}{"key_id": tt.keyID}, deps)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(tt.wantCalls) == 0 {
+				if !result.IsError || !strings.Contains(toolResultText(result), "Invalid 'key_id'") || len(requester.calls) != 0 {
+					t.Fatalf("result = %s, calls = %v, want the ID refused before any call", toolResultText(result), requester.calls)
+				}
+				return
+			}
+			if result.IsError || strings.Join(requester.calls, ",") != strings.Join(tt.wantCalls, ",") {
+				t.Fatalf("result = %s, calls = %v, want %v", toolResultText(result), requester.calls, tt.wantCalls)
+			}
+		})
+	}
+}
@@ -0,0 +1,243 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	aura "github.com/LackOfMorals/aura-client"
+	"github.com/LackOfMorals/mcp4AuraAPI/internal/config"
+)
+
+// fakeInstance is an instance held by fakeInstances
+type fakeInstance struct {
+	aura.GetInstanceData
+	Created  string
+	Statuses []string // Statuses reported by Get in turn before settling on Status
+}
+
+// fakeInstances is an in-memory InstanceService. It records every call that makes a change.
+// Methods the tests do not use are left to the embedded nil interface and panic
+type fakeInstances struct {
+	aura.InstanceService
+
+	mu        sync.Mutex
+	instances map[string]*fakeInstance
+	created   int
+	changes   []string
+}
+
+func newFakeInstances(instances ...fakeInstance) *fakeInstances {
+	f := &fakeInstances{instances: map[string]*fakeInstance{}}
+	for i := range instances {
+		f.instances[instances[i].Id] = &instances[i]
+	}
+	return f
+}
+
+// Changes returns the calls that made a change, such as 'DELETE a1'
+func (f *fakeInstances) Changes() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]string{}, f.changes...)
+}
+
+func (f *fakeInstances) notFound() error {
+	return &aura.APIError{StatusCode: http.StatusNotFound, Message: "Not Found"}
+}
+
+func (f *fakeInstances) List() (*aura.ListInstancesResponse, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	response := &aura.ListInstancesResponse{}
+	for _, inst := range f.instances {
+		response.Data = append(response.Data, aura.ListInstanceData{
+			Id:            inst.Id,
+			Name:          inst.Name,
+			Created:       inst.Created,
+			TenantId:      inst.TenantId,
+			CloudProvider: inst.CloudProvider,
+		})
+	}
+	sort.Slice(response.Data, func(i, j int) bool { return response.Data[i].Id < response.Data[j].Id })
+	return response, nil
+}
+
+func (f *fakeInstances) Get(instanceID string) (*aura.GetInstanceResponse, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	inst, ok := f.instances[instanceID]
+	if !ok {
+		return nil, f.notFound()
+	}
+	if len(inst.Statuses) > 0 {
+		inst.Status, inst.Statuses = inst.Statuses[0], inst.Statuses[1:]
+	}
+	return &aura.GetInstanceResponse{Data: inst.GetInstanceData}, nil
+}
+
+func (f *fakeInstances) Create(definition *aura.CreateInstanceConfigData) (*aura.CreateInstanceResponse, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.created++
+	id := fmt.Sprintf("new%d", f.created)
+	f.instances[id] = &fakeInstance{
+		GetInstanceData: aura.GetInstanceData{
+			Id:            id,
+			Name:          definition.Name,
+			Status:        "running",
+			TenantId:      definition.TenantId,
+			CloudProvider: definition.CloudProvider,
+			Region:        definition.Region,
+			Type:          definition.Type,
+			Memory:        definition.Memory,
+		},
+		Created: time.Now().UTC().Format(time.RFC3339),
+	}
+	f.changes = append(f.changes, "POST "+definition.Name)
+	return &aura.CreateInstanceResponse{Data: aura.CreateInstanceData{Id: id, Name: definition.Name, Username: "neo4j", Password: "secret"}}, nil
+}
+
+func (f *fakeInstances) Delete(instanceID string) (*aura.GetInstanceResponse, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	inst, ok := f.instances[instanceID]
+	if !ok {
+		return nil, f.notFound()
+	}
+	delete(f.instances, instanceID)
+	f.changes = append(f.changes, "DELETE "+instanceID)
+	return &aura.GetInstanceResponse{Data: inst.GetInstanceData}, nil
+}
+
+func (f *fakeInstances) Update(instanceID string, definition *aura.UpdateInstanceData) (*aura.GetInstanceResponse, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	inst, ok := f.instances[instanceID]
+	if !ok {
+		return nil, f.notFound()
+	}
+	inst.Name = definition.Name
+	inst.Memory = definition.Memory
+	f.changes = append(f.changes, "PATCH "+instanceID+" "+definition.Name)
+	return &aura.GetInstanceResponse{Data: inst.GetInstanceData}, nil
+}
+
+func (f *fakeInstances) Pause(instanceID string) (*aura.GetInstanceResponse, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	inst, ok := f.instances[instanceID]
+	if !ok {
+		return nil, f.notFound()
+	}
+	inst.Status = "paused"
+	f.changes = append(f.changes, "PAUSE "+instanceID)
+	return &aura.GetInstanceResponse{Data: inst.GetInstanceData}, nil
+}
+
+// Overwrite reports the instance as overwriting to the next Get and running again after that
+func (f *fakeInstances) Overwrite(instanceID, sourceInstanceID, sourceSnapshotID string) (*aura.OverwriteInstanceResponse, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	inst, ok := f.instances[instanceID]
+	if !ok {
+		return nil, f.notFound()
+	}
+	inst.Statuses = []string{"overwriting", "running"}
+	f.changes = append(f.changes, "OVERWRITE "+instanceID+" FROM "+sourceInstanceID)
+	return &aura.OverwriteInstanceResponse{}, nil
+}
+
+// fakeSnapshots is an in-memory SnapshotService whose snapshots complete as soon as they are taken
+type fakeSnapshots struct {
+	mu        sync.Mutex
+	snapshots map[string][]aura.GetSnapshotData
+	created   int
+}
+
+func (f *fakeSnapshots) List(instanceID string, snapshotDate string) (*aura.GetSnapshotsResponse, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return &aura.GetSnapshotsResponse{Data: append([]aura.GetSnapshotData{}, f.snapshots[instanceID]...)}, nil
+}
+
+func (f *fakeSnapshots) Create(instanceID string) (*aura.CreateSnapshotResponse, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.snapshots == nil {
+		f.snapshots = map[string][]aura.GetSnapshotData{}
+	}
+	f.created++
+	id := fmt.Sprintf("snap%d", f.created)
+	f.snapshots[instanceID] = append(f.snapshots[instanceID], aura.GetSnapshotData{
+		InstanceId: instanceID,
+		SnapshotId: id,
+		Status:     "Completed",
+		Timestamp:  time.Now().UTC().Format(time.RFC3339),
+	})
+	return &aura.CreateSnapshotResponse{Data: aura.CreateSnapshotData{SnapshotId: id}}, nil
+}
+
+// fakeTenants is a TenantService where every tenant allows the same instance configurations. It
+// counts the calls to Get
+type fakeTenants struct {
+	aura.TenantService
+	configurations []aura.TenantInstanceConfiguration
+	gets           int
+}
+
+func (f *fakeTenants) Get(tenantID string) (*aura.GetTenantResponse, error) {
+	f.gets++
+	return &aura.GetTenantResponse{Data: aura.TenantResponseData{Id: tenantID, InstanceConfigurations: f.configurations}}, nil
+}
+
+// fakeRequester is an auraRequester that records the calls that reach it. A call whose
+// "METHOD path" is in responses gets that JSON back; any other call returns no data
+type fakeRequester struct {
+	calls     []string
+	responses map[string]string
+}
+
+func (f *fakeRequester) Request(ctx context.Context, method, path string, body, result interface{}) error {
+	f.calls = append(f.calls, method+" "+path)
+	if response, ok := f.responses[method+" "+path]; ok && result != nil {
+		return json.Unmarshal([]byte(response), result)
+	}
+	return nil
+}
+
+// newTestDependencies returns dependencies that use instances in place of the Aura API and keep
+// local state in a temporary directory
+func newTestDependencies(t *testing.T, instances *fakeInstances) *Dependencies {
+	t.Helper()
+	stateDir := t.TempDir()
+	return &Dependencies{
+		AClient: &aura.AuraAPIClient{
+			Tenants:   &fakeTenants{},
+			Instances: instances,
+			Snapshots: &fakeSnapshots{},
+		},
+		Config: &config.Config{
+			StateDir:     stateDir,
+			Identity:     "tester",
+			ReaperPolicy: ttlActionPause,
+		},
+		OutComes:   NewOutcomeRegistry(),
+		TTLs:       NewTTLStore(stateDir),
+		Labels:     NewLabelStore(stateDir),
+		Protection: NewProtectionStore(stateDir),
+	}
+}
@@ -0,0 +1,232 @@
+// =============================================================================
+// These are all of the outcomes that report on the whole fleet of instances
+// =============================================================================
+
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/LackOfMorals/aura-client"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// defaultFleetConcurrency is used when the configuration does not set how many instances to fetch at once
+const defaultFleetConcurrency = 8
+
+// registerFleetInventoryOutcome registers the fleet-inventory outcome
+func (r *OutcomeRegistry) registerFleetInventoryOutcome() {
+	r.Outcomes["fleet-inventory"] = &Outcome{
+		ID:          "fleet-inventory",
+		Name:        "Fleet Inventory",
+		Description: "Report on every Neo4j Aura database instance in one call. Fetches the details of all instances and returns the number of instances, total memory and total storage grouped by status, region, cloud provider, type and tenant, plus the list of paused instances. Instances whose details cannot be fetched are listed separately rather than failing the report.",
+		Type:        OutcomesTypeRead,
+		ReadOnly:    true,
+		Parameters: []OutcomeParameter{
+			{
+				Name:        "tenant_id",
+				Type:        "string",
+				Description: "Only report on instances in this tenant",
+				Required:    false,
+			},
+		},
+		Metadata: map[string]interface{}{
+			"category": "fleet",
+		},
+		Handler: executeFleetInventory,
+	}
+}
+
+// executeFleetInventory implements the fleet-inventory outcome
+func executeFleetInventory(ctx context.Context, parameters map[string]interface{}, deps *Dependencies) (*mcp.CallToolResult, error) {
+	if deps.AClient == nil {
+		return mcp.NewToolResultError("Aura API Client is not initialized"), nil
+	}
+
+	tenantID, _ := parameters["tenant_id"].(string)
+
+	// Get the list of instances
+	instances, err := deps.AClient.Instances.List()
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to list instances: %v", err)), nil
+	}
+
+	ids := []string{}
+	for _, inst := range instances.Data {
+		if tenantID != "" && inst.TenantId != tenantID {
+			continue
+		}
+		ids = append(ids, inst.Id)
+	}
+
+	details, failures := fetchInstanceDetails(ctx, deps, ids)
+
+	// Aggregates for one group of instances
+	type group struct {
+		Count     int `json:"count"`
+		MemoryGB  int `json:"memory_gb"`
+		StorageGB int `json:"storage_gb"`
+	}
+
+	type pausedInstance struct {
+		Id     string `json:"id"`
+		Name   string `json:"name"`
+		Memory string `json:"memory"`
+	}
+
+	type failedInstance struct {
+		Id    string `json:"id"`
+		Error string `json:"error"`
+	}
+
+	type inventory struct {
+		TotalInstances int               `json:"total_instances"`
+		Fetched        int               `json:"fetched"`
+		TotalMemoryGB  int               `json:"total_memory_gb"`
+		TotalStorageGB int               `json:"total_storage_gb"`
+		ByStatus       map[string]*group `json:"by_status"`
+		ByRegion       map[string]*group `json:"by_region"`
+		ByCloud        map[string]*group `json:"by_cloud_provider"`
+		ByType         map[string]*group `json:"by_type"`
+		ByTenant       map[string]*group `json:"by_tenant"`
+		Paused         []pausedInstance  `json:"paused"`
+		Failed         []failedInstance  `json:"failed,omitempty"`
+	}
+
+	report := inventory{
+		TotalInstances: len(ids),
+		Fetched:        len(details),
+		ByStatus:       map[string]*group{},
+		ByRegion:       map[string]*group{},
+		ByCloud:        map[string]*group{},
+		ByType:         map[string]*group{},
+		ByTenant:       map[string]*group{},
+		Paused:         []pausedInstance{},
+	}
+
+	add := func(groups map[string]*group, key string, memoryGB, storageGB int) {
+		g, ok := groups[key]
+		if !ok {
+			g = &group{}
+			groups[key] = g
+		}
+		g.Count++
+		g.MemoryGB += memoryGB
+		g.StorageGB += storageGB
+	}
+
+	for _, id := range ids {
+		inst, ok := details[id]
+		if !ok {
+			continue
+		}
+
+		memoryGB := sizeInGB(inst.Memory)
+		storageGB := 0
+		if inst.Storage != nil {
+			storageGB = sizeInGB(*inst.Storage)
+		}
+
+		report.TotalMemoryGB += memoryGB
+		report.TotalStorageGB += storageGB
+
+		add(report.ByStatus, inst.Status, memoryGB, storageGB)
+		add(report.ByRegion, inst.Region, memoryGB, storageGB)
+		add(report.ByCloud, inst.CloudProvider, memoryGB, storageGB)
+		add(report.ByType, inst.Type, memoryGB, storageGB)
+		add(report.ByTenant, inst.TenantId, memoryGB, storageGB)
+
+		if inst.Status == "paused" {
+			report.Paused = append(report.Paused, pausedInstance{Id: inst.Id, Name: inst.Name, Memory: inst.Memory})
+		}
+	}
+
+	for id, err := range failures {
+		report.Failed = append(report.Failed, failedInstance{Id: id, Error: err.Error()})
+	}
+	sort.Slice(report.Failed, func(i, j int) bool {
+		return report.Failed[i].Id < report.Failed[j].Id
+	})
+
+	jsonData, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to serialize results: %v", err)), nil
+	}
+
+	return mcp.NewToolResultText(string(jsonData)), nil
+}
+
+// fetchInstanceDetails gets the details of the given instances using a bounded pool of workers.
+// The size of the pool comes from the configuration. Instances that cannot be fetched are returned
+// in the second map with the reason rather than stopping the others. Stops early if ctx is cancelled
+func fetchInstanceDetails(ctx context.Context, deps *Dependencies, ids []string) (map[string]*aura.GetInstanceData, map[string]error) {
+	concurrency := defaultFleetConcurrency
+	if deps.Config != nil && deps.Config.FleetConcurrency > 0 {
+		concurrency = deps.Config.FleetConcurrency
+	}
+
+	details := make(map[string]*aura.GetInstanceData, len(ids))
+	failures := make(map[string]error)
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	jobs := make(chan string)
+
+	for range min(concurrency, len(ids)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for id := range jobs {
+				instanceInfo, err := deps.AClient.Instances.Get(id)
+				mu.Lock()
+				if err != nil {
+					failures[id] = err
+				} else {
+					details[id] = &instanceInfo.Data
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+
+	skip := func(remaining []string) {
+		mu.Lock()
+		for _, id := range remaining {
+			failures[id] = ctx.Err()
+		}
+		mu.Unlock()
+	}
+
+send:
+	for i, id := range ids {
+		if ctx.Err() != nil {
+			skip(ids[i:])
+			break
+		}
+		select {
+		case jobs <- id:
+		case <-ctx.Done():
+			skip(ids[i:])
+			break send
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	return details, failures
+}
+
+// sizeInGB converts a size such as '8GB' to a number of GB. Returns 0 if the size is not understood
+func sizeInGB(size string) int {
+	value, err := strconv.Atoi(strings.TrimSuffix(strings.ToUpper(size), "GB"))
+	if err != nil {
+		return 0
+	}
+	return value
+}
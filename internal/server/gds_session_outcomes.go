@@ -0,0 +1,322 @@
+// =============================================================================
+// These are all of the Graph Data Science ( Graph Analytics ) session related outcomes
+//
+// The Aura API client only supports listing sessions at this time, so the size of a
+// session is estimated, and sessions are created and deleted, by calling the Aura API
+// directly; see aura_direct.go
+// =============================================================================
+
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+
+	"github.com/LackOfMorals/aura-client"
+	"github.com/LackOfMorals/mcp4AuraAPI/internal/config"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// registerListGDSSessionsOutcome registers the list-gds-sessions outcome
+func (r *OutcomeRegistry) registerListGDSSessionsOutcome() {
+	r.Outcomes["list-gds-sessions"] = &Outcome{
+		ID:          "list-gds-sessions",
+		Name:        "List GDS Sessions",
+		Description: "Retrieve a list of Aura Graph Analytics serverless sessions. Optionally filter to the sessions attached to a single instance. Returns the name, ID, status, memory, instance, expiry and TTL of each session.",
+		Type:        OutcomesTypeList,
+		ReadOnly:    true,
+		Parameters: []OutcomeParameter{
+			{
+				Name:        "instance_id",
+				Type:        "string",
+				Description: "Only return sessions attached to this instance",
+				Required:    false,
+			},
+		},
+		Metadata: map[string]interface{}{
+			"category": "gds-sessions",
+		},
+		Handler: executeListGDSSessions,
+	}
+}
+
+// executeListGDSSessions implements the list-gds-sessions outcome
+func executeListGDSSessions(ctx context.Context, parameters map[string]interface{}, deps *Dependencies) (*mcp.CallToolResult, error) {
+	if deps.AClient == nil {
+		return mcp.NewToolResultError("Aura API Client is not initialized"), nil
+	}
+
+	instanceID, _ := parameters["instance_id"].(string)
+
+	// Get the list of sessions
+	sessions, err := deps.AClient.GraphAnalytics.List()
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to list GDS sessions: %v", err)), nil
+	}
+
+	records := []aura.GetGDSSessionData{}
+	for _, session := range sessions.Data {
+		if instanceID != "" && session.InstanceId != instanceID {
+			continue
+		}
+		records = append(records, session)
+	}
+
+	if len(records) == 0 {
+		return mcp.NewToolResultText("No GDS sessions found or user does not have access to any GDS sessions."), nil
+	}
+
+	jsonData, err := json.Marshal(records)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to serialize results: %v", err)), nil
+	}
+
+	return mcp.NewToolResultText(string(jsonData)), nil
+}
+
+// gdsAlgorithmCategories are the kinds of algorithm a session can be sized for
+var gdsAlgorithmCategories = []string{"centrality", "community-detection", "machine-learning", "node-embedding", "path-finding", "similarity"}
+
+// registerEstimateGDSSessionOutcome registers the estimate-gds-session outcome
+func (r *OutcomeRegistry) registerEstimateGDSSessionOutcome() {
+	r.Outcomes["estimate-gds-session"] = &Outcome{
+		ID:          "estimate-gds-session",
+		Name:        "Estimate GDS Session",
+		Description: "Estimate the memory an Aura Graph Analytics serverless session needs for a graph of a given size and the algorithms that will be run on it. Use the recommended size as the memory of create-gds-session. Nothing is created.",
+		Type:        OutcomesTypeRead,
+		ReadOnly:    true,
+		Parameters: []OutcomeParameter{
+			{
+				Name:        "node_count",
+				Type:        "number",
+				Description: "The number of nodes in the graph that will be projected",
+				Required:    true,
+			},
+			{
+				Name:        "relationship_count",
+				Type:        "number",
+				Description: "The number of relationships in the graph that will be projected",
+				Required:    true,
+			},
+			{
+				Name:        "algorithm_categories",
+				Type:        "array",
+				Description: "The kinds of algorithm that will be run: 'centrality', 'community-detection', 'machine-learning', 'node-embedding', 'path-finding' or 'similarity'",
+				Required:    false,
+			},
+		},
+		Metadata: map[string]interface{}{
+			"category": "gds-sessions",
+		},
+		Handler: executeEstimateGDSSession,
+	}
+}
+
+// executeEstimateGDSSession implements the estimate-gds-session outcome
+func executeEstimateGDSSession(ctx context.Context, parameters map[string]interface{}, deps *Dependencies) (*mcp.CallToolResult, error) {
+	if deps.AuraAPI == nil {
+		return mcp.NewToolResultError("Aura API Client is not initialized"), nil
+	}
+
+	nodeCount, ok := parameters["node_count"].(float64)
+	if !ok || nodeCount < 0 {
+		return mcp.NewToolResultError("'node_count' parameter is required and must be a number of at least 0"), nil
+	}
+	relationshipCount, ok := parameters["relationship_count"].(float64)
+	if !ok || relationshipCount < 0 {
+		return mcp.NewToolResultError("'relationship_count' parameter is required and must be a number of at least 0"), nil
+	}
+
+	categories := []string{}
+	if values, ok := parameters["algorithm_categories"].([]interface{}); ok {
+		for _, value := range values {
+			category, _ := value.(string)
+			if !slices.Contains(gdsAlgorithmCategories, category) {
+				return mcp.NewToolResultError(fmt.Sprintf("Invalid algorithm category '%v'. Must be one of: %v", value, gdsAlgorithmCategories)), nil
+			}
+			categories = append(categories, category)
+		}
+	}
+
+	request := map[string]interface{}{
+		"node_count":           int64(nodeCount),
+		"relationship_count":   int64(relationshipCount),
+		"algorithm_categories": categories,
+	}
+
+	var response struct {
+		Data struct {
+			EstimatedMemory string `json:"estimated_memory"`
+			RecommendedSize string `json:"recommended_size"`
+		} `json:"data"`
+	}
+	if err := deps.AuraAPI.Request(ctx, http.MethodPost, "/graph-analytics/sessions/sizing", request, &response); err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to estimate GDS session size: %v", err)), nil
+	}
+
+	jsonData, err := json.MarshalIndent(response.Data, "", "  ")
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to serialize results: %v", err)), nil
+	}
+
+	return mcp.NewToolResultText(string(jsonData)), nil
+}
+
+// registerCreateGDSSessionOutcome registers the create-gds-session outcome
+func (r *OutcomeRegistry) registerCreateGDSSessionOutcome() {
+	r.Outcomes["create-gds-session"] = &Outcome{
+		ID:          "create-gds-session",
+		Name:        "Create GDS Session",
+		Description: "Create an Aura Graph Analytics serverless session, either attached to an instance or standalone in a cloud provider and region. Use estimate-gds-session to choose the memory. The session is deleted by Aura once its TTL passes without it being used.",
+		Type:        OutcomesTypeCreate,
+		ReadOnly:    false,
+		Parameters: []OutcomeParameter{
+			{
+				Name:        "name",
+				Type:        "string",
+				Description: "Name for the new session",
+				Required:    true,
+			},
+			{
+				Name:        "tenant_id",
+				Type:        "string",
+				Description: "The ID of the tenant to create the session in",
+				Required:    true,
+			},
+			{
+				Name:        "memory",
+				Type:        "string",
+				Description: "Memory size for the session, such as '8GB'",
+				Required:    true,
+			},
+			{
+				Name:        "instance_id",
+				Type:        "string",
+				Description: "The ID of the instance to attach the session to. Leave out for a standalone session",
+				Required:    false,
+			},
+			{
+				Name:        "cloud_provider",
+				Type:        "string",
+				Description: "Cloud provider for a standalone session: 'gcp', 'aws', or 'azure'. Required if 'instance_id' is not supplied",
+				Required:    false,
+			},
+			{
+				Name:        "region",
+				Type:        "string",
+				Description: "Region for a standalone session. Required if 'instance_id' is not supplied",
+				Required:    false,
+			},
+			{
+				Name:        "ttl",
+				Type:        "string",
+				Description: "How long the session may be idle before Aura deletes it, such as '1h'",
+				Required:    false,
+			},
+		},
+		Metadata: map[string]interface{}{
+			"category": "gds-sessions",
+		},
+		Handler: executeCreateGDSSession,
+	}
+}
+
+// executeCreateGDSSession implements the create-gds-session outcome
+func executeCreateGDSSession(ctx context.Context, parameters map[string]interface{}, deps *Dependencies) (*mcp.CallToolResult, error) {
+	if deps.AuraAPI == nil {
+		return mcp.NewToolResultError("Aura API Client is not initialized"), nil
+	}
+
+	request := map[string]string{}
+	for _, name := range []string{"name", "tenant_id", "memory"} {
+		value, ok := parameters[name].(string)
+		if !ok || value == "" {
+			return mcp.NewToolResultError(fmt.Sprintf("'%s' parameter is required and must be a non-empty string", name)), nil
+		}
+		request[name] = value
+	}
+
+	// A session is either attached to an instance or placed in a cloud provider and region
+	if instanceID, _ := parameters["instance_id"].(string); instanceID != "" {
+		request["instance_id"] = instanceID
+	} else {
+		cloudProvider, _ := parameters["cloud_provider"].(string)
+		region, _ := parameters["region"].(string)
+		if cloudProvider == "" || region == "" {
+			return mcp.NewToolResultError("Either 'instance_id', or both 'cloud_provider' and 'region', must be supplied"), nil
+		}
+		if !slices.Contains(config.ValidCloudProviders, cloudProvider) {
+			return mcp.NewToolResultError(fmt.Sprintf("Invalid cloud_provider '%s'. Must be one of: 'gcp', 'aws', 'azure'", cloudProvider)), nil
+		}
+		request["cloud_provider"] = cloudProvider
+		request["region"] = region
+	}
+
+	if ttl, _ := parameters["ttl"].(string); ttl != "" {
+		request["ttl"] = ttl
+	}
+
+	var response struct {
+		Data aura.GetGDSSessionData `json:"data"`
+	}
+	if err := deps.AuraAPI.Request(ctx, http.MethodPost, "/graph-analytics/sessions", request, &response); err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to create GDS session: %v", err)), nil
+	}
+
+	jsonData, err := json.MarshalIndent(response.Data, "", "  ")
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to serialize results: %v", err)), nil
+	}
+
+	return mcp.NewToolResultText(string(jsonData)), nil
+}
+
+// registerDeleteGDSSessionOutcome registers the delete-gds-session outcome
+func (r *OutcomeRegistry) registerDeleteGDSSessionOutcome() {
+	r.Outcomes["delete-gds-session"] = &Outcome{
+		ID:          "delete-gds-session",
+		Name:        "Delete GDS Session",
+		Description: "Delete an Aura Graph Analytics serverless session. Any graphs projected into it are lost; results already written back to the instance are kept.",
+		Type:        OutcomesTypeDelete,
+		ReadOnly:    false,
+		Parameters: []OutcomeParameter{
+			{
+				Name:        "session_id",
+				Type:        "string",
+				Description: "The ID of the session to delete",
+				Required:    true,
+			},
+		},
+		Metadata: map[string]interface{}{
+			"category":    "gds-sessions",
+			"destructive": true,
+			"warning":     "Any graphs projected into the session are lost. This cannot be undone.",
+		},
+		Handler: executeDeleteGDSSession,
+	}
+}
+
+// executeDeleteGDSSession implements the delete-gds-session outcome
+func executeDeleteGDSSession(ctx context.Context, parameters map[string]interface{}, deps *Dependencies) (*mcp.CallToolResult, error) {
+	if deps.AuraAPI == nil {
+		return mcp.NewToolResultError("Aura API Client is not initialized"), nil
+	}
+
+	sessionID, ok := parameters["session_id"].(string)
+	if !ok || sessionID == "" {
+		return mcp.NewToolResultError("'session_id' parameter is required and must be a non-empty string"), nil
+	}
+	sessionPath, err := resourcePath("/graph-analytics/sessions", sessionID)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Invalid 'session_id': %v", err)), nil
+	}
+
+	if err := deps.AuraAPI.Request(ctx, http.MethodDelete, sessionPath, nil, nil); err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to delete GDS session: %v", err)), nil
+	}
+
+	return mcp.NewToolResultText(fmt.Sprintf("GDS session '%s' has been deleted", sessionID)), nil
+}
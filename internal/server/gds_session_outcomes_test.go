@@ -0,0 +1,58 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestDeleteGDSSession(t *testing.T) {
+	tests := []struct {
+		name      string
+		sessionID string
+		wantCall  string // Empty if the ID is refused
+	}{
+		{name: "session", sessionID: "s-1", wantCall: "DELETE /graph-analytics/sessions/s-1"},
+		{name: "escaped", sessionID: "s 1", wantCall: "DELETE /graph-analytics/sessions/s%201"},
+		{name: "another endpoint", sessionID: "../../instances/a1"},
+		{name: "slash", sessionID: "s-1/x"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			requester := &fakeRequester{}
+			deps := newTestDependencies(t, newFakeInstances())
+			deps.AuraAPI = requester
+			ctx := context.Background()
+			parameters := map[string]interface{}{"session_id": tt.sessionID}
+
+			// Deleting a session is destructive, so the first call only returns a token
+			result, err := deps.OutComes.ExecuteOutcome(ctx, "delete-gds-session", parameters, deps)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.wantCall == "" {
+				if !result.IsError || !strings.Contains(toolResultText(result), "Invalid 'session_id'") || len(requester.calls) != 0 {
+					t.Fatalf("result = %s, calls = %v, want the ID refused before any call", toolResultText(result), requester.calls)
+				}
+				return
+			}
+			var summary struct {
+				Token string `json:"confirmation_token"`
+			}
+			if err := json.Unmarshal([]byte(toolResultText(result)), &summary); err != nil || summary.Token == "" || len(requester.calls) != 0 {
+				t.Fatalf("first call = %s, calls = %v, want a confirmation token and no calls", toolResultText(result), requester.calls)
+			}
+
+			parameters["confirmation_token"] = summary.Token
+			result, err = deps.OutComes.ExecuteOutcome(ctx, "delete-gds-session", parameters, deps)
+			if err != nil || result.IsError {
+				t.Fatalf("confirmed call = %s, %v", toolResultText(result), err)
+			}
+			if strings.Join(requester.calls, ",") != tt.wantCall {
+				t.Fatalf("calls = %v, want %s", requester.calls, tt.wantCall)
+			}
+		})
+	}
+}
@@ -0,0 +1,119 @@
+// guardrails.go checks every outcome call against the CEL rules in GUARDRAILS_FILE before it
+// is run. The rules see the outcome ID, its parameters, the caller identity, the tenant and the
+// current details of the instance the outcome acts on
+
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/LackOfMorals/mcp4AuraAPI/internal/guardrails"
+)
+
+// checkGuardrails returns an error naming the first rule that does not allow outcome to be run
+// with these parameters. Outcomes run by the server itself, such as by the TTL reaper, are not checked
+func checkGuardrails(ctx context.Context, outcome *Outcome, parameters map[string]interface{}, deps *Dependencies) error {
+	if deps.Config == nil || deps.Config.Guardrails == nil || isServerCaller(ctx) {
+		return nil
+	}
+
+	rules := deps.Config.Guardrails.Current()
+	if !rules.AppliesTo(outcome.ID) {
+		return nil
+	}
+
+	input, err := guardrailInput(ctx, outcome, parameters, deps)
+	if err != nil {
+		return err
+	}
+
+	if violation := rules.Check(input); violation != nil {
+		return violation
+	}
+	return nil
+}
+
+// checkCreateGuardrails checks the parameters of an instance that an outcome other than
+// create-instance, such as clone-instance, is about to create against the create-instance rules,
+// so that creating an instance some other way cannot be used to get round them
+func checkCreateGuardrails(ctx context.Context, createParameters map[string]interface{}, deps *Dependencies) error {
+	if deps.OutComes == nil {
+		return nil
+	}
+	outcome, err := deps.OutComes.GetOutcome("create-instance")
+	if err != nil {
+		return err
+	}
+	if err := checkGuardrails(ctx, outcome, createParameters, deps); err != nil {
+		return fmt.Errorf("the new instance is checked as if by create-instance and %w", err)
+	}
+	return nil
+}
+
+// guardrailInput gathers what the rules can see about an outcome call. Parameters taken from a
+// profile are filled in, so a profile cannot be used to get round a rule
+func guardrailInput(ctx context.Context, outcome *Outcome, parameters map[string]interface{}, deps *Dependencies) (guardrails.Input, error) {
+	input := guardrails.Input{
+		Outcome:    outcome.ID,
+		Parameters: parameters,
+		Identity:   callerIdentity(ctx, deps),
+		Instance:   map[string]interface{}{},
+	}
+
+	// An unknown profile is left for the outcome to report
+	if merged, err := applyProfile(deps, parameters); err == nil {
+		input.Parameters = merged
+	}
+
+	for _, name := range tenantParameterNames {
+		if tenantID, _ := input.Parameters[name].(string); tenantID != "" {
+			input.Tenant = tenantID
+			break
+		}
+	}
+
+	instanceID := ""
+	for _, name := range instanceParameterNames {
+		if id, _ := parameters[name].(string); id != "" {
+			instanceID = id
+			break
+		}
+	}
+	if instanceID == "" || deps.AClient == nil {
+		return input, nil
+	}
+
+	// The rules see the instance as get-instance-details reports it
+	instanceInfo, err := deps.AClient.Instances.Get(instanceID)
+	if err != nil {
+		return input, fmt.Errorf("failed to retrieve instance %s to check guardrails: %w", instanceID, err)
+	}
+	labels, err := deps.Labels.Get(instanceID)
+	if err != nil {
+		return input, fmt.Errorf("failed to load instance labels to check guardrails: %w", err)
+	}
+	details := struct {
+		instanceDetails
+		Owner  string            `json:"owner,omitempty"`
+		Labels map[string]string `json:"labels"`
+	}{
+		instanceDetails: newInstanceDetails(&instanceInfo.Data),
+		Owner:           labels.Owner,
+		Labels:          labels.Labels,
+	}
+
+	data, err := json.Marshal(details)
+	if err != nil {
+		return input, fmt.Errorf("failed to read instance details: %w", err)
+	}
+	if err := json.Unmarshal(data, &input.Instance); err != nil {
+		return input, fmt.Errorf("failed to read instance details: %w", err)
+	}
+
+	if input.Tenant == "" {
+		input.Tenant = instanceInfo.Data.TenantId
+	}
+	return input, nil
+}
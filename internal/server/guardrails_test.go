@@ -0,0 +1,90 @@
+package server
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	aura "github.com/LackOfMorals/aura-client"
+	"github.com/LackOfMorals/mcp4AuraAPI/internal/guardrails"
+)
+
+func TestCreateGuardrailsApplyToEveryNewInstance(t *testing.T) {
+	tests := []struct {
+		name       string
+		outcome    string
+		parameters map[string]interface{}
+		wantRule   string // Empty if the call is allowed
+	}{
+		{name: "create allowed", outcome: "create-instance", parameters: map[string]interface{}{"name": "x", "cloud_provider": "gcp", "region": "us-central1", "memory": "8GB", "type": "professional-db", "tenantId": "t1", "ttl": "4h"}},
+		{name: "create too big", outcome: "create-instance", parameters: map[string]interface{}{"name": "x", "cloud_provider": "gcp", "region": "us-central1", "memory": "32GB", "type": "professional-db", "tenantId": "t1", "ttl": "4h"}, wantRule: "max-memory"},
+		{name: "create without ttl", outcome: "create-instance", parameters: map[string]interface{}{"name": "x", "cloud_provider": "gcp", "region": "us-central1", "memory": "8GB", "type": "professional-db", "tenantId": "t1"}, wantRule: "ttl-required"},
+		{name: "clone allowed", outcome: "clone-instance", parameters: map[string]interface{}{"source_instance_id": "small", "name": "x", "ttl": "4h"}},
+		{name: "clone too big", outcome: "clone-instance", parameters: map[string]interface{}{"source_instance_id": "small", "name": "x", "memory": "32GB", "ttl": "4h"}, wantRule: "max-memory"},
+		{name: "clone takes size from source", outcome: "clone-instance", parameters: map[string]interface{}{"source_instance_id": "big", "name": "x", "ttl": "4h"}, wantRule: "max-memory"},
+		{name: "clone smaller than source", outcome: "clone-instance", parameters: map[string]interface{}{"source_instance_id": "big", "name": "x", "memory": "8GB", "ttl": "4h"}},
+		{name: "clone without ttl", outcome: "clone-instance", parameters: map[string]interface{}{"source_instance_id": "small", "name": "x"}, wantRule: "ttl-required"},
+		{name: "migrate allowed", outcome: "migrate-instance", parameters: map[string]interface{}{"source_instance_id": "small", "target_region": "europe-west1", "ttl": "4h"}},
+		{name: "migrate too big", outcome: "migrate-instance", parameters: map[string]interface{}{"source_instance_id": "big", "target_region": "europe-west1", "ttl": "4h"}, wantRule: "max-memory"},
+		{name: "migrate without ttl", outcome: "migrate-instance", parameters: map[string]interface{}{"source_instance_id": "small", "target_region": "europe-west1"}, wantRule: "ttl-required"},
+	}
+
+	rulesPath := filepath.Join(t.TempDir(), "guardrails.json")
+	rules := `{"rules": [
+		{"name": "max-memory", "outcomes": ["create-instance"], "expression": "gb(params.memory) <= 16"},
+		{"name": "ttl-required", "outcomes": ["create-instance"], "expression": "has(params.ttl)"}
+	]}`
+	if err := os.WriteFile(rulesPath, []byte(rules), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	watcher, err := guardrails.Watch(rulesPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var configurations []aura.TenantInstanceConfiguration
+	for _, region := range []string{"us-central1", "europe-west1"} {
+		for _, memory := range []string{"8GB", "32GB"} {
+			configurations = append(configurations, aura.TenantInstanceConfiguration{CloudProvider: "gcp", Region: region, Memory: memory, Type: "professional-db", Version: "5"})
+		}
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			instances := newFakeInstances(
+				fakeInstance{GetInstanceData: aura.GetInstanceData{Id: "small", Name: "small", Status: "running", TenantId: "t1", CloudProvider: "gcp", Region: "us-central1", Memory: "8GB", Type: "professional-db"}},
+				fakeInstance{GetInstanceData: aura.GetInstanceData{Id: "big", Name: "big", Status: "running", TenantId: "t1", CloudProvider: "gcp", Region: "us-central1", Memory: "32GB", Type: "professional-db"}},
+			)
+			deps := newTestDependencies(t, instances)
+			deps.AClient.Tenants = &fakeTenants{configurations: configurations}
+			deps.Config.Guardrails = watcher
+			for _, id := range []string{"small", "big"} {
+				if _, err := deps.AClient.Snapshots.Create(id); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			parameters := map[string]interface{}{"dry_run": true}
+			for key, value := range tt.parameters {
+				parameters[key] = value
+			}
+			result, err := deps.OutComes.ExecuteOutcome(context.Background(), tt.outcome, parameters, deps)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			text := toolResultText(result)
+			if tt.wantRule == "" && result.IsError {
+				t.Fatalf("%s was not allowed: %s", tt.outcome, text)
+			}
+			if tt.wantRule != "" && (!result.IsError || !strings.Contains(text, "guardrail '"+tt.wantRule+"'")) {
+				t.Fatalf("%s = %s, want guardrail '%s' not to allow it", tt.outcome, text, tt.wantRule)
+			}
+			if len(instances.Changes()) != 0 {
+				t.Fatalf("changes = %v, want none from a dry run", instances.Changes())
+			}
+		})
+	}
+}
@@ -6,22 +6,95 @@ package server
 
 import (
 	"context"
+	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"log/slog"
+	"path"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/LackOfMorals/aura-client"
+	"github.com/LackOfMorals/mcp4AuraAPI/internal/config"
 	"github.com/mark3labs/mcp-go/mcp"
 )
 
+// These are the supported sizes for an instance. They are used to validate
+// parameters when creating or updating an instance
+var supportedMemory = config.ValidMemorySizes
+var supportedStorage = config.ValidStorageSizes
+
 // registerListInstancesOutcome registers the list-instances Outcome
 func (r *OutcomeRegistry) registerListInstancesOutcome() {
 	r.Outcomes["list-instances"] = &Outcome{
 		ID:          "list-instances",
 		Name:        "List Instances",
-		Description: "Retrieve a list of all Neo4j Aura database instances. Returns instance details including name, ID, status, cloud provider, memory size, type, and connection URL.",
+		Description: "Retrieve a list of Neo4j Aura database instances. Returns the name, ID, creation time, tenant ID and cloud provider of each instance. Results can be filtered, sorted and paged; the response includes the total number of matching instances and a cursor for the next page.",
 		Type:        OutcomesTypeList,
 		ReadOnly:    true,
-		Parameters:  []OutcomeParameter{}, // No parameters needed for listing
+		Parameters: []OutcomeParameter{
+			{
+				Name:        "name",
+				Type:        "string",
+				Description: "Only return instances whose name matches this glob pattern, e.g. 'dev-*'",
+				Required:    false,
+			},
+			{
+				Name:        "name_regex",
+				Type:        "string",
+				Description: "Only return instances whose name matches this regular expression",
+				Required:    false,
+			},
+			{
+				Name:        "cloud_provider",
+				Type:        "string",
+				Description: "Only return instances in this cloud provider: 'gcp', 'aws', or 'azure'",
+				Required:    false,
+			},
+			{
+				Name:        "tenant_id",
+				Type:        "string",
+				Description: "Only return instances in this tenant",
+				Required:    false,
+			},
+			{
+				Name:        "created_after",
+				Type:        "string",
+				Description: "Only return instances created after this time. RFC3339 or YYYY-MM-DD",
+				Required:    false,
+			},
+			{
+				Name:        "created_before",
+				Type:        "string",
+				Description: "Only return instances created before this time. RFC3339 or YYYY-MM-DD",
+				Required:    false,
+			},
+			{
+				Name:        "sort_by",
+				Type:        "string",
+				Description: "Field to sort by: 'name', 'created', 'cloud_provider', 'tenant_id' or 'id'. Prefix with '-' to sort in descending order, e.g. '-created'",
+				Required:    false,
+				Default:     "name",
+			},
+			{
+				Name:        "limit",
+				Type:        "number",
+				Description: fmt.Sprintf("Maximum number of instances to return, up to %d", maxListInstancesLimit),
+				Required:    false,
+				Default:     defaultListInstancesLimit,
+			},
+			{
+				Name:        "cursor",
+				Type:        "string",
+				Description: "Cursor from a previous response to get the next page. Use the same filters and sort_by as the previous call",
+				Required:    false,
+			},
+		},
 		Metadata: map[string]interface{}{
 			"category": "instances",
 		},
@@ -29,6 +102,12 @@ func (r *OutcomeRegistry) registerListInstancesOutcome() {
 	}
 }
 
+// These control the page size of list-instances
+const (
+	defaultListInstancesLimit = 50
+	maxListInstancesLimit     = 500
+)
+
 // executeListInstances implements the list-instances Outcome
 func executeListInstances(ctx context.Context, parameters map[string]interface{}, deps *Dependencies) (*mcp.CallToolResult, error) {
 
@@ -40,30 +119,189 @@ func executeListInstances(ctx context.Context, parameters map[string]interface{}
 		return mcp.NewToolResultError("Aura API Client is not initialized"), nil
 	}
 
+	// Validate and extract the optional filters
+	namePattern, _ := parameters["name"].(string)
+	if namePattern != "" {
+		if _, err := path.Match(namePattern, ""); err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("Invalid 'name' glob pattern '%s': %v", namePattern, err)), nil
+		}
+	}
+
+	var nameRegex *regexp.Regexp
+	if v, _ := parameters["name_regex"].(string); v != "" {
+		re, err := regexp.Compile(v)
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("Invalid 'name_regex' '%s': %v", v, err)), nil
+		}
+		nameRegex = re
+	}
+
+	cloudProvider, _ := parameters["cloud_provider"].(string)
+	tenantID, _ := parameters["tenant_id"].(string)
+
+	var createdAfter, createdBefore time.Time
+	if v, _ := parameters["created_after"].(string); v != "" {
+		t, err := parseTimeParameter(v)
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("Invalid 'created_after': %v", err)), nil
+		}
+		createdAfter = t
+	}
+	if v, _ := parameters["created_before"].(string); v != "" {
+		t, err := parseTimeParameter(v)
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("Invalid 'created_before': %v", err)), nil
+		}
+		createdBefore = t
+	}
+
+	sortBy, _ := parameters["sort_by"].(string)
+	if sortBy == "" {
+		sortBy = "name"
+	}
+	descending := strings.HasPrefix(sortBy, "-")
+	sortField := strings.TrimPrefix(sortBy, "-")
+
+	sortKeys := map[string]func(instanceSummary) string{
+		"name":           func(i instanceSummary) string { return strings.ToLower(i.Name) },
+		"created":        func(i instanceSummary) string { return i.Created },
+		"cloud_provider": func(i instanceSummary) string { return i.CloudProvider },
+		"tenant_id":      func(i instanceSummary) string { return i.TenantId },
+		"id":             func(i instanceSummary) string { return i.Id },
+	}
+	sortKey, ok := sortKeys[sortField]
+	if !ok {
+		return mcp.NewToolResultError(fmt.Sprintf("Invalid 'sort_by' '%s'. Must be one of: 'name', 'created', 'cloud_provider', 'tenant_id', 'id', optionally prefixed with '-'", sortBy)), nil
+	}
+
+	limit := defaultListInstancesLimit
+	if v, ok := parameters["limit"].(float64); ok {
+		if v < 1 || v > maxListInstancesLimit {
+			return mcp.NewToolResultError(fmt.Sprintf("'limit' must be between 1 and %d", maxListInstancesLimit)), nil
+		}
+		limit = int(v)
+	}
+
+	offset := 0
+	if v, _ := parameters["cursor"].(string); v != "" {
+		o, err := decodeListCursor(v)
+		if err != nil {
+			return mcp.NewToolResultError("Invalid 'cursor'. Use the next_cursor value from a previous list-instances response"), nil
+		}
+		offset = o
+	}
+
 	// Get the list of instances
 	instances, err := deps.AClient.Instances.List()
 	if err != nil {
 		return mcp.NewToolResultError(fmt.Sprintf("Failed to list instances: %v", err)), nil
 	}
 
+	if len(instances.Data) == 0 {
+		return mcp.NewToolResultText("No instances found or user does not have access to any instances."), nil
+	}
+
 	// Create an empty list
 	records := instanceList{}
 
-	// Fill the list with our instance summary
+	// Instances whose creation time cannot be read are kept rather than hidden by a date filter
+	var unknownCreated []string
+
+	// Fill the list with our instance summary for those that pass the filters
 	for _, inst := range instances.Data {
+		if namePattern != "" {
+			if matched, _ := path.Match(namePattern, inst.Name); !matched {
+				continue
+			}
+		}
+		if nameRegex != nil && !nameRegex.MatchString(inst.Name) {
+			continue
+		}
+		if cloudProvider != "" && inst.CloudProvider != cloudProvider {
+			continue
+		}
+		if tenantID != "" && inst.TenantId != tenantID {
+			continue
+		}
+		if !createdAfter.IsZero() || !createdBefore.IsZero() {
+			created, err := time.Parse(time.RFC3339, inst.Created)
+			switch {
+			case err != nil:
+				unknownCreated = append(unknownCreated, inst.Id)
+			case !createdAfter.IsZero() && !created.After(createdAfter):
+				continue
+			case !createdBefore.IsZero() && !created.Before(createdBefore):
+				continue
+			}
+		}
+
 		records = append(records, instanceSummary{
 			Name:          inst.Name,
 			Id:            inst.Id,
 			Created:       inst.Created,
+			TenantId:      inst.TenantId,
 			CloudProvider: inst.CloudProvider,
 		})
 	}
 
-	if len(records) == 0 {
-		return mcp.NewToolResultText("No instances found or user does not have access to any instances."), nil
+	// Sort, using the ID to keep the order stable between pages
+	sort.SliceStable(records, func(i, j int) bool {
+		a, b := sortKey(records[i]), sortKey(records[j])
+		if a == b {
+			return records[i].Id < records[j].Id
+		}
+		if descending {
+			return a > b
+		}
+		return a < b
+	})
+
+	// Take the page that was asked for, adding any local labels
+	type labelledInstance struct {
+		instanceSummary
+		Owner  string            `json:"owner,omitempty"`
+		Labels map[string]string `json:"labels,omitempty"`
+	}
+
+	type instancePage struct {
+		TotalCount int                `json:"total_count"`
+		Returned   int                `json:"returned"`
+		NextCursor string             `json:"next_cursor,omitempty"`
+		Notes      []string           `json:"notes,omitempty"`
+		Instances  []labelledInstance `json:"instances"`
+	}
+
+	page := instancePage{
+		TotalCount: len(records),
+		Instances:  []labelledInstance{},
+	}
+	if len(unknownCreated) > 0 {
+		sort.Strings(unknownCreated)
+		page.Notes = append(page.Notes, fmt.Sprintf("The creation time of these instances could not be read so they are included without applying 'created_after' or 'created_before': %s", strings.Join(unknownCreated, ", ")))
+	}
+
+	labels, err := deps.Labels.All()
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to load instance labels: %v", err)), nil
+	}
+
+	if offset < len(records) {
+		end := min(offset+limit, len(records))
+		for _, record := range records[offset:end] {
+			entry := labels[record.Id]
+			page.Instances = append(page.Instances, labelledInstance{
+				instanceSummary: record,
+				Owner:           entry.Owner,
+				Labels:          entry.Labels,
+			})
+		}
+		if end < len(records) {
+			page.NextCursor = encodeListCursor(end)
+		}
 	}
+	page.Returned = len(page.Instances)
 
-	jsonData, err := json.Marshal(records)
+	jsonData, err := json.Marshal(page)
 	if err != nil {
 		return mcp.NewToolResultError(fmt.Sprintf("Failed to serialize results: %v", err)), nil
 	}
@@ -71,6 +309,40 @@ func executeListInstances(ctx context.Context, parameters map[string]interface{}
 	return mcp.NewToolResultText(string(jsonData)), nil
 }
 
+// parseTimeParameter parses a time given as RFC3339 or as a date in the form YYYY-MM-DD
+func parseTimeParameter(value string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	t, err := time.Parse(time.DateOnly, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("'%s' must be in RFC3339 format or YYYY-MM-DD", value)
+	}
+	return t, nil
+}
+
+// encodeListCursor returns an opaque cursor for the given offset into a list
+func encodeListCursor(offset int) string {
+	return base64.RawURLEncoding.EncodeToString([]byte("offset:" + strconv.Itoa(offset)))
+}
+
+// decodeListCursor returns the offset held in a cursor made by encodeListCursor
+func decodeListCursor(cursor string) (int, error) {
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return 0, err
+	}
+	value, found := strings.CutPrefix(string(data), "offset:")
+	if !found {
+		return 0, fmt.Errorf("cursor is not valid")
+	}
+	offset, err := strconv.Atoi(value)
+	if err != nil || offset < 0 {
+		return 0, fmt.Errorf("cursor is not valid")
+	}
+	return offset, nil
+}
+
 // registerGetInstanceDetailsOutcome registers the get-instance-details outcome
 func (r *OutcomeRegistry) registerGetInstanceDetailsOutcome() {
 	r.Outcomes["get-instance-details"] = &Outcome{
@@ -112,21 +384,21 @@ func executeGetInstanceDetails(ctx context.Context, parameters map[string]interf
 		return mcp.NewToolResultError(fmt.Sprintf("Failed to retrieve instance details: %v. The instance may not exist or you may not have access to it.", err)), nil
 	}
 
+	// Add any local labels
+	labels, err := deps.Labels.Get(instanceID)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to load instance labels: %v", err)), nil
+	}
+
 	// Format the response with all relevant details
-	type instanceDetails aura.GetInstanceData
-
-	details := instanceDetails{
-		Id:            instanceInfo.Data.Id,
-		Name:          instanceInfo.Data.Name,
-		Status:        instanceInfo.Data.Status,
-		ConnectionUrl: instanceInfo.Data.ConnectionUrl,
-		CloudProvider: instanceInfo.Data.CloudProvider,
-		Region:        instanceInfo.Data.Region,
-		Memory:        instanceInfo.Data.Memory,
-		Storage:       instanceInfo.Data.Storage,
-		Type:          instanceInfo.Data.Type,
-		TenantId:      instanceInfo.Data.TenantId,
-		MetricsURL:    instanceInfo.Data.MetricsURL,
+	details := struct {
+		instanceDetails
+		Owner  string            `json:"owner,omitempty"`
+		Labels map[string]string `json:"labels"`
+	}{
+		instanceDetails: newInstanceDetails(&instanceInfo.Data),
+		Owner:           labels.Owner,
+		Labels:          labels.Labels,
 	}
 
 	jsonData, err := json.MarshalIndent(details, "", "  ")
@@ -137,12 +409,33 @@ func executeGetInstanceDetails(ctx context.Context, parameters map[string]interf
 	return mcp.NewToolResultText(string(jsonData)), nil
 }
 
+// instanceDetails is the set of instance fields reported by get-instance-details and by
+// the outcomes that compare instances
+type instanceDetails aura.GetInstanceData
+
+// newInstanceDetails takes the fields reported by get-instance-details from the Aura API response
+func newInstanceDetails(data *aura.GetInstanceData) instanceDetails {
+	return instanceDetails{
+		Id:            data.Id,
+		Name:          data.Name,
+		Status:        data.Status,
+		ConnectionUrl: data.ConnectionUrl,
+		CloudProvider: data.CloudProvider,
+		Region:        data.Region,
+		Memory:        data.Memory,
+		Storage:       data.Storage,
+		Type:          data.Type,
+		TenantId:      data.TenantId,
+		MetricsURL:    data.MetricsURL,
+	}
+}
+
 // registerDeleteInstanceOutcome registers the delete-instance outcome
 func (r *OutcomeRegistry) registerDeleteInstanceOutcome() {
 	r.Outcomes["delete-instance"] = &Outcome{
 		ID:          "delete-instance",
 		Name:        "Delete Instance",
-		Description: "Permanently delete a Neo4j Aura database instance. This is a destructive operation that cannot be undone. Requires explicit confirmation via the 'confirm' parameter.",
+		Description: "Permanently delete a Neo4j Aura database instance. This is a destructive operation that cannot be undone. The first call returns a confirmation token; call again with the same parameters and the token to delete.",
 		Type:        OutcomesTypeDelete,
 		ReadOnly:    false,
 		Parameters: []OutcomeParameter{
@@ -152,12 +445,6 @@ func (r *OutcomeRegistry) registerDeleteInstanceOutcome() {
 				Description: "The ID of the instance to delete",
 				Required:    true,
 			},
-			{
-				Name:        "confirm",
-				Type:        "boolean",
-				Description: "Must be set to true to confirm deletion. This is a safety measure to prevent accidental deletions.",
-				Required:    true,
-			},
 		},
 		Metadata: map[string]interface{}{
 			"category":    "instances",
@@ -180,28 +467,31 @@ func executeDeleteInstance(ctx context.Context, parameters map[string]interface{
 		return mcp.NewToolResultError("'instance_id' parameter is required and must be a non-empty string"), nil
 	}
 
-	// Check for confirmation
-	confirm, ok := parameters["confirm"].(bool)
-	if !ok {
-		return mcp.NewToolResultError("'confirm' parameter is required and must be a boolean (true to confirm deletion)"), nil
-	}
-
-	if !confirm {
-		return mcp.NewToolResultError("Deletion not confirmed. Set 'confirm' to true to proceed with deletion. WARNING: This action cannot be undone."), nil
-	}
-
 	// Get instance details first to return information about what was deleted
 	instanceInfo, err := deps.AClient.Instances.Get(instanceID)
 	if err != nil {
 		return mcp.NewToolResultError(fmt.Sprintf("Failed to retrieve instance details before deletion: %v. The instance may not exist or you may not have access to it.", err)), nil
 	}
 
+	// Refuse to delete a protected instance
+	if err := checkDeletionProtection(deps, instanceID, instanceInfo.Data.Name); err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Deletion refused: %v", err)), nil
+	}
+
 	// Delete the instance using the Aura API client
 	_, err = deps.AClient.Instances.Delete(instanceID)
 	if err != nil {
 		return mcp.NewToolResultError(fmt.Sprintf("Failed to delete instance: %v", err)), nil
 	}
 
+	// It no longer needs to expire or be labelled
+	if err := deps.TTLs.Remove(instanceID); err != nil {
+		slog.Warn("Failed to forget the TTL of a deleted instance", "instance_id", instanceID, "error", err)
+	}
+	if err := deps.Labels.Remove(instanceID); err != nil {
+		slog.Warn("Failed to forget the labels of a deleted instance", "instance_id", instanceID, "error", err)
+	}
+
 	// Format the response
 	type deleteResult struct {
 		Success     bool   `json:"success"`
@@ -232,7 +522,7 @@ func (r *OutcomeRegistry) registerCreateInstanceOutcome() {
 	r.Outcomes["create-instance"] = &Outcome{
 		ID:          "create-instance",
 		Name:        "Create Instance",
-		Description: "Create a new Neo4j Aura database instance with specified configuration. Returns the created instance details including ID, name, and connection information.",
+		Description: "Create a new Neo4j Aura database instance with specified configuration, or from a named profile ( see list-profiles ) plus any overrides. The combination of cloud provider, region, memory, type and version must be one the tenant allows; see get-tenant-details. Returns the created instance details including ID, name, and connection information.",
 		Type:        OutcomesTypeCreate,
 		ReadOnly:    false,
 		Parameters: []OutcomeParameter{
@@ -242,35 +532,72 @@ func (r *OutcomeRegistry) registerCreateInstanceOutcome() {
 				Description: "Name for the new instance",
 				Required:    true,
 			},
+			{
+				Name:        "profile",
+				Type:        "string",
+				Description: "Name of an instance profile to take the other parameters from. Any other parameters supplied override the profile",
+				Required:    false,
+			},
 			{
 				Name:        "cloud_provider",
 				Type:        "string",
-				Description: "Cloud provider: 'gcp', 'aws', or 'azure'",
-				Required:    true,
+				Description: "Cloud provider: 'gcp', 'aws', or 'azure'. Required unless supplied by 'profile'",
+				Required:    false,
 			},
 			{
 				Name:        "region",
 				Type:        "string",
-				Description: "Cloud region (e.g., 'us-east-1' for AWS, 'us-central1' for GCP, 'eastus' for Azure)",
-				Required:    true,
+				Description: "Cloud region (e.g., 'us-east-1' for AWS, 'us-central1' for GCP, 'eastus' for Azure). Required unless supplied by 'profile'",
+				Required:    false,
 			},
 			{
 				Name:        "memory",
 				Type:        "string",
-				Description: "Memory size for the instance ('2GB', '4GB', '8GB', '16GB', '32GB', '48GB', '64GB', '96GB', '128GB', '192GB', '256GB', '384GB', '512GB', '768GB', '1024GB', '1536GB', '2048GB')",
-				Required:    true,
+				Description: "Memory size for the instance ('1GB', '2GB', '4GB', '8GB', '16GB', '24GB', '32GB', '48GB', '64GB', '128GB', '192GB', '256GB', '384GB', '512GB'). Required unless supplied by 'profile'",
+				Required:    false,
 			},
 			{
 				Name:        "type",
 				Type:        "string",
-				Description: "Instance type: 'free-db', 'professional-db', or 'business-critical','enterprise-db', 'enterprise-ds'",
-				Required:    true,
+				Description: "Instance type: 'free-db', 'professional-db', or 'business-critical','enterprise-db', 'enterprise-ds'. Required unless supplied by 'profile'",
+				Required:    false,
 			},
 			{
 				Name:        "tenantId",
 				Type:        "string",
-				Description: "The id of the project that the instance will be created in.",
-				Required:    true,
+				Description: "The id of the project that the instance will be created in. Use list-tenants to find it. Required unless supplied by 'profile'",
+				Required:    false,
+			},
+			{
+				Name:        "version",
+				Type:        "string",
+				Description: "Neo4j version for the instance. Use get-tenant-details to see the versions the tenant allows",
+				Required:    false,
+				Default:     "5",
+			},
+			{
+				Name:        "ttl",
+				Type:        "string",
+				Description: "How long the instance is needed for, such as '4h' or '2d'. Once it has passed the instance is paused or deleted. Leave out for an instance that is kept until deleted",
+				Required:    false,
+			},
+			{
+				Name:        "ttl_action",
+				Type:        "string",
+				Description: "What to do when the TTL has passed: 'pause' or 'delete'. Defaults to the server's REAPER_POLICY",
+				Required:    false,
+			},
+			{
+				Name:        "labels",
+				Type:        "object",
+				Description: "Labels to record against the instance, such as {\"env\": \"dev\", \"team\": \"graph\"}. The caller is recorded as the owner",
+				Required:    false,
+			},
+			{
+				Name:        "customer_managed_key_id",
+				Type:        "string",
+				Description: "The ID of a customer managed encryption key to encrypt the instance with. Use list-encryption-keys to find it. The key must be in the same tenant, cloud provider and region, and be for the same instance type",
+				Required:    false,
 			},
 		},
 		Metadata: map[string]interface{}{
@@ -282,102 +609,111 @@ func (r *OutcomeRegistry) registerCreateInstanceOutcome() {
 
 // executeCreateInstance implements the create-instance outcome
 func executeCreateInstance(ctx context.Context, parameters map[string]interface{}, deps *Dependencies) (*mcp.CallToolResult, error) {
-	// These are supported parameters for creating an instance
-	/*
-		var supportedMemory = []string{
-			"1GB", "2GB", "4GB", "8GB", "16GB", "24GB", "32GB", "48GB", "64GB", "128GB", "192GB", "256GB", "384GB", "512GB",
-		}
-		var supportedTypes = []string{
-			"enterprise-db", "enterprise-ds", "professional-db", "professional-ds", "free-db", "business-critical",
-		}
-		var supportedCloudProviders = []string{"gcp", "aws", "azure"}
-		var supportedVersions = []string{"5"}
-		var supportedStorage = []string{
-			"2GB", "4GB", "8GB", "16GB", "32GB", "48GB", "64GB", "96GB", "128GB", "192GB", "256GB", "384GB", "512GB",
-			"768GB", "1024GB", "1536GB", "2048GB",
-		}
-	*/
-
 	if deps.AClient == nil {
 		return mcp.NewToolResultError("Aura API Client is not initialized"), nil
 	}
 
-	// Validate and extract required parameters
-	name, ok := parameters["name"].(string)
-	if !ok || name == "" {
-		return mcp.NewToolResultError("'name' parameter is required and must be a non-empty string"), nil
+	// Fill in anything not supplied from the named profile, if any
+	parameters, err := applyProfile(deps, parameters)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
 	}
 
-	cloudProvider, ok := parameters["cloud_provider"].(string)
-	if !ok || cloudProvider == "" {
-		return mcp.NewToolResultError("'cloud_provider' parameter is required and must be one of: 'gcp', 'aws', 'azure'"), nil
+	// Validate and extract the instance definition from the parameters
+	instanceDefinition, err := instanceDefinitionFromParameters(parameters)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
 	}
 
-	// Validate cloud provider
-	validProviders := map[string]bool{"gcp": true, "aws": true, "azure": true}
-	if !validProviders[cloudProvider] {
-		return mcp.NewToolResultError(fmt.Sprintf("Invalid cloud_provider '%s'. Must be one of: 'gcp', 'aws', 'azure'", cloudProvider)), nil
+	// Check the tenant allows this combination before asking for it
+	if err := validateInstanceDefinition(deps, instanceDefinition); err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
 	}
 
-	region, ok := parameters["region"].(string)
-	if !ok || region == "" {
-		return mcp.NewToolResultError("'region' parameter is required and must be a non-empty string"), nil
+	// Check any TTL before creating anything
+	ttl, ttlAction, err := ttlFromParameters(parameters)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
 	}
 
-	memory, ok := parameters["memory"].(string)
-	if !ok || memory == "" {
-		return mcp.NewToolResultError("'memory' parameter is required (e.g., '2GB', '8GB', '16GB', '32GB', '64GB')"), nil
+	// Check any labels before creating anything
+	labels := map[string]string{}
+	if value, ok := parameters["labels"]; ok && value != nil {
+		labels, err = labelsFromParameter(value)
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+		for key, value := range labels {
+			if value == "" {
+				delete(labels, key)
+			}
+		}
 	}
 
-	instanceType, ok := parameters["type"].(string)
-	if !ok || instanceType == "" {
-		return mcp.NewToolResultError("'type' parameter is required and must be one of: 'free', 'professional', 'enterprise'"), nil
+	if dry := dryRunFromContext(ctx); dry != nil {
+		dry.localChange("record %s as the owner of the new instance", callerIdentity(ctx, deps))
+		if len(labels) > 0 {
+			dry.localChange("record labels %v against the new instance", labels)
+		}
+		if ttl > 0 {
+			dry.localChange("expire the new instance after %s", ttl)
+		}
 	}
 
-	// Validate instance type
-	validTypes := map[string]bool{"enterprise-db": true, "enterprise-ds": true, "professional-db": true, "professional-ds": true, "free-db": true, "business-critical": true}
-	if !validTypes[instanceType] {
-		return mcp.NewToolResultError(fmt.Sprintf("Invalid type '%s'. Must be one of: 'free', 'professional', 'enterprise'", instanceType)), nil
+	// Call the Aura API to create the instance, encrypted with a customer managed key if one was given
+	var instance *aura.CreateInstanceResponse
+	if keyID, _ := parameters["customer_managed_key_id"].(string); keyID != "" {
+		instance, err = createInstanceWithKey(ctx, deps, instanceDefinition, keyID)
+	} else {
+		instance, err = deps.AClient.Instances.Create(instanceDefinition)
 	}
-
-	tenant, ok := parameters["tenantId"].(string)
-	if !ok || instanceType == "" {
-		return mcp.NewToolResultError("'tenantId' parameter is required"), nil
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to create instance: %v", err)), nil
 	}
 
-	version := "5" // default
-
-	// Create the instance using the Aura API client
-
-	instanceDefinition := aura.CreateInstanceConfigData{
-		Name:          name,
-		CloudProvider: cloudProvider,
-		Region:        region,
-		Memory:        memory,
-		Type:          instanceType,
-		Version:       version,
-		TenantId:      tenant,
+	// Record who owns it and any labels
+	var warnings []string
+	owner := callerIdentity(ctx, deps)
+	_, err = deps.Labels.Update(instance.Data.Id, func(e *instanceLabels) {
+		e.Name = instance.Data.Name
+		e.Owner = owner
+		e.UpdatedBy = owner
+		for key, value := range labels {
+			e.Labels[key] = value
+		}
+	})
+	if err != nil {
+		warnings = append(warnings, fmt.Sprintf("The instance was created but its owner and labels could not be recorded: %v", err))
 	}
 
-	// Call the Aura API to create the instance
-	instance, err := deps.AClient.Instances.Create(&instanceDefinition)
-	if err != nil {
-		return mcp.NewToolResultError(fmt.Sprintf("Failed to create instance: %v", err)), nil
+	// Record when it expires
+	var expiresAt *time.Time
+	if ttl > 0 {
+		expiry, err := recordTTL(deps, instance.Data.Id, instance.Data.Name, ttl, ttlAction)
+		if err != nil {
+			warnings = append(warnings, fmt.Sprintf("The instance was created but its TTL could not be recorded so it will not expire: %v", err))
+		} else {
+			expiresAt = &expiry
+		}
 	}
 
 	// Format the response
 	type createResult struct {
-		Success       bool   `json:"success"`
-		Message       string `json:"message"`
-		Id            string `json:"id"`
-		Name          string `json:"name"`
-		Status        string `json:"status"`
-		CloudProvider string `json:"cloud_provider"`
-		Memory        string `json:"memory"`
-		Type          string `json:"type"`
-		URL           string `json:"url,omitempty"`
-		Username      string `json:"User"`
-		Password      string `json:"Password"`
+		Success       bool              `json:"success"`
+		Message       string            `json:"message"`
+		Id            string            `json:"id"`
+		Name          string            `json:"name"`
+		Status        string            `json:"status"`
+		CloudProvider string            `json:"cloud_provider"`
+		Memory        string            `json:"memory"`
+		Type          string            `json:"type"`
+		URL           string            `json:"url,omitempty"`
+		Username      string            `json:"User"`
+		Password      string            `json:"Password"`
+		Owner         string            `json:"owner,omitempty"`
+		Labels        map[string]string `json:"labels,omitempty"`
+		ExpiresAt     *time.Time        `json:"expires_at,omitempty"`
+		Warnings      []string          `json:"warnings,omitempty"`
 	}
 
 	result := createResult{
@@ -390,6 +726,10 @@ func executeCreateInstance(ctx context.Context, parameters map[string]interface{
 		URL:           instance.Data.ConnectionUrl,
 		Username:      instance.Data.Username,
 		Password:      instance.Data.Password,
+		Owner:         owner,
+		Labels:        labels,
+		ExpiresAt:     expiresAt,
+		Warnings:      warnings,
 	}
 
 	jsonData, err := json.MarshalIndent(result, "", "  ")
@@ -399,3 +739,937 @@ func executeCreateInstance(ctx context.Context, parameters map[string]interface{
 
 	return mcp.NewToolResultText(string(jsonData)), nil
 }
+
+// registerPauseInstanceOutcome registers the pause-instance outcome
+func (r *OutcomeRegistry) registerPauseInstanceOutcome() {
+	r.Outcomes["pause-instance"] = &Outcome{
+		ID:          "pause-instance",
+		Name:        "Pause Instance",
+		Description: "Pause a running Neo4j Aura database instance to reduce costs. The instance keeps its data and can be resumed later with resume-instance. Returns the status of the instance before and after the request.",
+		Type:        OutcomesTypeUpdate,
+		ReadOnly:    false,
+		Parameters: []OutcomeParameter{
+			{
+				Name:        "instance_id",
+				Type:        "string",
+				Description: "The ID of the instance to pause",
+				Required:    true,
+			},
+		},
+		Metadata: map[string]interface{}{
+			"category": "instances",
+		},
+		Handler: executePauseInstance,
+	}
+}
+
+// executePauseInstance implements the pause-instance outcome
+func executePauseInstance(ctx context.Context, parameters map[string]interface{}, deps *Dependencies) (*mcp.CallToolResult, error) {
+	if deps.AClient == nil {
+		return mcp.NewToolResultError("Aura API Client is not initialized"), nil
+	}
+
+	// Validate and extract required parameter
+	instanceID, ok := parameters["instance_id"].(string)
+	if !ok || instanceID == "" {
+		return mcp.NewToolResultError("'instance_id' parameter is required and must be a non-empty string"), nil
+	}
+
+	// Check the current status first so we can give a clear message if there is nothing to do
+	instanceInfo, err := deps.AClient.Instances.Get(instanceID)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to retrieve instance details before pausing: %v. The instance may not exist or you may not have access to it.", err)), nil
+	}
+
+	statusBefore := instanceInfo.Data.Status
+	switch statusBefore {
+	case "paused", "pausing":
+		return mcp.NewToolResultError(fmt.Sprintf("Instance '%s' (ID: %s) is already %s. Nothing to do.", instanceInfo.Data.Name, instanceID, statusBefore)), nil
+	case "running":
+		// ok to pause
+	default:
+		return mcp.NewToolResultError(fmt.Sprintf("Instance '%s' (ID: %s) cannot be paused while its status is '%s'. Only running instances can be paused.", instanceInfo.Data.Name, instanceID, statusBefore)), nil
+	}
+
+	// Pause the instance using the Aura API client
+	paused, err := deps.AClient.Instances.Pause(instanceID)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to pause instance: %v", err)), nil
+	}
+
+	return instanceStatusChangeResult(
+		fmt.Sprintf("Pause requested for instance '%s' (ID: %s)", instanceInfo.Data.Name, instanceID),
+		instanceID, instanceInfo.Data.Name, statusBefore, paused.Data.Status,
+	)
+}
+
+// registerResumeInstanceOutcome registers the resume-instance outcome
+func (r *OutcomeRegistry) registerResumeInstanceOutcome() {
+	r.Outcomes["resume-instance"] = &Outcome{
+		ID:          "resume-instance",
+		Name:        "Resume Instance",
+		Description: "Resume a paused Neo4j Aura database instance. Returns the status of the instance before and after the request.",
+		Type:        OutcomesTypeUpdate,
+		ReadOnly:    false,
+		Parameters: []OutcomeParameter{
+			{
+				Name:        "instance_id",
+				Type:        "string",
+				Description: "The ID of the instance to resume",
+				Required:    true,
+			},
+		},
+		Metadata: map[string]interface{}{
+			"category": "instances",
+		},
+		Handler: executeResumeInstance,
+	}
+}
+
+// executeResumeInstance implements the resume-instance outcome
+func executeResumeInstance(ctx context.Context, parameters map[string]interface{}, deps *Dependencies) (*mcp.CallToolResult, error) {
+	if deps.AClient == nil {
+		return mcp.NewToolResultError("Aura API Client is not initialized"), nil
+	}
+
+	// Validate and extract required parameter
+	instanceID, ok := parameters["instance_id"].(string)
+	if !ok || instanceID == "" {
+		return mcp.NewToolResultError("'instance_id' parameter is required and must be a non-empty string"), nil
+	}
+
+	// Check the current status first so we can give a clear message if there is nothing to do
+	instanceInfo, err := deps.AClient.Instances.Get(instanceID)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to retrieve instance details before resuming: %v. The instance may not exist or you may not have access to it.", err)), nil
+	}
+
+	statusBefore := instanceInfo.Data.Status
+	switch statusBefore {
+	case "running", "resuming":
+		return mcp.NewToolResultError(fmt.Sprintf("Instance '%s' (ID: %s) is already %s. Nothing to do.", instanceInfo.Data.Name, instanceID, statusBefore)), nil
+	case "paused":
+		// ok to resume
+	default:
+		return mcp.NewToolResultError(fmt.Sprintf("Instance '%s' (ID: %s) cannot be resumed while its status is '%s'. Only paused instances can be resumed.", instanceInfo.Data.Name, instanceID, statusBefore)), nil
+	}
+
+	// Resume the instance using the Aura API client
+	resumed, err := deps.AClient.Instances.Resume(instanceID)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to resume instance: %v", err)), nil
+	}
+
+	return instanceStatusChangeResult(
+		fmt.Sprintf("Resume requested for instance '%s' (ID: %s)", instanceInfo.Data.Name, instanceID),
+		instanceID, instanceInfo.Data.Name, statusBefore, resumed.Data.Status,
+	)
+}
+
+// registerUpdateInstanceOutcome registers the update-instance outcome
+func (r *OutcomeRegistry) registerUpdateInstanceOutcome() {
+	r.Outcomes["update-instance"] = &Outcome{
+		ID:          "update-instance",
+		Name:        "Update Instance",
+		Description: "Rename and / or resize a Neo4j Aura database instance. Only the supplied fields are changed. Returns a before and after view of the fields that were changed.",
+		Type:        OutcomesTypeUpdate,
+		ReadOnly:    false,
+		Parameters: []OutcomeParameter{
+			{
+				Name:        "instance_id",
+				Type:        "string",
+				Description: "The ID of the instance to update",
+				Required:    true,
+			},
+			{
+				Name:        "name",
+				Type:        "string",
+				Description: "New name for the instance",
+				Required:    false,
+			},
+			{
+				Name:        "memory",
+				Type:        "string",
+				Description: "New memory size for the instance ('1GB', '2GB', '4GB', '8GB', '16GB', '24GB', '32GB', '48GB', '64GB', '128GB', '192GB', '256GB', '384GB', '512GB')",
+				Required:    false,
+			},
+			{
+				Name:        "storage",
+				Type:        "string",
+				Description: "New storage size for the instance ('2GB', '4GB', '8GB', '16GB', '32GB', '48GB', '64GB', '96GB', '128GB', '192GB', '256GB', '384GB', '512GB', '768GB', '1024GB', '1536GB', '2048GB')",
+				Required:    false,
+			},
+			{
+				Name:        "secret",
+				Type:        "string",
+				Description: "The unprotect secret, supplied by the user. Only needed to rename an instance so that a name pattern that protects it no longer matches; see protect-instance",
+				Required:    false,
+			},
+		},
+		Metadata: map[string]interface{}{
+			"category": "instances",
+		},
+		Handler: executeUpdateInstance,
+	}
+}
+
+// executeUpdateInstance implements the update-instance outcome
+func executeUpdateInstance(ctx context.Context, parameters map[string]interface{}, deps *Dependencies) (*mcp.CallToolResult, error) {
+	if deps.AClient == nil {
+		return mcp.NewToolResultError("Aura API Client is not initialized"), nil
+	}
+
+	// Validate and extract required parameter
+	instanceID, ok := parameters["instance_id"].(string)
+	if !ok || instanceID == "" {
+		return mcp.NewToolResultError("'instance_id' parameter is required and must be a non-empty string"), nil
+	}
+
+	// Optional parameters. At least one of them must be given
+	name, _ := parameters["name"].(string)
+	memory, _ := parameters["memory"].(string)
+	storage, _ := parameters["storage"].(string)
+
+	if name == "" && memory == "" && storage == "" {
+		return mcp.NewToolResultError("At least one of 'name', 'memory' or 'storage' must be supplied"), nil
+	}
+
+	// Validate sizes against the supported catalog
+	if memory != "" && !slices.Contains(supportedMemory, memory) {
+		return mcp.NewToolResultError(fmt.Sprintf("Invalid memory '%s'. Must be one of: %s", memory, strings.Join(supportedMemory, ", "))), nil
+	}
+
+	if storage != "" && !slices.Contains(supportedStorage, storage) {
+		return mcp.NewToolResultError(fmt.Sprintf("Invalid storage '%s'. Must be one of: %s", storage, strings.Join(supportedStorage, ", "))), nil
+	}
+
+	// Get the current instance details so we can work out what changes
+	instanceInfo, err := deps.AClient.Instances.Get(instanceID)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to retrieve instance details before update: %v. The instance may not exist or you may not have access to it.", err)), nil
+	}
+
+	currentStorage := ""
+	if instanceInfo.Data.Storage != nil {
+		currentStorage = *instanceInfo.Data.Storage
+	}
+
+	// The Aura API client only supports changing name and memory. Storage follows memory for
+	// most instance types so only reject a storage request that differs from the current value
+	if storage != "" && storage != currentStorage {
+		return mcp.NewToolResultError(fmt.Sprintf("Changing storage independently is not supported. Current storage is '%s'. Change 'memory' instead; storage is resized with it for most instance types.", currentStorage)), nil
+	}
+
+	type fieldChange struct {
+		Before string `json:"before"`
+		After  string `json:"after"`
+	}
+
+	changes := map[string]fieldChange{}
+
+	// The update request needs both fields so start from the current values
+	update := aura.UpdateInstanceData{
+		Name:   instanceInfo.Data.Name,
+		Memory: instanceInfo.Data.Memory,
+	}
+
+	if name != "" && name != instanceInfo.Data.Name {
+		// A rename must not be a way round a name pattern that protects the instance
+		secret, _ := parameters["secret"].(string)
+		if err := checkNameProtection(deps, instanceID, instanceInfo.Data.Name, name, secret); err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+		update.Name = name
+		changes["name"] = fieldChange{Before: instanceInfo.Data.Name, After: name}
+	}
+
+	if memory != "" && memory != instanceInfo.Data.Memory {
+		update.Memory = memory
+		changes["memory"] = fieldChange{Before: instanceInfo.Data.Memory, After: memory}
+	}
+
+	if len(changes) == 0 {
+		return mcp.NewToolResultError(fmt.Sprintf("Instance '%s' (ID: %s) already has the requested values. Nothing to do.", instanceInfo.Data.Name, instanceID)), nil
+	}
+
+	// Update the instance using the Aura API client
+	updated, err := deps.AClient.Instances.Update(instanceID, &update)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to update instance: %v", err)), nil
+	}
+
+	// Storage is reported if it was changed as a result of the memory change
+	if updated.Data.Storage != nil && *updated.Data.Storage != "" && *updated.Data.Storage != currentStorage {
+		changes["storage"] = fieldChange{Before: currentStorage, After: *updated.Data.Storage}
+	}
+
+	// Format the response
+	type updateResult struct {
+		Success bool                   `json:"success"`
+		Message string                 `json:"message"`
+		Id      string                 `json:"id"`
+		Name    string                 `json:"name"`
+		Status  string                 `json:"status"`
+		Changes map[string]fieldChange `json:"changes"`
+	}
+
+	result := updateResult{
+		Success: true,
+		Message: fmt.Sprintf("Instance '%s' (ID: %s) update requested", update.Name, instanceID),
+		Id:      instanceID,
+		Name:    update.Name,
+		Status:  updated.Data.Status,
+		Changes: changes,
+	}
+
+	jsonData, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to serialize results: %v", err)), nil
+	}
+
+	return mcp.NewToolResultText(string(jsonData)), nil
+}
+
+// registerOverwriteInstanceOutcome registers the overwrite-instance outcome
+func (r *OutcomeRegistry) registerOverwriteInstanceOutcome() {
+	r.Outcomes["overwrite-instance"] = &Outcome{
+		ID:          "overwrite-instance",
+		Name:        "Overwrite Instance",
+		Description: "Replace all of the data in a Neo4j Aura database instance with the data from another instance or from a snapshot. This is a destructive operation that cannot be undone. The first call returns a confirmation token; call again with the same parameters and the token to overwrite.",
+		Type:        OutcomesTypeUpdate,
+		ReadOnly:    false,
+		Parameters: []OutcomeParameter{
+			{
+				Name:        "target_instance_id",
+				Type:        "string",
+				Description: "The ID of the instance whose data will be overwritten",
+				Required:    true,
+			},
+			{
+				Name:        "source_instance_id",
+				Type:        "string",
+				Description: "The ID of the instance to copy data from. Either this or 'source_snapshot_id' must be supplied. When used with 'source_snapshot_id' it is the instance that owns the snapshot",
+				Required:    false,
+			},
+			{
+				Name:        "source_snapshot_id",
+				Type:        "string",
+				Description: "The ID of the snapshot to copy data from. If 'source_instance_id' is not supplied, the snapshot must belong to the target instance",
+				Required:    false,
+			},
+		},
+		Metadata: map[string]interface{}{
+			"category":    "instances",
+			"destructive": true,
+			"warning":     "This operation replaces all of the data in the target instance. The existing data cannot be recovered unless a snapshot of it exists.",
+		},
+		Handler: executeOverwriteInstance,
+	}
+}
+
+// executeOverwriteInstance implements the overwrite-instance outcome
+func executeOverwriteInstance(ctx context.Context, parameters map[string]interface{}, deps *Dependencies) (*mcp.CallToolResult, error) {
+	if deps.AClient == nil {
+		return mcp.NewToolResultError("Aura API Client is not initialized"), nil
+	}
+
+	// Validate and extract required parameters
+	targetID, ok := parameters["target_instance_id"].(string)
+	if !ok || targetID == "" {
+		return mcp.NewToolResultError("'target_instance_id' parameter is required and must be a non-empty string"), nil
+	}
+
+	sourceInstanceID, _ := parameters["source_instance_id"].(string)
+	sourceSnapshotID, _ := parameters["source_snapshot_id"].(string)
+
+	if sourceInstanceID == "" && sourceSnapshotID == "" {
+		return mcp.NewToolResultError("Either 'source_instance_id' or 'source_snapshot_id' must be supplied"), nil
+	}
+
+	if sourceInstanceID == targetID && sourceSnapshotID == "" {
+		return mcp.NewToolResultError("'source_instance_id' must be different from 'target_instance_id' unless restoring from a snapshot"), nil
+	}
+
+	// A snapshot on its own is taken to belong to the target instance
+	if sourceInstanceID == "" {
+		sourceInstanceID = targetID
+	}
+
+	// Get details of both instances first to return information about what was overwritten
+	targetInfo, err := deps.AClient.Instances.Get(targetID)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to retrieve target instance details before overwrite: %v. The instance may not exist or you may not have access to it.", err)), nil
+	}
+
+	sourceInfo, err := deps.AClient.Instances.Get(sourceInstanceID)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to retrieve source instance details before overwrite: %v. The instance may not exist or you may not have access to it.", err)), nil
+	}
+
+	// Refuse to overwrite a protected instance
+	if err := checkDeletionProtection(deps, targetID, targetInfo.Data.Name); err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Overwrite refused: %v", err)), nil
+	}
+
+	// Overwrite the instance using the Aura API client
+	_, err = deps.AClient.Instances.Overwrite(targetID, sourceInstanceID, sourceSnapshotID)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to overwrite instance: %v", err)), nil
+	}
+
+	// Format the response
+	type overwriteResult struct {
+		Success            bool   `json:"success"`
+		Message            string `json:"message"`
+		TargetInstanceId   string `json:"target_instance_id"`
+		TargetInstanceName string `json:"target_instance_name"`
+		SourceInstanceId   string `json:"source_instance_id"`
+		SourceInstanceName string `json:"source_instance_name"`
+		SourceSnapshotId   string `json:"source_snapshot_id,omitempty"`
+		Warning            string `json:"warning"`
+	}
+
+	source := fmt.Sprintf("instance '%s' (ID: %s)", sourceInfo.Data.Name, sourceInstanceID)
+	if sourceSnapshotID != "" {
+		source = fmt.Sprintf("snapshot %s of %s", sourceSnapshotID, source)
+	}
+
+	result := overwriteResult{
+		Success:            true,
+		Message:            fmt.Sprintf("Overwrite of instance '%s' (ID: %s) from %s has been requested", targetInfo.Data.Name, targetID, source),
+		TargetInstanceId:   targetID,
+		TargetInstanceName: targetInfo.Data.Name,
+		SourceInstanceId:   sourceInstanceID,
+		SourceInstanceName: sourceInfo.Data.Name,
+		SourceSnapshotId:   sourceSnapshotID,
+		Warning:            "The previous data in the target instance has been replaced.",
+	}
+
+	jsonData, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to serialize results: %v", err)), nil
+	}
+
+	return mcp.NewToolResultText(string(jsonData)), nil
+}
+
+// registerCloneInstanceOutcome registers the clone-instance outcome
+func (r *OutcomeRegistry) registerCloneInstanceOutcome() {
+	r.Outcomes["clone-instance"] = &Outcome{
+		ID:          "clone-instance",
+		Name:        "Clone Instance",
+		Description: "Create a new Neo4j Aura database instance and restore a snapshot of another instance into it. The source instance is not changed. Waits for the new instance to be running before restoring the snapshot. Any of cloud_provider, region, memory, type and tenantId that are not supplied are taken from the source instance.",
+		Type:        OutcomesTypeCreate,
+		ReadOnly:    false,
+		Parameters: []OutcomeParameter{
+			{
+				Name:        "source_instance_id",
+				Type:        "string",
+				Description: "The ID of the instance to clone",
+				Required:    true,
+			},
+			{
+				Name:        "snapshot_id",
+				Type:        "string",
+				Description: "The ID of the snapshot of the source instance to restore. Defaults to the most recent completed snapshot",
+				Required:    false,
+			},
+			{
+				Name:        "name",
+				Type:        "string",
+				Description: "Name for the new instance",
+				Required:    true,
+			},
+			{
+				Name:        "cloud_provider",
+				Type:        "string",
+				Description: "Cloud provider: 'gcp', 'aws', or 'azure'. Defaults to that of the source instance",
+				Required:    false,
+			},
+			{
+				Name:        "region",
+				Type:        "string",
+				Description: "Cloud region. Defaults to that of the source instance",
+				Required:    false,
+			},
+			{
+				Name:        "memory",
+				Type:        "string",
+				Description: "Memory size for the instance. Defaults to that of the source instance",
+				Required:    false,
+			},
+			{
+				Name:        "type",
+				Type:        "string",
+				Description: "Instance type. Defaults to that of the source instance",
+				Required:    false,
+			},
+			{
+				Name:        "tenantId",
+				Type:        "string",
+				Description: "The id of the project that the instance will be created in. Defaults to that of the source instance",
+				Required:    false,
+			},
+			{
+				Name:        "wait_timeout_minutes",
+				Type:        "number",
+				Description: "How long to wait for the new instance to be running before giving up",
+				Required:    false,
+				Default:     30,
+			},
+			{
+				Name:        "ttl",
+				Type:        "string",
+				Description: "How long the new instance is needed for, such as '4h' or '2d'. Once it has passed the instance is paused or deleted. Leave out for an instance that is kept until deleted",
+				Required:    false,
+			},
+			{
+				Name:        "ttl_action",
+				Type:        "string",
+				Description: "What to do when the TTL has passed: 'pause' or 'delete'. Defaults to the server's REAPER_POLICY",
+				Required:    false,
+			},
+		},
+		Metadata: map[string]interface{}{
+			"category": "instances",
+		},
+		Handler: executeCloneInstance,
+	}
+}
+
+// executeCloneInstance implements the clone-instance outcome
+func executeCloneInstance(ctx context.Context, parameters map[string]interface{}, deps *Dependencies) (*mcp.CallToolResult, error) {
+	if deps.AClient == nil {
+		return mcp.NewToolResultError("Aura API Client is not initialized"), nil
+	}
+
+	// Validate and extract required parameters
+	sourceID, ok := parameters["source_instance_id"].(string)
+	if !ok || sourceID == "" {
+		return mcp.NewToolResultError("'source_instance_id' parameter is required and must be a non-empty string"), nil
+	}
+
+	timeoutMinutes := 30.0
+	if v, ok := parameters["wait_timeout_minutes"].(float64); ok && v > 0 {
+		timeoutMinutes = v
+	}
+
+	sourceInfo, err := deps.AClient.Instances.Get(sourceID)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to retrieve source instance details: %v. The instance may not exist or you may not have access to it.", err)), nil
+	}
+
+	// Check the snapshot exists before creating anything
+	snapshotID, _ := parameters["snapshot_id"].(string)
+	if snapshotID != "" {
+		if _, err := findSnapshot(deps, sourceID, snapshotID); err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+	} else {
+		snapshots, err := deps.AClient.Snapshots.List(sourceID, "")
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("Failed to list snapshots: %v", err)), nil
+		}
+		latest := ""
+		for _, snap := range snapshots.Data {
+			if snap.Status == "Completed" && snap.Timestamp > latest {
+				latest = snap.Timestamp
+				snapshotID = snap.SnapshotId
+			}
+		}
+		if snapshotID == "" {
+			return mcp.NewToolResultError(fmt.Sprintf("Instance '%s' (ID: %s) has no completed snapshots to clone from. Use create-snapshot first.", sourceInfo.Data.Name, sourceID)), nil
+		}
+	}
+
+	// Anything not supplied is taken from the source instance
+	createParameters := map[string]interface{}{
+		"cloud_provider": sourceInfo.Data.CloudProvider,
+		"region":         sourceInfo.Data.Region,
+		"memory":         sourceInfo.Data.Memory,
+		"type":           sourceInfo.Data.Type,
+		"tenantId":       sourceInfo.Data.TenantId,
+	}
+	for _, key := range []string{"name", "cloud_provider", "region", "memory", "type", "tenantId", "ttl", "ttl_action"} {
+		if v, ok := parameters[key].(string); ok && v != "" {
+			createParameters[key] = v
+		}
+	}
+
+	instanceDefinition, err := instanceDefinitionFromParameters(createParameters)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	// Check the tenant allows this combination before asking for it
+	if err := validateInstanceDefinition(deps, instanceDefinition); err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	// Check any TTL before creating anything
+	ttl, ttlAction, err := ttlFromParameters(parameters)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	// The rules for create-instance apply to the new instance too
+	if err := checkCreateGuardrails(ctx, createParameters, deps); err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Cannot clone instance: %v", err)), nil
+	}
+
+	// The overwrite depends on the ID of the new instance, so both calls are recorded here
+	if dry := dryRunFromContext(ctx); dry != nil {
+		dry.apiCall("POST", "/instances", instanceDefinition)
+		if ttl > 0 {
+			dry.localChange("expire the new instance after %s", ttl)
+		}
+		dry.apiCall("POST", "/instances/<new instance id>/overwrite", overwriteBody(sourceID, snapshotID))
+		return dry.result(), nil
+	}
+
+	// Create the new instance
+	instance, err := deps.AClient.Instances.Create(instanceDefinition)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to create instance: %v", err)), nil
+	}
+
+	cloneID := instance.Data.Id
+
+	// Record when it expires straight away, so it still expires if the restore fails
+	var warnings []string
+	var expiresAt *time.Time
+	if ttl > 0 {
+		expiry, err := recordTTL(deps, cloneID, instance.Data.Name, ttl, ttlAction)
+		if err != nil {
+			warnings = append(warnings, fmt.Sprintf("The instance was created but its TTL could not be recorded so it will not expire: %v", err))
+		} else {
+			expiresAt = &expiry
+		}
+	}
+
+	// The snapshot can only be restored once the new instance is running
+	_, err = waitForInstanceStatus(ctx, deps, cloneID, "running", time.Duration(timeoutMinutes*float64(time.Minute)), nil)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Instance '%s' (ID: %s) was created but did not become ready: %v. The snapshot has not been restored. Username: %s Password: %s", instance.Data.Name, cloneID, err, instance.Data.Username, instance.Data.Password)), nil
+	}
+
+	// Restore the snapshot into the new instance
+	_, err = deps.AClient.Instances.Overwrite(cloneID, sourceID, snapshotID)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Instance '%s' (ID: %s) was created but restoring snapshot %s failed: %v. Username: %s Password: %s", instance.Data.Name, cloneID, snapshotID, err, instance.Data.Username, instance.Data.Password)), nil
+	}
+
+	// Format the response
+	type cloneResult struct {
+		Success            bool       `json:"success"`
+		Message            string     `json:"message"`
+		Id                 string     `json:"id"`
+		Name               string     `json:"name"`
+		CloudProvider      string     `json:"cloud_provider"`
+		Region             string     `json:"region"`
+		Memory             string     `json:"memory"`
+		Type               string     `json:"type"`
+		URL                string     `json:"url,omitempty"`
+		Username           string     `json:"User"`
+		Password           string     `json:"Password"`
+		SourceInstanceId   string     `json:"source_instance_id"`
+		SourceInstanceName string     `json:"source_instance_name"`
+		SourceSnapshotId   string     `json:"source_snapshot_id"`
+		ExpiresAt          *time.Time `json:"expires_at,omitempty"`
+		Warnings           []string   `json:"warnings,omitempty"`
+	}
+
+	result := cloneResult{
+		Success:            true,
+		Message:            fmt.Sprintf("Instance '%s' (ID: %s) created and restore of snapshot %s from instance '%s' (ID: %s) has been requested", instance.Data.Name, cloneID, snapshotID, sourceInfo.Data.Name, sourceID),
+		Id:                 cloneID,
+		Name:               instance.Data.Name,
+		CloudProvider:      instanceDefinition.CloudProvider,
+		Region:             instanceDefinition.Region,
+		Memory:             instanceDefinition.Memory,
+		Type:               instanceDefinition.Type,
+		URL:                instance.Data.ConnectionUrl,
+		Username:           instance.Data.Username,
+		Password:           instance.Data.Password,
+		SourceInstanceId:   sourceID,
+		SourceInstanceName: sourceInfo.Data.Name,
+		SourceSnapshotId:   snapshotID,
+		ExpiresAt:          expiresAt,
+		Warnings:           warnings,
+	}
+
+	jsonData, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to serialize results: %v", err)), nil
+	}
+
+	return mcp.NewToolResultText(string(jsonData)), nil
+}
+
+// registerWaitForInstanceStatusOutcome registers the wait-for-instance-status outcome
+func (r *OutcomeRegistry) registerWaitForInstanceStatusOutcome() {
+	r.Outcomes["wait-for-instance-status"] = &Outcome{
+		ID:          "wait-for-instance-status",
+		Name:        "Wait For Instance Status",
+		Description: "Wait until a Neo4j Aura database instance reaches a status of 'running', 'paused' or 'destroyed', or until the timeout is reached. Use this after creating, pausing, resuming or deleting an instance instead of repeatedly calling get-instance-details. Sends progress notifications while waiting if the client asked for them.",
+		Type:        OutcomesTypeRead,
+		ReadOnly:    true,
+		Parameters: []OutcomeParameter{
+			{
+				Name:        "instance_id",
+				Type:        "string",
+				Description: "The ID of the instance to wait for",
+				Required:    true,
+			},
+			{
+				Name:        "status",
+				Type:        "string",
+				Description: "The status to wait for: 'running', 'paused' or 'destroyed'",
+				Required:    true,
+			},
+			{
+				Name:        "timeout_minutes",
+				Type:        "number",
+				Description: "How long to wait before giving up",
+				Required:    false,
+				Default:     15,
+			},
+		},
+		Metadata: map[string]interface{}{
+			"category": "instances",
+		},
+		Handler: executeWaitForInstanceStatus,
+	}
+}
+
+// executeWaitForInstanceStatus implements the wait-for-instance-status outcome
+func executeWaitForInstanceStatus(ctx context.Context, parameters map[string]interface{}, deps *Dependencies) (*mcp.CallToolResult, error) {
+	if deps.AClient == nil {
+		return mcp.NewToolResultError("Aura API Client is not initialized"), nil
+	}
+
+	// Validate and extract parameters
+	instanceID, ok := parameters["instance_id"].(string)
+	if !ok || instanceID == "" {
+		return mcp.NewToolResultError("'instance_id' parameter is required and must be a non-empty string"), nil
+	}
+
+	status, ok := parameters["status"].(string)
+	validStatuses := map[string]bool{"running": true, "paused": true, "destroyed": true}
+	if !ok || !validStatuses[status] {
+		return mcp.NewToolResultError("'status' parameter is required and must be one of: 'running', 'paused', 'destroyed'"), nil
+	}
+
+	timeoutMinutes := 15.0
+	if v, ok := parameters["timeout_minutes"].(float64); ok && v > 0 {
+		timeoutMinutes = v
+	}
+	timeout := time.Duration(timeoutMinutes * float64(time.Minute))
+
+	// Report progress as time elapsed against the timeout
+	polls := 0
+	onPoll := func(current string, elapsed time.Duration) {
+		polls++
+		sendProgress(ctx, elapsed.Seconds(), timeout.Seconds(),
+			fmt.Sprintf("Instance %s is '%s', waiting for '%s' (%s elapsed)", instanceID, current, status, elapsed.Round(time.Second)))
+	}
+
+	start := time.Now()
+	lastStatus, err := waitForInstanceStatus(ctx, deps, instanceID, status, timeout, onPoll)
+
+	// Format the response
+	type waitResult struct {
+		Success        bool   `json:"success"`
+		Message        string `json:"message"`
+		Id             string `json:"id"`
+		TargetStatus   string `json:"target_status"`
+		Status         string `json:"status"`
+		ElapsedSeconds int    `json:"elapsed_seconds"`
+		Polls          int    `json:"polls"`
+	}
+
+	result := waitResult{
+		Success:        err == nil,
+		Id:             instanceID,
+		TargetStatus:   status,
+		Status:         lastStatus,
+		ElapsedSeconds: int(time.Since(start).Seconds()),
+		Polls:          polls,
+	}
+
+	if err != nil {
+		result.Message = fmt.Sprintf("Instance %s did not reach status '%s': %v", instanceID, status, err)
+	} else {
+		result.Message = fmt.Sprintf("Instance %s has reached status '%s'", instanceID, status)
+	}
+
+	jsonData, jsonErr := json.MarshalIndent(result, "", "  ")
+	if jsonErr != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to serialize results: %v", jsonErr)), nil
+	}
+
+	if err != nil {
+		return mcp.NewToolResultError(string(jsonData)), nil
+	}
+
+	return mcp.NewToolResultText(string(jsonData)), nil
+}
+
+// instanceStatusChangeResult formats the response for outcomes that change the status of an instance
+func instanceStatusChangeResult(message, instanceID, name, statusBefore, statusAfter string) (*mcp.CallToolResult, error) {
+	type statusChangeResult struct {
+		Success      bool   `json:"success"`
+		Message      string `json:"message"`
+		Id           string `json:"id"`
+		Name         string `json:"name"`
+		StatusBefore string `json:"status_before"`
+		StatusAfter  string `json:"status_after"`
+	}
+
+	result := statusChangeResult{
+		Success:      true,
+		Message:      message,
+		Id:           instanceID,
+		Name:         name,
+		StatusBefore: statusBefore,
+		StatusAfter:  statusAfter,
+	}
+
+	jsonData, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to serialize results: %v", err)), nil
+	}
+
+	return mcp.NewToolResultText(string(jsonData)), nil
+}
+
+// instanceDefinitionFromParameters checks the create-instance parameters are present and returns the
+// instance definition to send to the Aura API. Memory and type are not checked here as the sizes and
+// types on offer depend on the tenant; validateInstanceDefinition checks the whole combination
+func instanceDefinitionFromParameters(parameters map[string]interface{}) (*aura.CreateInstanceConfigData, error) {
+	// Validate and extract required parameters
+	name, ok := parameters["name"].(string)
+	if !ok || name == "" {
+		return nil, fmt.Errorf("'name' parameter is required and must be a non-empty string")
+	}
+
+	cloudProvider, ok := parameters["cloud_provider"].(string)
+	if !ok || cloudProvider == "" {
+		return nil, fmt.Errorf("'cloud_provider' parameter is required and must be one of: 'gcp', 'aws', 'azure'")
+	}
+
+	// Validate cloud provider
+	if !slices.Contains(config.ValidCloudProviders, cloudProvider) {
+		return nil, fmt.Errorf("Invalid cloud_provider '%s'. Must be one of: 'gcp', 'aws', 'azure'", cloudProvider)
+	}
+
+	region, ok := parameters["region"].(string)
+	if !ok || region == "" {
+		return nil, fmt.Errorf("'region' parameter is required and must be a non-empty string")
+	}
+
+	memory, ok := parameters["memory"].(string)
+	if !ok || memory == "" {
+		return nil, fmt.Errorf("'memory' parameter is required (e.g., '2GB', '8GB', '16GB', '32GB', '64GB')")
+	}
+
+	instanceType, ok := parameters["type"].(string)
+	if !ok || instanceType == "" {
+		return nil, fmt.Errorf("'type' parameter is required and must be one of: 'free', 'professional', 'enterprise'")
+	}
+
+	tenant, ok := parameters["tenantId"].(string)
+	if !ok || tenant == "" {
+		return nil, fmt.Errorf("'tenantId' parameter is required")
+	}
+
+	version := "5" // default
+	if v, ok := parameters["version"].(string); ok && v != "" {
+		version = v
+	}
+
+	instanceDefinition := aura.CreateInstanceConfigData{
+		Name:          name,
+		CloudProvider: cloudProvider,
+		Region:        region,
+		Memory:        memory,
+		Type:          instanceType,
+		Version:       version,
+		TenantId:      tenant,
+	}
+
+	return &instanceDefinition, nil
+}
+
+// These control how often the status of an instance is checked while waiting for it to change
+const (
+	waitInitialInterval = 5 * time.Second
+	waitMaxInterval     = 30 * time.Second
+)
+
+// waitForInstanceStatus polls an instance until it reaches the wanted status, the timeout passes or
+// ctx is cancelled. The interval between polls doubles each time up to waitMaxInterval.
+// onPoll, if not nil, is called with the status seen after each poll. The last seen status is returned
+func waitForInstanceStatus(ctx context.Context, deps *Dependencies, instanceID, status string, timeout time.Duration, onPoll func(status string, elapsed time.Duration)) (string, error) {
+	return pollInstanceStatus(ctx, deps, instanceID, fmt.Sprintf("status '%s'", status), status == "destroyed", func(s string) bool {
+		return s == status
+	}, timeout, onPoll)
+}
+
+// waitForInstanceToLeaveStatus polls an instance, in the same way as waitForInstanceStatus, until it
+// has any status other than the one given. The status it moved to is returned
+func waitForInstanceToLeaveStatus(ctx context.Context, deps *Dependencies, instanceID, status string, timeout time.Duration) (string, error) {
+	return pollInstanceStatus(ctx, deps, instanceID, fmt.Sprintf("status to change from '%s'", status), false, func(s string) bool {
+		return s != status
+	}, timeout, nil)
+}
+
+// pollInstanceStatus polls an instance until done returns true for its status. If goneIsDestroyed is
+// true an instance that is no longer found has the status 'destroyed'
+func pollInstanceStatus(ctx context.Context, deps *Dependencies, instanceID, waitingFor string, goneIsDestroyed bool, done func(status string) bool, timeout time.Duration, onPoll func(status string, elapsed time.Duration)) (string, error) {
+	start := time.Now()
+	deadline := start.Add(timeout)
+	interval := waitInitialInterval
+	lastStatus := ""
+
+	for {
+		instanceInfo, err := deps.AClient.Instances.Get(instanceID)
+		switch {
+		case err == nil:
+			lastStatus = instanceInfo.Data.Status
+		case goneIsDestroyed && isNotFound(err):
+			// A deleted instance is no longer found
+			lastStatus = "destroyed"
+		default:
+			return lastStatus, fmt.Errorf("failed to retrieve instance status: %w", err)
+		}
+
+		if onPoll != nil {
+			onPoll(lastStatus, time.Since(start))
+		}
+
+		if done(lastStatus) {
+			return lastStatus, nil
+		}
+
+		remaining := time.Until(deadline)
+		if remaining <= 0 {
+			return lastStatus, fmt.Errorf("timed out after %s waiting for %s, last status was '%s'", timeout, waitingFor, lastStatus)
+		}
+
+		// Never sleep past the deadline, so there is one last poll at it
+		select {
+		case <-ctx.Done():
+			return lastStatus, ctx.Err()
+		case <-time.After(min(interval, remaining)):
+		}
+
+		interval = min(interval*2, waitMaxInterval)
+	}
+}
+
+// isNotFound returns true if err is an Aura API error saying the resource does not exist
+func isNotFound(err error) bool {
+	var apiErr *aura.APIError
+	return errors.As(err, &apiErr) && apiErr.IsNotFound()
+}
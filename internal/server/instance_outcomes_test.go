@@ -0,0 +1,197 @@
+package server
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	aura "github.com/LackOfMorals/aura-client"
+)
+
+// listPage is the part of a list-instances response the tests look at
+type listPage struct {
+	TotalCount int      `json:"total_count"`
+	Returned   int      `json:"returned"`
+	NextCursor string   `json:"next_cursor"`
+	Notes      []string `json:"notes"`
+	Instances  []struct {
+		Id string `json:"id"`
+	} `json:"instances"`
+}
+
+// newListDependencies returns dependencies holding instances a1 to a5. a5 has a creation time
+// that cannot be read
+func newListDependencies(t *testing.T) *Dependencies {
+	t.Helper()
+	instance := func(id, name, created, tenant, cloud string) fakeInstance {
+		return fakeInstance{
+			GetInstanceData: aura.GetInstanceData{Id: id, Name: name, TenantId: tenant, CloudProvider: cloud},
+			Created:         created,
+		}
+	}
+	return newTestDependencies(t, newFakeInstances(
+		instance("a1", "dev-one", "2024-01-01T00:00:00Z", "t1", "gcp"),
+		instance("a2", "dev-two", "2024-02-01T00:00:00Z", "t1", "aws"),
+		instance("a3", "Prod-one", "2024-03-01T00:00:00Z", "t2", "gcp"),
+		instance("a4", "prod-two", "2024-04-01T00:00:00Z", "t2", "azure"),
+		instance("a5", "test", "sometime", "t1", "gcp"),
+	))
+}
+
+func listInstances(t *testing.T, deps *Dependencies, parameters map[string]interface{}) (listPage, string, bool) {
+	t.Helper()
+	result, err := executeListInstances(context.Background(), parameters, deps)
+	if err != nil {
+		t.Fatal(err)
+	}
+	text := toolResultText(result)
+	var page listPage
+	if !result.IsError {
+		if err := json.Unmarshal([]byte(text), &page); err != nil {
+			t.Fatalf("failed to parse %s: %v", text, err)
+		}
+	}
+	return page, text, result.IsError
+}
+
+func TestExecuteListInstances(t *testing.T) {
+	tests := []struct {
+		name       string
+		parameters map[string]interface{}
+		wantIDs    []string
+		wantNote   bool
+		wantErr    string
+	}{
+		{name: "default sort by name ignoring case", parameters: map[string]interface{}{}, wantIDs: []string{"a1", "a2", "a3", "a4", "a5"}},
+		{name: "name glob", parameters: map[string]interface{}{"name": "dev-*"}, wantIDs: []string{"a1", "a2"}},
+		{name: "name regex", parameters: map[string]interface{}{"name_regex": "(?i)^prod"}, wantIDs: []string{"a3", "a4"}},
+		{name: "cloud provider", parameters: map[string]interface{}{"cloud_provider": "gcp"}, wantIDs: []string{"a1", "a3", "a5"}},
+		{name: "tenant", parameters: map[string]interface{}{"tenant_id": "t2"}, wantIDs: []string{"a3", "a4"}},
+		{name: "descending", parameters: map[string]interface{}{"sort_by": "-id"}, wantIDs: []string{"a5", "a4", "a3", "a2", "a1"}},
+		{name: "ties sorted by id", parameters: map[string]interface{}{"sort_by": "cloud_provider"}, wantIDs: []string{"a2", "a4", "a1", "a3", "a5"}},
+		{name: "created after keeps unreadable times", parameters: map[string]interface{}{"created_after": "2024-02-15", "sort_by": "id"}, wantIDs: []string{"a3", "a4", "a5"}, wantNote: true},
+		{name: "created between", parameters: map[string]interface{}{"created_after": "2024-01-15T00:00:00Z", "created_before": "2024-03-15", "tenant_id": "t2"}, wantIDs: []string{"a3"}},
+		{name: "invalid glob", parameters: map[string]interface{}{"name": "["}, wantErr: "Invalid 'name' glob pattern"},
+		{name: "invalid regex", parameters: map[string]interface{}{"name_regex": "("}, wantErr: "Invalid 'name_regex'"},
+		{name: "invalid time", parameters: map[string]interface{}{"created_before": "yesterday"}, wantErr: "Invalid 'created_before'"},
+		{name: "invalid sort", parameters: map[string]interface{}{"sort_by": "memory"}, wantErr: "Invalid 'sort_by'"},
+		{name: "limit too small", parameters: map[string]interface{}{"limit": float64(0)}, wantErr: "'limit' must be between"},
+		{name: "limit too big", parameters: map[string]interface{}{"limit": float64(maxListInstancesLimit + 1)}, wantErr: "'limit' must be between"},
+		{name: "invalid cursor", parameters: map[string]interface{}{"cursor": "not-a-cursor"}, wantErr: "Invalid 'cursor'"},
+	}
+
+	deps := newListDependencies(t)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			page, text, isError := listInstances(t, deps, tt.parameters)
+			if tt.wantErr != "" {
+				if !isError || !strings.Contains(text, tt.wantErr) {
+					t.Fatalf("result = %s, want an error containing %q", text, tt.wantErr)
+				}
+				return
+			}
+			if isError {
+				t.Fatalf("result = %s, want success", text)
+			}
+
+			var got []string
+			for _, inst := range page.Instances {
+				got = append(got, inst.Id)
+			}
+			if strings.Join(got, ",") != strings.Join(tt.wantIDs, ",") {
+				t.Fatalf("instances = %v, want %v", got, tt.wantIDs)
+			}
+			if page.TotalCount != len(tt.wantIDs) || page.Returned != len(tt.wantIDs) || page.NextCursor != "" {
+				t.Fatalf("total_count = %d, returned = %d, next_cursor = %q, want all %d on one page", page.TotalCount, page.Returned, page.NextCursor, len(tt.wantIDs))
+			}
+			if gotNote := len(page.Notes) > 0 && strings.Contains(page.Notes[0], "a5"); gotNote != tt.wantNote {
+				t.Fatalf("notes = %v, want a note about a5 %t", page.Notes, tt.wantNote)
+			}
+		})
+	}
+}
+
+func TestExecuteListInstancesPages(t *testing.T) {
+	tests := []struct {
+		name      string
+		limit     float64
+		wantPages [][]string
+	}{
+		{name: "one per page", limit: 1, wantPages: [][]string{{"a5"}, {"a4"}, {"a3"}, {"a2"}, {"a1"}}},
+		{name: "uneven pages", limit: 2, wantPages: [][]string{{"a5", "a4"}, {"a3", "a2"}, {"a1"}}},
+		{name: "exact fit", limit: 5, wantPages: [][]string{{"a5", "a4", "a3", "a2", "a1"}}},
+		{name: "larger than the list", limit: 50, wantPages: [][]string{{"a5", "a4", "a3", "a2", "a1"}}},
+	}
+
+	deps := newListDependencies(t)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cursor := ""
+			for i, want := range tt.wantPages {
+				parameters := map[string]interface{}{"sort_by": "-id", "limit": tt.limit}
+				if cursor != "" {
+					parameters["cursor"] = cursor
+				}
+				page, text, isError := listInstances(t, deps, parameters)
+				if isError {
+					t.Fatalf("page %d = %s", i+1, text)
+				}
+
+				var got []string
+				for _, inst := range page.Instances {
+					got = append(got, inst.Id)
+				}
+				if strings.Join(got, ",") != strings.Join(want, ",") {
+					t.Fatalf("page %d = %v, want %v", i+1, got, want)
+				}
+				if page.TotalCount != 5 || page.Returned != len(want) {
+					t.Fatalf("page %d total_count = %d, returned = %d, want 5, %d", i+1, page.TotalCount, page.Returned, len(want))
+				}
+				last := i == len(tt.wantPages)-1
+				if (page.NextCursor == "") != last {
+					t.Fatalf("page %d next_cursor = %q, want one only if more pages follow", i+1, page.NextCursor)
+				}
+				cursor = page.NextCursor
+			}
+		})
+	}
+
+	// A cursor past the end gives an empty page rather than an error
+	page, text, isError := listInstances(t, deps, map[string]interface{}{"cursor": encodeListCursor(10)})
+	if isError || page.TotalCount != 5 || page.Returned != 0 || page.NextCursor != "" {
+		t.Fatalf("page past the end = %s, want an empty page", text)
+	}
+}
+
+func TestListCursor(t *testing.T) {
+	encode := func(s string) string { return base64.RawURLEncoding.EncodeToString([]byte(s)) }
+
+	tests := []struct {
+		name       string
+		cursor     string
+		wantOffset int
+		wantErr    bool
+	}{
+		{name: "start", cursor: encodeListCursor(0), wantOffset: 0},
+		{name: "offset", cursor: encodeListCursor(42), wantOffset: 42},
+		{name: "not base64", cursor: "!!!", wantErr: true},
+		{name: "no prefix", cursor: encode("42"), wantErr: true},
+		{name: "not a number", cursor: encode("offset:many"), wantErr: true},
+		{name: "negative", cursor: encode("offset:-1"), wantErr: true},
+		{name: "padded base64", cursor: base64.URLEncoding.EncodeToString([]byte("offset:1")), wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			offset, err := decodeListCursor(tt.cursor)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("decodeListCursor(%q) error = %v, want error %t", tt.cursor, err, tt.wantErr)
+			}
+			if !tt.wantErr && offset != tt.wantOffset {
+				t.Fatalf("decodeListCursor(%q) = %d, want %d", tt.cursor, offset, tt.wantOffset)
+			}
+		})
+	}
+}
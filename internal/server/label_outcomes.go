@@ -0,0 +1,284 @@
+// =============================================================================
+// These are the outcomes for the local labels and owner of instances
+//
+// Aura instances cannot be tagged, so labels such as team, environment or
+// purpose are kept locally; see label_store.go
+// =============================================================================
+
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// registerSetInstanceLabelsOutcome registers the set-instance-labels outcome
+func (r *OutcomeRegistry) registerSetInstanceLabelsOutcome() {
+	r.Outcomes["set-instance-labels"] = &Outcome{
+		ID:          "set-instance-labels",
+		Name:        "Set Instance Labels",
+		Description: "Set labels such as team, environment or purpose on an instance. Labels are kept locally by this server as Aura does not support them. Labels not mentioned are kept unless 'replace' is true. Give a label an empty value to remove it. A label that protects the instance from deletion can only be removed or changed with the unprotect secret.",
+		Type:        OutcomesTypeUpdate,
+		ReadOnly:    false,
+		Parameters: []OutcomeParameter{
+			{
+				Name:        "instance_id",
+				Type:        "string",
+				Description: "The ID of the instance",
+				Required:    true,
+			},
+			{
+				Name:        "labels",
+				Type:        "object",
+				Description: "Labels to set as an object of names to string values, such as {\"env\": \"dev\", \"team\": \"graph\"}",
+				Required:    true,
+			},
+			{
+				Name:        "replace",
+				Type:        "boolean",
+				Description: "Remove every label not in 'labels'",
+				Required:    false,
+				Default:     false,
+			},
+			{
+				Name:        "owner",
+				Type:        "string",
+				Description: "Change the recorded owner of the instance",
+				Required:    false,
+			},
+			{
+				Name:        "secret",
+				Type:        "string",
+				Description: "The unprotect secret, supplied by the user. Only needed to remove or change a label that protects the instance; see protect-instance",
+				Required:    false,
+			},
+		},
+		Metadata: map[string]interface{}{
+			"category": "labels",
+		},
+		Handler: executeSetInstanceLabels,
+	}
+}
+
+// registerGetInstanceLabelsOutcome registers the get-instance-labels outcome
+func (r *OutcomeRegistry) registerGetInstanceLabelsOutcome() {
+	r.Outcomes["get-instance-labels"] = &Outcome{
+		ID:          "get-instance-labels",
+		Name:        "Get Instance Labels",
+		Description: "Get the labels and owner recorded for an instance.",
+		Type:        OutcomesTypeRead,
+		ReadOnly:    true,
+		Parameters: []OutcomeParameter{
+			{
+				Name:        "instance_id",
+				Type:        "string",
+				Description: "The ID of the instance",
+				Required:    true,
+			},
+		},
+		Metadata: map[string]interface{}{
+			"category": "labels",
+		},
+		Handler: executeGetInstanceLabels,
+	}
+}
+
+// registerFindInstancesByLabelOutcome registers the find-instances-by-label outcome
+func (r *OutcomeRegistry) registerFindInstancesByLabelOutcome() {
+	r.Outcomes["find-instances-by-label"] = &Outcome{
+		ID:          "find-instances-by-label",
+		Name:        "Find Instances By Label",
+		Description: "Find the instances whose labels match a selector, and optionally have a given owner. Only instances that still exist in Aura are returned.",
+		Type:        OutcomesTypeList,
+		ReadOnly:    true,
+		Parameters: []OutcomeParameter{
+			{
+				Name:        "selector",
+				Type:        "string",
+				Description: "Comma separated labels to match, such as 'env=prod,team=graph'. A label name on its own matches any value. Either this or 'owner' must be supplied",
+				Required:    false,
+			},
+			{
+				Name:        "owner",
+				Type:        "string",
+				Description: "Only return instances with this owner",
+				Required:    false,
+			},
+		},
+		Metadata: map[string]interface{}{
+			"category": "labels",
+		},
+		Handler: executeFindInstancesByLabel,
+	}
+}
+
+// executeSetInstanceLabels implements the set-instance-labels outcome
+func executeSetInstanceLabels(ctx context.Context, parameters map[string]interface{}, deps *Dependencies) (*mcp.CallToolResult, error) {
+	if deps.AClient == nil {
+		return mcp.NewToolResultError("Aura API Client is not initialized"), nil
+	}
+
+	instanceID, ok := parameters["instance_id"].(string)
+	if !ok || instanceID == "" {
+		return mcp.NewToolResultError("instance_id parameter is required"), nil
+	}
+
+	labels, err := labelsFromParameter(parameters["labels"])
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	replace, _ := parameters["replace"].(bool)
+	owner, _ := parameters["owner"].(string)
+
+	// Only label instances that exist, and record their name to make the labels easier to read
+	instanceInfo, err := deps.AClient.Instances.Get(instanceID)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to retrieve instance: %v", err)), nil
+	}
+
+	// A label that protection matches on is protected as well
+	current, err := deps.Labels.Get(instanceID)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to load labels: %v", err)), nil
+	}
+	secret, _ := parameters["secret"].(string)
+	if err := checkLabelProtection(deps, instanceID, instanceInfo.Data.Name, current.Labels, changeLabels(current.Labels, labels, replace), secret); err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	if dry := dryRunFromContext(ctx); dry != nil {
+		dry.localChange("set labels %v on instance '%s' (ID: %s), replacing existing labels: %t", labels, instanceInfo.Data.Name, instanceID, replace)
+		if owner != "" {
+			dry.localChange("record %s as the owner of instance '%s' (ID: %s)", owner, instanceInfo.Data.Name, instanceID)
+		}
+		return dry.result(), nil
+	}
+
+	identity := callerIdentity(ctx, deps)
+	entry, err := deps.Labels.Update(instanceID, func(e *instanceLabels) {
+		e.Name = instanceInfo.Data.Name
+		e.UpdatedBy = identity
+		e.Labels = changeLabels(e.Labels, labels, replace)
+		if owner != "" {
+			e.Owner = owner
+		}
+	})
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to save labels: %v", err)), nil
+	}
+
+	jsonData, err := json.MarshalIndent(entry, "", "  ")
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to serialize results: %v", err)), nil
+	}
+
+	return mcp.NewToolResultText(string(jsonData)), nil
+}
+
+// changeLabels returns the labels of an instance after setting labels on it. A label with an empty
+// value is removed, as is every label not in labels if replace is true
+func changeLabels(current, labels map[string]string, replace bool) map[string]string {
+	result := map[string]string{}
+	if !replace {
+		for key, value := range current {
+			result[key] = value
+		}
+	}
+	for key, value := range labels {
+		if value == "" {
+			delete(result, key)
+		} else {
+			result[key] = value
+		}
+	}
+	return result
+}
+
+// executeGetInstanceLabels implements the get-instance-labels outcome
+func executeGetInstanceLabels(ctx context.Context, parameters map[string]interface{}, deps *Dependencies) (*mcp.CallToolResult, error) {
+	instanceID, ok := parameters["instance_id"].(string)
+	if !ok || instanceID == "" {
+		return mcp.NewToolResultError("instance_id parameter is required"), nil
+	}
+
+	entry, err := deps.Labels.Get(instanceID)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to load labels: %v", err)), nil
+	}
+
+	jsonData, err := json.MarshalIndent(entry, "", "  ")
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to serialize results: %v", err)), nil
+	}
+
+	return mcp.NewToolResultText(string(jsonData)), nil
+}
+
+// executeFindInstancesByLabel implements the find-instances-by-label outcome
+func executeFindInstancesByLabel(ctx context.Context, parameters map[string]interface{}, deps *Dependencies) (*mcp.CallToolResult, error) {
+	if deps.AClient == nil {
+		return mcp.NewToolResultError("Aura API Client is not initialized"), nil
+	}
+
+	selectorText, _ := parameters["selector"].(string)
+	owner, _ := parameters["owner"].(string)
+	if selectorText == "" && owner == "" {
+		return mcp.NewToolResultError("Either 'selector' or 'owner' must be supplied"), nil
+	}
+
+	var selector map[string]*string
+	if selectorText != "" {
+		s, err := parseLabelSelector(selectorText)
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+		selector = s
+	}
+
+	entries, err := deps.Labels.All()
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to load labels: %v", err)), nil
+	}
+
+	// Leave out instances that have been deleted, and report current names
+	instances, err := deps.AClient.Instances.List()
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to list instances: %v", err)), nil
+	}
+	names := make(map[string]string, len(instances.Data))
+	for _, inst := range instances.Data {
+		names[inst.Id] = inst.Name
+	}
+
+	matches := []instanceLabels{}
+	for _, id := range sortedLabelIDs(entries) {
+		entry := entries[id]
+		name, exists := names[id]
+		if !exists {
+			continue
+		}
+		if owner != "" && entry.Owner != owner {
+			continue
+		}
+		if selector != nil && !matchesLabelSelector(entry.Labels, selector) {
+			continue
+		}
+		entry.Name = name
+		matches = append(matches, entry)
+	}
+
+	if len(matches) == 0 {
+		return mcp.NewToolResultText("No instances found with matching labels."), nil
+	}
+
+	jsonData, err := json.MarshalIndent(matches, "", "  ")
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to serialize results: %v", err)), nil
+	}
+
+	return mcp.NewToolResultText(string(jsonData)), nil
+}
@@ -0,0 +1,230 @@
+// label_store.go holds the local labels and owner of each instance. Aura instances cannot
+// be tagged so these are kept in a file in the state directory, keyed by instance ID
+
+package server
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/LackOfMorals/mcp4AuraAPI/internal/state"
+)
+
+// validLabelKey limits label keys to short, lower case names such as 'env' or 'team.name'
+var validLabelKey = regexp.MustCompile(`^[a-z0-9][a-z0-9._/-]{0,62}$`)
+
+// maxLabelValueLength is the longest label value that can be stored
+const maxLabelValueLength = 256
+
+// instanceLabels is the local metadata kept for one instance
+type instanceLabels struct {
+	InstanceId string            `json:"instance_id"`
+	Name       string            `json:"name"`
+	Owner      string            `json:"owner,omitempty"`
+	Labels     map[string]string `json:"labels"`
+	UpdatedAt  time.Time         `json:"updated_at"`
+	UpdatedBy  string            `json:"updated_by,omitempty"`
+}
+
+// LabelStore keeps the labels and owner of instances in a local file
+type LabelStore struct {
+	mu      sync.Mutex
+	path    string
+	entries map[string]*instanceLabels
+	loaded  bool
+}
+
+// NewLabelStore creates a store kept in labels.json in the state directory
+func NewLabelStore(stateDir string) *LabelStore {
+	return &LabelStore{
+		path:    filepath.Join(stateDir, "labels.json"),
+		entries: make(map[string]*instanceLabels),
+	}
+}
+
+// load reads the file the first time the store is used. Must be called with mu held
+func (l *LabelStore) load() error {
+	if l.loaded {
+		return nil
+	}
+	if err := state.Load(l.path, &l.entries); err != nil {
+		return err
+	}
+	if l.entries == nil {
+		l.entries = make(map[string]*instanceLabels)
+	}
+	l.loaded = true
+	return nil
+}
+
+// Get returns the labels of an instance. An instance without any has an empty set
+func (l *LabelStore) Get(instanceID string) (instanceLabels, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if err := l.load(); err != nil {
+		return instanceLabels{}, err
+	}
+	entry, ok := l.entries[instanceID]
+	if !ok {
+		return instanceLabels{InstanceId: instanceID, Labels: map[string]string{}}, nil
+	}
+	return copyInstanceLabels(entry), nil
+}
+
+// All returns the labels of every instance that has any, keyed by instance ID
+func (l *LabelStore) All() (map[string]instanceLabels, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if err := l.load(); err != nil {
+		return nil, err
+	}
+	result := make(map[string]instanceLabels, len(l.entries))
+	for id, entry := range l.entries {
+		result[id] = copyInstanceLabels(entry)
+	}
+	return result, nil
+}
+
+// Update changes the labels of an instance using fn, creating an entry if it has none
+func (l *LabelStore) Update(instanceID string, fn func(*instanceLabels)) (instanceLabels, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if err := l.load(); err != nil {
+		return instanceLabels{}, err
+	}
+	entry, ok := l.entries[instanceID]
+	if !ok {
+		entry = &instanceLabels{InstanceId: instanceID, Labels: map[string]string{}}
+		l.entries[instanceID] = entry
+	}
+	fn(entry)
+	entry.UpdatedAt = time.Now().UTC()
+	if err := state.Save(l.path, l.entries); err != nil {
+		return instanceLabels{}, err
+	}
+	return copyInstanceLabels(entry), nil
+}
+
+// Remove forgets the labels of an instance
+func (l *LabelStore) Remove(instanceID string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if err := l.load(); err != nil {
+		return err
+	}
+	if _, ok := l.entries[instanceID]; !ok {
+		return nil
+	}
+	delete(l.entries, instanceID)
+	return state.Save(l.path, l.entries)
+}
+
+// copyInstanceLabels returns a copy of entry that can be changed without affecting the store
+func copyInstanceLabels(entry *instanceLabels) instanceLabels {
+	result := *entry
+	result.Labels = make(map[string]string, len(entry.Labels))
+	for k, v := range entry.Labels {
+		result.Labels[k] = v
+	}
+	return result
+}
+
+// labelsFromParameter checks and extracts a set of labels given as a JSON object. A value that
+// is empty or null means the label should be removed, and is returned as an empty string
+func labelsFromParameter(value interface{}) (map[string]string, error) {
+	raw, ok := value.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("labels must be an object of label names to string values, such as {\"env\": \"dev\"}")
+	}
+
+	labels := make(map[string]string, len(raw))
+	for key, v := range raw {
+		if !validLabelKey.MatchString(key) {
+			return nil, fmt.Errorf("label name '%s' is not valid. Use up to 63 lower case letters, digits, '.', '_', '/' or '-', starting with a letter or digit", key)
+		}
+		switch v := v.(type) {
+		case nil:
+			labels[key] = ""
+		case string:
+			if len(v) > maxLabelValueLength {
+				return nil, fmt.Errorf("the value of label '%s' is longer than %d characters", key, maxLabelValueLength)
+			}
+			labels[key] = v
+		default:
+			return nil, fmt.Errorf("the value of label '%s' must be a string", key)
+		}
+	}
+	return labels, nil
+}
+
+// parseLabelSelector parses a selector such as 'env=prod,team=graph'. A name on its own
+// matches any instance that has that label, whatever its value
+func parseLabelSelector(selector string) (map[string]*string, error) {
+	result := map[string]*string{}
+	for _, part := range strings.Split(selector, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		key, value, hasValue := strings.Cut(part, "=")
+		key = strings.TrimSpace(key)
+		if !validLabelKey.MatchString(key) {
+			return nil, fmt.Errorf("'%s' in the selector is not a valid label name", key)
+		}
+		if hasValue {
+			v := strings.TrimSpace(value)
+			result[key] = &v
+		} else {
+			result[key] = nil
+		}
+	}
+	if len(result) == 0 {
+		return nil, fmt.Errorf("the selector must name at least one label, such as 'env=prod'")
+	}
+	return result, nil
+}
+
+// matchesLabelSelector returns true if labels satisfy every part of selector
+func matchesLabelSelector(labels map[string]string, selector map[string]*string) bool {
+	for key, want := range selector {
+		got, ok := labels[key]
+		if !ok || (want != nil && got != *want) {
+			return false
+		}
+	}
+	return true
+}
+
+// sortedLabelIDs returns the instance IDs of entries in name order
+func sortedLabelIDs(entries map[string]instanceLabels) []string {
+	ids := make([]string, 0, len(entries))
+	for id := range entries {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		a, b := entries[ids[i]], entries[ids[j]]
+		if a.Name == b.Name {
+			return a.InstanceId < b.InstanceId
+		}
+		return a.Name < b.Name
+	})
+	return ids
+}
+
+// callerIdentity returns who is calling the server
+func callerIdentity(ctx context.Context, deps *Dependencies) string {
+	if deps.Config == nil || deps.Config.Identity == "" {
+		return "unknown"
+	}
+	return deps.Config.Identity
+}
@@ -0,0 +1,772 @@
+// =============================================================================
+// These are the outcomes that move an instance to another region or cloud provider
+//
+// A migration is a workflow of steps that is saved to a local file after every
+// step so that it can be resumed, for example after the server restarts. The
+// source instance is not changed until the target has been verified, including
+// a snapshot of its data, and is only deleted if that was asked for when the
+// migration was started
+// =============================================================================
+
+package server
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/LackOfMorals/aura-client"
+	"github.com/LackOfMorals/mcp4AuraAPI/internal/state"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// The steps of a migration, in the order they are run
+const (
+	migrationStepCreateTarget  = "create_target"
+	migrationStepWaitTarget    = "wait_for_target"
+	migrationStepOverwrite     = "overwrite_target"
+	migrationStepWaitOverwrite = "wait_for_overwrite"
+	migrationStepVerify        = "verify_target"
+	migrationStepDeleteSource  = "delete_source"
+)
+
+var migrationSteps = []string{
+	migrationStepCreateTarget,
+	migrationStepWaitTarget,
+	migrationStepOverwrite,
+	migrationStepWaitOverwrite,
+	migrationStepVerify,
+	migrationStepDeleteSource,
+}
+
+// The status of a migration step
+const (
+	stepPending   = "pending"
+	stepRunning   = "running"
+	stepCompleted = "completed"
+	stepFailed    = "failed"
+	stepSkipped   = "skipped"
+)
+
+// validWorkflowID limits workflow IDs to those that are safe to use as a file name
+var validWorkflowID = regexp.MustCompile(`^mig-[a-z0-9-]+$`)
+
+// migrationLocks stops the same migration being run twice at the same time
+var migrationLocks sync.Map
+
+// migrationStep is the progress of a single step of a migration
+type migrationStep struct {
+	Name        string     `json:"name"`
+	Status      string     `json:"status"`
+	StartedAt   *time.Time `json:"started_at,omitempty"`
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+	Detail      string     `json:"detail,omitempty"`
+}
+
+// migrationWorkflow is everything needed to run or resume a migration
+type migrationWorkflow struct {
+	Id                  string          `json:"id"`
+	CreatedAt           time.Time       `json:"created_at"`
+	UpdatedAt           time.Time       `json:"updated_at"`
+	Status              string          `json:"status"`
+	SourceInstanceId    string          `json:"source_instance_id"`
+	SourceInstanceName  string          `json:"source_instance_name"`
+	TargetInstanceId    string          `json:"target_instance_id,omitempty"`
+	TargetName          string          `json:"target_name"`
+	TargetTenantId      string          `json:"target_tenant_id"`
+	TargetCloudProvider string          `json:"target_cloud_provider"`
+	TargetRegion        string          `json:"target_region"`
+	TargetMemory        string          `json:"target_memory"`
+	TargetType          string          `json:"target_type"`
+	DeleteSource        bool            `json:"delete_source"`
+	WaitTimeoutMinutes  float64         `json:"wait_timeout_minutes"`
+	TargetTTL           string          `json:"target_ttl,omitempty"`
+	TargetTTLAction     string          `json:"target_ttl_action,omitempty"`
+	TargetCreateSent    bool            `json:"target_create_sent,omitempty"` // Saved just before the request to create the target is sent
+	OverwriteStarted    bool            `json:"overwrite_started,omitempty"`  // The target has been seen to leave running after the overwrite was requested
+	TargetSnapshotId    string          `json:"target_snapshot_id,omitempty"` // The snapshot taken of the target to verify its data
+	Steps               []migrationStep `json:"steps"`
+
+	path string
+}
+
+// registerMigrateInstanceOutcome registers the migrate-instance outcome
+func (r *OutcomeRegistry) registerMigrateInstanceOutcome() {
+	r.Outcomes["migrate-instance"] = &Outcome{
+		ID:          "migrate-instance",
+		Name:        "Migrate Instance",
+		Description: "Move a Neo4j Aura database instance to another region and / or cloud provider as one resumable workflow: create the target, wait for it, overwrite it from the source, wait for the overwrite, verify the target with a snapshot of its data and, only if asked for, delete the source. The source is not changed until the target is verified. The target is named with a suffix unique to the migration, so that an instance created by an interrupted attempt can be told apart from any other. Progress is saved after every step; if the workflow is interrupted, for example by a server restart, call again with just 'workflow_id' to resume from the last completed step. Reports the status of every step. Connection credentials for the target are only shown when it is created.",
+		Type:        OutcomesTypeCreate,
+		ReadOnly:    false,
+		Parameters: []OutcomeParameter{
+			{
+				Name:        "workflow_id",
+				Type:        "string",
+				Description: "ID of an existing migration to resume. When supplied all other parameters are ignored",
+				Required:    false,
+			},
+			{
+				Name:        "source_instance_id",
+				Type:        "string",
+				Description: "The ID of the instance to migrate. Required to start a migration",
+				Required:    false,
+			},
+			{
+				Name:        "target_region",
+				Type:        "string",
+				Description: "Region for the target instance. Required to start a migration",
+				Required:    false,
+			},
+			{
+				Name:        "target_cloud_provider",
+				Type:        "string",
+				Description: "Cloud provider for the target instance. Defaults to that of the source",
+				Required:    false,
+			},
+			{
+				Name:        "target_name",
+				Type:        "string",
+				Description: "Name for the target instance, to which a suffix unique to the migration is added, e.g. 'orders-eu' becomes 'orders-eu-3fa91c'. Defaults to the source name followed by the target region",
+				Required:    false,
+			},
+			{
+				Name:        "target_memory",
+				Type:        "string",
+				Description: "Memory for the target instance. Defaults to that of the source. Must not be smaller than the source",
+				Required:    false,
+			},
+			{
+				Name:        "target_tenant_id",
+				Type:        "string",
+				Description: "Tenant for the target instance. Defaults to that of the source",
+				Required:    false,
+			},
+			{
+				Name:        "delete_source",
+				Type:        "boolean",
+				Description: "Set to true to delete the source instance once the target has been verified. The source is kept if this is not set",
+				Required:    false,
+				Default:     false,
+			},
+			{
+				Name:        "wait_timeout_minutes",
+				Type:        "number",
+				Description: "How long to wait for the target, or the snapshot of it, at each waiting step before stopping. The migration can then be resumed",
+				Required:    false,
+				Default:     30,
+			},
+			{
+				Name:        "ttl",
+				Type:        "string",
+				Description: "How long the target instance is needed for, such as '4h' or '2d', counted from when it is created. Once it has passed the target is paused or deleted. Leave out for a target that is kept until deleted",
+				Required:    false,
+			},
+			{
+				Name:        "ttl_action",
+				Type:        "string",
+				Description: "What to do when the TTL of the target has passed: 'pause' or 'delete'. Defaults to the server's REAPER_POLICY",
+				Required:    false,
+			},
+		},
+		Metadata: map[string]interface{}{
+			"category":    "instances",
+			"destructive": true,
+			"warning":     "If delete_source is true the source instance and all its data are permanently deleted once the target has been verified.",
+		},
+		Handler: executeMigrateInstance,
+	}
+}
+
+// executeMigrateInstance implements the migrate-instance outcome
+func executeMigrateInstance(ctx context.Context, parameters map[string]interface{}, deps *Dependencies) (*mcp.CallToolResult, error) {
+	if deps.AClient == nil {
+		return mcp.NewToolResultError("Aura API Client is not initialized"), nil
+	}
+
+	if deps.Config == nil || deps.Config.StateDir == "" {
+		return mcp.NewToolResultError("No state directory is configured. Set STATE_DIR to use migrations"), nil
+	}
+
+	var workflow *migrationWorkflow
+	var err error
+
+	if workflowID, _ := parameters["workflow_id"].(string); workflowID != "" {
+		workflow, err = loadMigrationWorkflow(deps, workflowID)
+	} else {
+		workflow, err = newMigrationWorkflow(ctx, deps, parameters)
+	}
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	if dry := dryRunFromContext(ctx); dry != nil {
+		migrationDryRun(dry, workflow)
+		return dry.result(), nil
+	}
+
+	if err := workflow.save(); err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	// Only one call at a time may run a given migration
+	lock, _ := migrationLocks.LoadOrStore(workflow.Id, &sync.Mutex{})
+	if !lock.(*sync.Mutex).TryLock() {
+		return mcp.NewToolResultError(fmt.Sprintf("Migration %s is already running", workflow.Id)), nil
+	}
+	defer lock.(*sync.Mutex).Unlock()
+
+	credentials := runMigrationWorkflow(ctx, deps, workflow)
+
+	// Format the response
+	type migrateResult struct {
+		Success bool   `json:"success"`
+		Message string `json:"message"`
+		*migrationWorkflow
+		Username string `json:"User,omitempty"`
+		Password string `json:"Password,omitempty"`
+	}
+
+	result := migrateResult{
+		Success:           workflow.Status == stepCompleted,
+		migrationWorkflow: workflow,
+		Username:          credentials[0],
+		Password:          credentials[1],
+	}
+
+	switch workflow.Status {
+	case stepCompleted:
+		result.Message = fmt.Sprintf("Instance '%s' (ID: %s) has been migrated to '%s' (ID: %s) in %s %s", workflow.SourceInstanceName, workflow.SourceInstanceId, workflow.TargetName, workflow.TargetInstanceId, workflow.TargetCloudProvider, workflow.TargetRegion)
+	default:
+		result.Message = fmt.Sprintf("Migration %s stopped before it finished. Fix the problem shown in the failed step and call migrate-instance with workflow_id '%s' to resume", workflow.Id, workflow.Id)
+	}
+
+	jsonData, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to serialize results: %v", err)), nil
+	}
+
+	if !result.Success {
+		return mcp.NewToolResultError(string(jsonData)), nil
+	}
+
+	return mcp.NewToolResultText(string(jsonData)), nil
+}
+
+// registerListMigrationsOutcome registers the list-migrations outcome
+func (r *OutcomeRegistry) registerListMigrationsOutcome() {
+	r.Outcomes["list-migrations"] = &Outcome{
+		ID:          "list-migrations",
+		Name:        "List Migrations",
+		Description: "List the migrations started by migrate-instance with the status of each one and its steps. Use this to find a migration to resume.",
+		Type:        OutcomesTypeList,
+		ReadOnly:    true,
+		Parameters:  []OutcomeParameter{}, // No parameters needed for listing
+		Metadata: map[string]interface{}{
+			"category": "instances",
+		},
+		Handler: executeListMigrations,
+	}
+}
+
+// executeListMigrations implements the list-migrations outcome
+func executeListMigrations(ctx context.Context, parameters map[string]interface{}, deps *Dependencies) (*mcp.CallToolResult, error) {
+	if deps.Config == nil || deps.Config.StateDir == "" {
+		return mcp.NewToolResultError("No state directory is configured. Set STATE_DIR to use migrations"), nil
+	}
+
+	files, err := filepath.Glob(filepath.Join(deps.Config.StateDir, "migrations", "mig-*.json"))
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to list migrations: %v", err)), nil
+	}
+
+	workflows := []migrationWorkflow{}
+	for _, file := range files {
+		var workflow migrationWorkflow
+		if err := state.Load(file, &workflow); err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("Failed to load migration: %v", err)), nil
+		}
+		workflows = append(workflows, workflow)
+	}
+
+	if len(workflows) == 0 {
+		return mcp.NewToolResultText("No migrations found."), nil
+	}
+
+	sort.Slice(workflows, func(i, j int) bool {
+		return workflows[i].CreatedAt.After(workflows[j].CreatedAt)
+	})
+
+	jsonData, err := json.MarshalIndent(workflows, "", "  ")
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to serialize results: %v", err)), nil
+	}
+
+	return mcp.NewToolResultText(string(jsonData)), nil
+}
+
+// newMigrationWorkflow checks the parameters and works out the target of a new workflow
+func newMigrationWorkflow(ctx context.Context, deps *Dependencies, parameters map[string]interface{}) (*migrationWorkflow, error) {
+	sourceID, ok := parameters["source_instance_id"].(string)
+	if !ok || sourceID == "" {
+		return nil, fmt.Errorf("'source_instance_id' parameter is required to start a migration, or give 'workflow_id' to resume one")
+	}
+
+	targetRegion, ok := parameters["target_region"].(string)
+	if !ok || targetRegion == "" {
+		return nil, fmt.Errorf("'target_region' parameter is required to start a migration")
+	}
+
+	sourceInfo, err := deps.AClient.Instances.Get(sourceID)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to retrieve source instance details: %v. The instance may not exist or you may not have access to it.", err)
+	}
+	source := sourceInfo.Data
+
+	if source.Status != "running" {
+		return nil, fmt.Errorf("Source instance '%s' (ID: %s) is '%s'. It must be running to be migrated", source.Name, sourceID, source.Status)
+	}
+
+	// The suffix makes the workflow ID and the target name unique to this migration
+	suffix := make([]byte, 3)
+	if _, err := rand.Read(suffix); err != nil {
+		return nil, fmt.Errorf("Failed to create a workflow ID: %v", err)
+	}
+
+	stringParameter := func(key, fallback string) string {
+		if v, ok := parameters[key].(string); ok && v != "" {
+			return v
+		}
+		return fallback
+	}
+
+	workflow := &migrationWorkflow{
+		SourceInstanceId:    sourceID,
+		SourceInstanceName:  source.Name,
+		TargetCloudProvider: stringParameter("target_cloud_provider", source.CloudProvider),
+		TargetRegion:        targetRegion,
+		TargetName:          stringParameter("target_name", source.Name+"-"+targetRegion) + "-" + hex.EncodeToString(suffix),
+		TargetMemory:        stringParameter("target_memory", source.Memory),
+		TargetTenantId:      stringParameter("target_tenant_id", source.TenantId),
+		TargetType:          source.Type,
+		WaitTimeoutMinutes:  30,
+	}
+
+	if v, ok := parameters["delete_source"].(bool); ok {
+		workflow.DeleteSource = v
+	}
+	if v, ok := parameters["wait_timeout_minutes"].(float64); ok && v > 0 {
+		workflow.WaitTimeoutMinutes = v
+	}
+	if _, _, err := ttlFromParameters(parameters); err != nil {
+		return nil, err
+	}
+	workflow.TargetTTL, _ = parameters["ttl"].(string)
+	workflow.TargetTTLAction, _ = parameters["ttl_action"].(string)
+
+	if workflow.TargetCloudProvider == source.CloudProvider && workflow.TargetRegion == source.Region {
+		return nil, fmt.Errorf("The target cloud provider and region are the same as the source. Nothing to migrate")
+	}
+
+	if sizeInGB(workflow.TargetMemory) < sizeInGB(source.Memory) {
+		return nil, fmt.Errorf("'target_memory' %s is smaller than the source memory %s", workflow.TargetMemory, source.Memory)
+	}
+
+	// Check the target is something that can be created before starting
+	definition, err := instanceDefinitionFromParameters(workflow.createParameters())
+	if err != nil {
+		return nil, err
+	}
+	if err := validateInstanceDefinition(deps, definition); err != nil {
+		return nil, err
+	}
+
+	// The rules for create-instance apply to the target too
+	if err := checkCreateGuardrails(ctx, workflow.createParameters(), deps); err != nil {
+		return nil, err
+	}
+
+	// An instance that already has the target name is never taken over as the target
+	existing, err := findInstanceByName(deps, workflow.TargetName, workflow.TargetTenantId)
+	if err != nil {
+		return nil, err
+	}
+	if existing != nil {
+		return nil, fmt.Errorf("There is already an instance named '%s' (ID: %s) in tenant %s. Give a different 'target_name'", existing.Name, existing.Id, existing.TenantId)
+	}
+
+	now := time.Now().UTC()
+	workflow.Id = fmt.Sprintf("mig-%s-%s", now.Format("20060102-150405"), hex.EncodeToString(suffix))
+	workflow.CreatedAt = now
+	workflow.Status = stepPending
+	workflow.path = filepath.Join(deps.Config.StateDir, "migrations", workflow.Id+".json")
+
+	for _, name := range migrationSteps {
+		workflow.Steps = append(workflow.Steps, migrationStep{Name: name, Status: stepPending})
+	}
+
+	return workflow, nil
+}
+
+// migrationDryRun records what running the steps of workflow that have not completed would do
+func migrationDryRun(dry *dryRun, workflow *migrationWorkflow) {
+	if _, err := os.Stat(workflow.path); err != nil {
+		dry.localChange("save a new migration workflow for instance '%s' (ID: %s) in the state directory", workflow.SourceInstanceName, workflow.SourceInstanceId)
+	}
+
+	targetID := workflow.TargetInstanceId
+	if targetID == "" {
+		targetID = "<new instance id>"
+	}
+
+	for _, step := range workflow.Steps {
+		if step.Status == stepCompleted || step.Status == stepSkipped {
+			continue
+		}
+		switch step.Name {
+		case migrationStepCreateTarget:
+			definition, _ := instanceDefinitionFromParameters(workflow.createParameters())
+			dry.apiCall("POST", "/instances", definition)
+			if workflow.TargetTTL != "" {
+				dry.localChange("expire the target instance after %s", workflow.TargetTTL)
+			}
+		case migrationStepOverwrite:
+			dry.apiCall("POST", "/instances/"+targetID+"/overwrite", overwriteBody(workflow.SourceInstanceId, ""))
+		case migrationStepVerify:
+			if workflow.TargetSnapshotId == "" {
+				dry.apiCall("POST", "/instances/"+targetID+"/snapshots", nil)
+			}
+		case migrationStepDeleteSource:
+			if workflow.DeleteSource {
+				dry.apiCall("DELETE", "/instances/"+workflow.SourceInstanceId, nil)
+			}
+		}
+	}
+}
+
+// loadMigrationWorkflow loads a saved workflow so that it can be resumed
+func loadMigrationWorkflow(deps *Dependencies, workflowID string) (*migrationWorkflow, error) {
+	if !validWorkflowID.MatchString(workflowID) {
+		return nil, fmt.Errorf("Invalid workflow_id '%s'", workflowID)
+	}
+
+	path := filepath.Join(deps.Config.StateDir, "migrations", workflowID+".json")
+	if _, err := os.Stat(path); err != nil {
+		return nil, fmt.Errorf("Migration '%s' not found. Use list-migrations to see the migrations", workflowID)
+	}
+
+	workflow := &migrationWorkflow{}
+	if err := state.Load(path, workflow); err != nil {
+		return nil, fmt.Errorf("Failed to load migration: %v", err)
+	}
+	workflow.path = path
+
+	return workflow, nil
+}
+
+// runMigrationWorkflow runs each step that has not completed, saving the workflow after each change.
+// It stops at the first step that fails. The username and password of the target are returned if
+// it was created by this call
+func runMigrationWorkflow(ctx context.Context, deps *Dependencies, workflow *migrationWorkflow) [2]string {
+	var credentials [2]string
+	timeout := time.Duration(workflow.WaitTimeoutMinutes * float64(time.Minute))
+
+	workflow.Status = stepRunning
+	_ = workflow.save()
+
+	for i := range workflow.Steps {
+		step := &workflow.Steps[i]
+		if step.Status == stepCompleted || step.Status == stepSkipped {
+			continue
+		}
+
+		sendProgress(ctx, float64(i), float64(len(workflow.Steps)), fmt.Sprintf("Migration %s: %s", workflow.Id, step.Name))
+
+		now := time.Now().UTC()
+		step.StartedAt = &now
+		step.CompletedAt = nil
+		step.Status = stepRunning
+		step.Detail = ""
+		if err := workflow.save(); err != nil {
+			step.Status = stepFailed
+			step.Detail = err.Error()
+			workflow.Status = stepFailed
+			return credentials
+		}
+
+		var err error
+		switch step.Name {
+		case migrationStepCreateTarget:
+			credentials, err = migrationCreateTarget(ctx, deps, workflow, step)
+		case migrationStepWaitTarget:
+			_, err = waitForInstanceStatus(ctx, deps, workflow.TargetInstanceId, "running", timeout, nil)
+			step.Detail = "target instance is running"
+		case migrationStepOverwrite:
+			err = checkDeletionProtection(deps, workflow.TargetInstanceId, workflow.TargetName)
+			if err == nil {
+				_, err = deps.AClient.Instances.Overwrite(workflow.TargetInstanceId, workflow.SourceInstanceId, "")
+			}
+			workflow.OverwriteStarted = false
+			workflow.TargetSnapshotId = ""
+			step.Detail = "overwrite of the target from the source has been requested"
+		case migrationStepWaitOverwrite:
+			err = migrationWaitForOverwrite(ctx, deps, workflow, timeout)
+			step.Detail = "overwrite of the target has finished"
+		case migrationStepVerify:
+			err = migrationVerifyTarget(ctx, deps, workflow, step, timeout)
+		case migrationStepDeleteSource:
+			err = migrationDeleteSource(ctx, deps, workflow, step)
+		}
+
+		done := time.Now().UTC()
+		if err != nil {
+			step.Status = stepFailed
+			step.Detail = err.Error()
+			workflow.Status = stepFailed
+			_ = workflow.save()
+			return credentials
+		}
+
+		if step.Status == stepRunning {
+			step.Status = stepCompleted
+		}
+		step.CompletedAt = &done
+		if err := workflow.save(); err != nil {
+			workflow.Status = stepFailed
+			step.Detail += ". " + err.Error()
+			return credentials
+		}
+	}
+
+	workflow.Status = stepCompleted
+	_ = workflow.save()
+	sendProgress(ctx, float64(len(workflow.Steps)), float64(len(workflow.Steps)), fmt.Sprintf("Migration %s completed", workflow.Id))
+
+	return credentials
+}
+
+// migrationCreateTarget creates the target instance. The target name is unique to the migration and
+// it is saved that the create request is being sent before it is. An instance with the target name is
+// only taken to be the one an earlier attempt created if that attempt had sent the request
+func migrationCreateTarget(ctx context.Context, deps *Dependencies, workflow *migrationWorkflow, step *migrationStep) ([2]string, error) {
+	if workflow.TargetInstanceId != "" {
+		step.Detail = fmt.Sprintf("target instance %s was already created", workflow.TargetInstanceId)
+		return [2]string{}, nil
+	}
+
+	existing, err := findInstanceByName(deps, workflow.TargetName, workflow.TargetTenantId)
+	if err != nil {
+		return [2]string{}, err
+	}
+	if existing != nil {
+		if !workflow.TargetCreateSent {
+			return [2]string{}, fmt.Errorf("there is already an instance named '%s' (ID: %s) in tenant %s, but this migration has not asked for its target to be created so it is not used. Rename or delete that instance before resuming", existing.Name, existing.Id, existing.TenantId)
+		}
+		workflow.TargetInstanceId = existing.Id
+		step.Detail = fmt.Sprintf("found target instance %s created by an earlier attempt. Its credentials were shown when it was created", existing.Id)
+		migrationRecordTTL(deps, workflow, step)
+		return [2]string{}, nil
+	}
+
+	definition, err := instanceDefinitionFromParameters(workflow.createParameters())
+	if err != nil {
+		return [2]string{}, err
+	}
+
+	// The rules may have changed since the migration was started
+	if err := checkCreateGuardrails(ctx, workflow.createParameters(), deps); err != nil {
+		return [2]string{}, err
+	}
+
+	workflow.TargetCreateSent = true
+	if err := workflow.save(); err != nil {
+		return [2]string{}, err
+	}
+
+	instance, err := deps.AClient.Instances.Create(definition)
+	if err != nil {
+		return [2]string{}, fmt.Errorf("failed to create target instance: %w", err)
+	}
+
+	workflow.TargetInstanceId = instance.Data.Id
+	step.Detail = fmt.Sprintf("created target instance %s", instance.Data.Id)
+	migrationRecordTTL(deps, workflow, step)
+	return [2]string{instance.Data.Username, instance.Data.Password}, nil
+}
+
+// migrationRecordTTL records when the target expires, if the migration was started with a ttl. As
+// with create-instance, a TTL that cannot be recorded does not stop the target being used
+func migrationRecordTTL(deps *Dependencies, workflow *migrationWorkflow, step *migrationStep) {
+	if workflow.TargetTTL == "" {
+		return
+	}
+	ttl, err := parseTTL(workflow.TargetTTL)
+	if err == nil {
+		var expiry time.Time
+		expiry, err = recordTTL(deps, workflow.TargetInstanceId, workflow.TargetName, ttl, workflow.TargetTTLAction)
+		if err == nil {
+			step.Detail += fmt.Sprintf(". It expires at %s", expiry.Format(time.RFC3339))
+			return
+		}
+	}
+	step.Detail += fmt.Sprintf(". Its TTL could not be recorded so it will not expire: %v", err)
+}
+
+// findInstanceByName returns the instance with a name in a tenant, or nil if there is none
+func findInstanceByName(deps *Dependencies, name, tenantID string) (*aura.ListInstanceData, error) {
+	instances, err := deps.AClient.Instances.List()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list instances: %w", err)
+	}
+	for _, inst := range instances.Data {
+		if inst.Name == name && inst.TenantId == tenantID {
+			return &inst, nil
+		}
+	}
+	return nil, nil
+}
+
+// migrationWaitForOverwrite waits for the target to finish being overwritten. The target first has
+// to leave the running status, to show the overwrite has started, and then return to it. If it never
+// leaves running the overwrite step is set back to pending so that resuming requests it again
+func migrationWaitForOverwrite(ctx context.Context, deps *Dependencies, workflow *migrationWorkflow, timeout time.Duration) error {
+	if !workflow.OverwriteStarted {
+		if _, err := waitForInstanceToLeaveStatus(ctx, deps, workflow.TargetInstanceId, "running", timeout); err != nil {
+			if ctx.Err() == nil {
+				for i := range workflow.Steps {
+					if workflow.Steps[i].Name == migrationStepOverwrite {
+						workflow.Steps[i].Status = stepPending
+					}
+				}
+			}
+			return fmt.Errorf("the overwrite of the target did not start: %w. It will be requested again when the migration is resumed", err)
+		}
+		workflow.OverwriteStarted = true
+		if err := workflow.save(); err != nil {
+			return err
+		}
+	}
+
+	_, err := waitForInstanceStatus(ctx, deps, workflow.TargetInstanceId, "running", timeout, nil)
+	return err
+}
+
+// migrationVerifyTarget checks that the target is running and is compatible with the source, then
+// checks its data can be read by taking a snapshot of it and waiting for the snapshot to complete
+func migrationVerifyTarget(ctx context.Context, deps *Dependencies, workflow *migrationWorkflow, step *migrationStep, timeout time.Duration) error {
+	sourceInfo, err := deps.AClient.Instances.Get(workflow.SourceInstanceId)
+	if err != nil {
+		return fmt.Errorf("failed to retrieve source instance details: %w", err)
+	}
+
+	targetInfo, err := deps.AClient.Instances.Get(workflow.TargetInstanceId)
+	if err != nil {
+		return fmt.Errorf("failed to retrieve target instance details: %w", err)
+	}
+
+	if targetInfo.Data.Status != "running" {
+		return fmt.Errorf("target instance is '%s', not running", targetInfo.Data.Status)
+	}
+
+	problems := []string{}
+	for _, d := range compareInstanceDetails(newInstanceDetails(&sourceInfo.Data), newInstanceDetails(&targetInfo.Data)) {
+		if d.Matters {
+			problems = append(problems, fmt.Sprintf("%s: %s", d.Field, d.Reason))
+		}
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("target instance does not match the source: %s", strings.Join(problems, "; "))
+	}
+
+	// The snapshot is remembered so that resuming waits for it rather than taking another
+	if workflow.TargetSnapshotId == "" {
+		snapshot, err := deps.AClient.Snapshots.Create(workflow.TargetInstanceId)
+		if err != nil {
+			return fmt.Errorf("failed to create a snapshot of the target instance: %w", err)
+		}
+		workflow.TargetSnapshotId = snapshot.Data.SnapshotId
+		if err := workflow.save(); err != nil {
+			return err
+		}
+	}
+	if _, err := waitForSnapshot(ctx, deps, workflow.TargetInstanceId, workflow.TargetSnapshotId, timeout); err != nil {
+		return fmt.Errorf("failed to verify the data of the target instance: %w", err)
+	}
+
+	step.Detail = fmt.Sprintf("target instance is running, matches the source and snapshot %s of its data has completed", workflow.TargetSnapshotId)
+	return nil
+}
+
+// migrationDeleteSource deletes the source through the delete-instance outcome so that the usual
+// checks apply. It is skipped unless the migration was started with delete_source
+func migrationDeleteSource(ctx context.Context, deps *Dependencies, workflow *migrationWorkflow, step *migrationStep) error {
+	if !workflow.DeleteSource {
+		step.Status = stepSkipped
+		step.Detail = "source instance kept as delete_source was not set"
+		return nil
+	}
+
+	for _, s := range workflow.Steps {
+		if s.Name == migrationStepVerify && s.Status != stepCompleted {
+			return fmt.Errorf("the target instance has not been verified, so the source instance is kept")
+		}
+	}
+
+	// An earlier attempt may have deleted it before being interrupted
+	if _, err := deps.AClient.Instances.Get(workflow.SourceInstanceId); err != nil {
+		if isNotFound(err) {
+			step.Detail = "source instance no longer exists"
+			return nil
+		}
+		return fmt.Errorf("failed to retrieve source instance details: %w", err)
+	}
+
+	result, err := deps.OutComes.ExecuteOutcome(ctx, "delete-instance", map[string]interface{}{
+		"instance_id": workflow.SourceInstanceId,
+	}, deps)
+	if err != nil {
+		return err
+	}
+	if result.IsError {
+		return fmt.Errorf("failed to delete source instance: %s", toolResultText(result))
+	}
+
+	step.Detail = "source instance deleted"
+	return nil
+}
+
+// createParameters returns the create-instance parameters for the target instance
+func (w *migrationWorkflow) createParameters() map[string]interface{} {
+	parameters := map[string]interface{}{
+		"name":           w.TargetName,
+		"cloud_provider": w.TargetCloudProvider,
+		"region":         w.TargetRegion,
+		"memory":         w.TargetMemory,
+		"type":           w.TargetType,
+		"tenantId":       w.TargetTenantId,
+	}
+	if w.TargetTTL != "" {
+		parameters["ttl"] = w.TargetTTL
+	}
+	if w.TargetTTLAction != "" {
+		parameters["ttl_action"] = w.TargetTTLAction
+	}
+	return parameters
+}
+
+// save writes the workflow to its state file
+func (w *migrationWorkflow) save() error {
+	w.UpdatedAt = time.Now().UTC()
+	if err := state.Save(w.path, w); err != nil {
+		return fmt.Errorf("failed to save migration progress: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,406 @@
+package server
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	aura "github.com/LackOfMorals/aura-client"
+)
+
+// newMigrationDependencies returns dependencies holding a running source instance 'src' in
+// gcp us-central1 and tenant t1, along with any other instances given. Every tenant allows
+// instances in us-central1 and europe-west1
+func newMigrationDependencies(t *testing.T, others ...fakeInstance) (*Dependencies, *fakeInstances) {
+	t.Helper()
+	source := fakeInstance{GetInstanceData: aura.GetInstanceData{Id: "src", Name: "src", Status: "running", TenantId: "t1", CloudProvider: "gcp", Region: "us-central1", Memory: "8GB", Type: "professional-db"}}
+	instances := newFakeInstances(append([]fakeInstance{source}, others...)...)
+	deps := newTestDependencies(t, instances)
+
+	var configurations []aura.TenantInstanceConfiguration
+	for _, region := range []string{"us-central1", "europe-west1"} {
+		for _, memory := range []string{"8GB", "16GB"} {
+			configurations = append(configurations, aura.TenantInstanceConfiguration{CloudProvider: "gcp", Region: region, Memory: memory, Type: "professional-db", Version: "5"})
+		}
+	}
+	deps.AClient.Tenants = &fakeTenants{configurations: configurations}
+	return deps, instances
+}
+
+// targetInstance is an instance in europe-west1 that can be used as the target of a migration of src
+func targetInstance(id, name, tenantID string) fakeInstance {
+	return fakeInstance{GetInstanceData: aura.GetInstanceData{Id: id, Name: name, Status: "running", TenantId: tenantID, CloudProvider: "gcp", Region: "europe-west1", Memory: "8GB", Type: "professional-db"}}
+}
+
+func TestNewMigrationWorkflow(t *testing.T) {
+	tests := []struct {
+		name       string
+		parameters map[string]interface{}
+		wantName   string
+		wantErr    string
+	}{
+		{name: "defaults", parameters: map[string]interface{}{}, wantName: "src-europe-west1"},
+		{name: "given name", parameters: map[string]interface{}{"target_name": "orders-eu"}, wantName: "orders-eu"},
+		{name: "given name already used", parameters: map[string]interface{}{"target_name": "taken"}, wantName: "taken"},
+		{name: "no source", parameters: map[string]interface{}{"source_instance_id": ""}, wantErr: "'source_instance_id' parameter is required"},
+		{name: "no region", parameters: map[string]interface{}{"target_region": ""}, wantErr: "'target_region' parameter is required"},
+		{name: "source not running", parameters: map[string]interface{}{"source_instance_id": "paused"}, wantErr: "must be running"},
+		{name: "same region", parameters: map[string]interface{}{"target_region": "us-central1"}, wantErr: "Nothing to migrate"},
+		{name: "smaller target", parameters: map[string]interface{}{"source_instance_id": "big", "target_memory": "8GB"}, wantErr: "smaller than the source memory"},
+		{name: "region not allowed", parameters: map[string]interface{}{"target_region": "asia-east1"}, wantErr: "asia-east1"},
+		{name: "invalid ttl", parameters: map[string]interface{}{"ttl": "soon"}, wantErr: "not a valid TTL"},
+	}
+
+	deps, _ := newMigrationDependencies(t,
+		fakeInstance{GetInstanceData: aura.GetInstanceData{Id: "paused", Name: "paused", Status: "paused", TenantId: "t1", CloudProvider: "gcp", Region: "us-central1", Memory: "8GB", Type: "professional-db"}},
+		fakeInstance{GetInstanceData: aura.GetInstanceData{Id: "big", Name: "big", Status: "running", TenantId: "t1", CloudProvider: "gcp", Region: "us-central1", Memory: "16GB", Type: "professional-db"}},
+		targetInstance("x1", "taken", "t1"),
+	)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parameters := map[string]interface{}{"source_instance_id": "src", "target_region": "europe-west1"}
+			for key, value := range tt.parameters {
+				parameters[key] = value
+			}
+
+			workflow, err := newMigrationWorkflow(context.Background(), deps, parameters)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("newMigrationWorkflow() error = %v, want one containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("newMigrationWorkflow() error = %v", err)
+			}
+			// The target name has the same suffix as the workflow ID
+			suffix := workflow.Id[strings.LastIndex(workflow.Id, "-"):]
+			if workflow.TargetName != tt.wantName+suffix || len(suffix) != 7 || !validWorkflowID.MatchString(workflow.Id) || len(workflow.Steps) != len(migrationSteps) {
+				t.Fatalf("workflow = %+v, want target '%s' with a unique suffix and a pending step for each of %v", workflow, tt.wantName, migrationSteps)
+			}
+		})
+	}
+}
+
+func TestMigrationCreateTarget(t *testing.T) {
+	const targetName = "src-europe-west1-3fa91c"
+
+	tests := []struct {
+		name          string
+		existing      *fakeInstance // An instance with the given name and tenant
+		targetID      string        // Target recorded by an earlier attempt
+		createSent    bool          // An earlier attempt sent the request to create the target
+		wantTarget    string
+		wantCreated   bool
+		wantTTLRecord bool
+		wantErr       string
+	}{
+		{name: "new target", wantTarget: "new1", wantCreated: true, wantTTLRecord: true},
+		{name: "created by an earlier attempt", targetID: "x1", wantTarget: "x1"},
+		{name: "adopted after an interrupted create", existing: &fakeInstance{GetInstanceData: aura.GetInstanceData{Name: targetName, TenantId: "t1"}}, createSent: true, wantTarget: "found", wantTTLRecord: true},
+		{name: "create not sent", existing: &fakeInstance{GetInstanceData: aura.GetInstanceData{Name: targetName, TenantId: "t1"}}, wantErr: "has not asked for its target to be created"},
+		{name: "create sent but not made", createSent: true, wantTarget: "new1", wantCreated: true, wantTTLRecord: true},
+		{name: "same name in another tenant", existing: &fakeInstance{GetInstanceData: aura.GetInstanceData{Name: targetName, TenantId: "t2"}}, createSent: true, wantTarget: "new1", wantCreated: true, wantTTLRecord: true},
+		{name: "name without the suffix", existing: &fakeInstance{GetInstanceData: aura.GetInstanceData{Name: "src-europe-west1", TenantId: "t1"}}, createSent: true, wantTarget: "new1", wantCreated: true, wantTTLRecord: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var others []fakeInstance
+			if tt.existing != nil {
+				others = append(others, targetInstance("found", tt.existing.Name, tt.existing.TenantId))
+			}
+			deps, instances := newMigrationDependencies(t, others...)
+			workflow := &migrationWorkflow{
+				Id:                  "mig-test",
+				SourceInstanceId:    "src",
+				TargetInstanceId:    tt.targetID,
+				TargetName:          targetName,
+				TargetTenantId:      "t1",
+				TargetCloudProvider: "gcp",
+				TargetRegion:        "europe-west1",
+				TargetMemory:        "8GB",
+				TargetType:          "professional-db",
+				TargetTTL:           "4h",
+				TargetCreateSent:    tt.createSent,
+				path:                filepath.Join(deps.Config.StateDir, "migrations", "mig-test.json"),
+			}
+			step := &migrationStep{Name: migrationStepCreateTarget, Status: stepRunning}
+
+			credentials, err := migrationCreateTarget(context.Background(), deps, workflow, step)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("migrationCreateTarget() error = %v, want one containing %q", err, tt.wantErr)
+				}
+				if workflow.TargetInstanceId != "" || len(instances.Changes()) != 0 {
+					t.Fatalf("target = %q, changes = %v, want neither", workflow.TargetInstanceId, instances.Changes())
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("migrationCreateTarget() error = %v", err)
+			}
+
+			if workflow.TargetInstanceId != tt.wantTarget {
+				t.Fatalf("target = %q, want %q", workflow.TargetInstanceId, tt.wantTarget)
+			}
+			created := len(instances.Changes()) == 1 && instances.Changes()[0] == "POST "+targetName
+			if created != tt.wantCreated || (!tt.wantCreated && len(instances.Changes()) != 0) {
+				t.Fatalf("changes = %v, want an instance created %t", instances.Changes(), tt.wantCreated)
+			}
+			if (credentials[1] != "") != tt.wantCreated {
+				t.Fatalf("credentials = %v, want them only for a new instance", credentials)
+			}
+			_, recorded, err := deps.TTLs.Get(tt.wantTarget)
+			if err != nil || recorded != tt.wantTTLRecord {
+				t.Fatalf("TTL recorded = %t, %v, want %t", recorded, err, tt.wantTTLRecord)
+			}
+
+			// That the request was sent is saved before it is sent
+			if tt.wantCreated {
+				saved, err := loadMigrationWorkflow(deps, "mig-test")
+				if err != nil || !saved.TargetCreateSent {
+					t.Fatalf("saved workflow = %+v, %v, want target_create_sent", saved, err)
+				}
+			}
+		})
+	}
+}
+
+// setStepStatus sets the status of the named steps of workflow
+func setStepStatus(workflow *migrationWorkflow, status string, names ...string) {
+	for i := range workflow.Steps {
+		for _, name := range names {
+			if workflow.Steps[i].Name == name {
+				workflow.Steps[i].Status = status
+			}
+		}
+	}
+}
+
+// stepStatus returns the status of the named step of workflow
+func stepStatus(workflow *migrationWorkflow, name string) string {
+	for _, step := range workflow.Steps {
+		if step.Name == name {
+			return step.Status
+		}
+	}
+	return ""
+}
+
+func TestRunMigrationWorkflow(t *testing.T) {
+	throughOverwrite := []string{migrationStepCreateTarget, migrationStepWaitTarget, migrationStepOverwrite}
+
+	tests := []struct {
+		name            string
+		deleteSource    bool
+		setup           func(workflow *migrationWorkflow, instances *fakeInstances, snapshots *fakeSnapshots)
+		wantStatus      string
+		wantChanges     []string
+		wantTarget      string
+		wantSteps       map[string]string
+		wantCredentials bool
+		wantSnapshots   int // Snapshots taken of the target
+	}{
+		{
+			name:            "new migration keeping the source",
+			wantStatus:      stepCompleted,
+			wantChanges:     []string{"POST <target>", "OVERWRITE new1 FROM src"},
+			wantTarget:      "new1",
+			wantSteps:       map[string]string{migrationStepVerify: stepCompleted, migrationStepDeleteSource: stepSkipped},
+			wantCredentials: true,
+			wantSnapshots:   1,
+		},
+		{
+			name:            "new migration deleting the source",
+			deleteSource:    true,
+			wantStatus:      stepCompleted,
+			wantChanges:     []string{"POST <target>", "OVERWRITE new1 FROM src", "DELETE src"},
+			wantTarget:      "new1",
+			wantSteps:       map[string]string{migrationStepDeleteSource: stepCompleted},
+			wantCredentials: true,
+			wantSnapshots:   1,
+		},
+		{
+			name: "resumed after creating the target was interrupted",
+			setup: func(workflow *migrationWorkflow, instances *fakeInstances, snapshots *fakeSnapshots) {
+				workflow.Steps[0].Status = stepRunning
+				workflow.TargetCreateSent = true
+				found := targetInstance("found", workflow.TargetName, "t1")
+				instances.instances["found"] = &found
+			},
+			wantStatus:    stepCompleted,
+			wantChanges:   []string{"OVERWRITE found FROM src"},
+			wantTarget:    "found",
+			wantSnapshots: 1,
+		},
+		{
+			name: "target name used by an instance this migration did not create",
+			setup: func(workflow *migrationWorkflow, instances *fakeInstances, snapshots *fakeSnapshots) {
+				workflow.Steps[0].Status = stepRunning
+				other := targetInstance("other", workflow.TargetName, "t1")
+				instances.instances["other"] = &other
+			},
+			wantStatus: stepFailed,
+			wantSteps:  map[string]string{migrationStepCreateTarget: stepFailed, migrationStepOverwrite: stepPending},
+		},
+		{
+			name: "resumed while waiting for a started overwrite",
+			setup: func(workflow *migrationWorkflow, instances *fakeInstances, snapshots *fakeSnapshots) {
+				setStepStatus(workflow, stepCompleted, throughOverwrite...)
+				setStepStatus(workflow, stepRunning, migrationStepWaitOverwrite)
+				workflow.TargetInstanceId = "tgt"
+				workflow.OverwriteStarted = true
+				tgt := targetInstance("tgt", "src-europe-west1", "t1")
+				tgt.Status = "overwriting"
+				tgt.Statuses = []string{"running"} // Waiting for it to leave running would never finish
+				instances.instances["tgt"] = &tgt
+			},
+			wantStatus:    stepCompleted,
+			wantTarget:    "tgt",
+			wantSteps:     map[string]string{migrationStepWaitOverwrite: stepCompleted},
+			wantSnapshots: 1,
+		},
+		{
+			name:         "resumed while waiting for the target snapshot",
+			deleteSource: true,
+			setup: func(workflow *migrationWorkflow, instances *fakeInstances, snapshots *fakeSnapshots) {
+				setStepStatus(workflow, stepCompleted, append(throughOverwrite, migrationStepWaitOverwrite)...)
+				setStepStatus(workflow, stepRunning, migrationStepVerify)
+				workflow.TargetInstanceId = "tgt"
+				workflow.OverwriteStarted = true
+				tgt := targetInstance("tgt", "src-europe-west1", "t1")
+				instances.instances["tgt"] = &tgt
+				if _, err := snapshots.Create("tgt"); err != nil {
+					t.Fatal(err)
+				}
+				workflow.TargetSnapshotId = "snap1"
+			},
+			wantStatus:  stepCompleted,
+			wantChanges: []string{"DELETE src"},
+			wantTarget:  "tgt",
+		},
+		{
+			name:         "source kept when the target was not verified",
+			deleteSource: true,
+			setup: func(workflow *migrationWorkflow, instances *fakeInstances, snapshots *fakeSnapshots) {
+				setStepStatus(workflow, stepCompleted, append(throughOverwrite, migrationStepWaitOverwrite)...)
+				setStepStatus(workflow, stepSkipped, migrationStepVerify)
+				workflow.TargetInstanceId = "tgt"
+			},
+			wantStatus: stepFailed,
+			wantTarget: "tgt",
+			wantSteps:  map[string]string{migrationStepDeleteSource: stepFailed},
+		},
+		{
+			name:         "source deleted by an interrupted attempt",
+			deleteSource: true,
+			setup: func(workflow *migrationWorkflow, instances *fakeInstances, snapshots *fakeSnapshots) {
+				setStepStatus(workflow, stepCompleted, append(throughOverwrite, migrationStepWaitOverwrite, migrationStepVerify)...)
+				setStepStatus(workflow, stepRunning, migrationStepDeleteSource)
+				workflow.TargetInstanceId = "tgt"
+				delete(instances.instances, "src")
+			},
+			wantStatus: stepCompleted,
+			wantTarget: "tgt",
+			wantSteps:  map[string]string{migrationStepDeleteSource: stepCompleted},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			deps, instances := newMigrationDependencies(t)
+			snapshots := deps.AClient.Snapshots.(*fakeSnapshots)
+			workflow, err := newMigrationWorkflow(context.Background(), deps, map[string]interface{}{
+				"source_instance_id": "src",
+				"target_region":      "europe-west1",
+				"delete_source":      tt.deleteSource,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.setup != nil {
+				tt.setup(workflow, instances, snapshots)
+			}
+
+			// Run the workflow as saved, as a resume does
+			if err := workflow.save(); err != nil {
+				t.Fatal(err)
+			}
+			workflow, err = loadMigrationWorkflow(deps, workflow.Id)
+			if err != nil {
+				t.Fatal(err)
+			}
+			snapshotsBefore := snapshots.created
+			credentials := runMigrationWorkflow(withConfirmation(context.Background()), deps, workflow)
+
+			if workflow.Status != tt.wantStatus {
+				t.Fatalf("status = %s, want %s. Steps: %+v", workflow.Status, tt.wantStatus, workflow.Steps)
+			}
+			wantChanges := strings.ReplaceAll(strings.Join(tt.wantChanges, ","), "<target>", workflow.TargetName)
+			if strings.Join(instances.Changes(), ",") != wantChanges {
+				t.Fatalf("changes = %v, want %v", instances.Changes(), wantChanges)
+			}
+			if workflow.TargetInstanceId != tt.wantTarget {
+				t.Fatalf("target = %q, want %q", workflow.TargetInstanceId, tt.wantTarget)
+			}
+			for name, want := range tt.wantSteps {
+				if got := stepStatus(workflow, name); got != want {
+					t.Fatalf("step %s = %s, want %s", name, got, want)
+				}
+			}
+			if (credentials[1] != "") != tt.wantCredentials {
+				t.Fatalf("credentials = %v, want them %t", credentials, tt.wantCredentials)
+			}
+			if got := snapshots.created - snapshotsBefore; got != tt.wantSnapshots {
+				t.Fatalf("%d snapshots taken, want %d", got, tt.wantSnapshots)
+			}
+
+			// The saved workflow matches the one that ran
+			saved, err := loadMigrationWorkflow(deps, workflow.Id)
+			if err != nil || saved.Status != workflow.Status || saved.TargetInstanceId != workflow.TargetInstanceId {
+				t.Fatalf("saved workflow = %+v, %v, want it to match", saved, err)
+			}
+		})
+	}
+}
+
+func TestMigrationOverwriteRequestedAgain(t *testing.T) {
+	deps, instances := newMigrationDependencies(t)
+	workflow, err := newMigrationWorkflow(context.Background(), deps, map[string]interface{}{
+		"source_instance_id": "src",
+		"target_region":      "europe-west1",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	setStepStatus(workflow, stepCompleted, migrationStepCreateTarget, migrationStepWaitTarget, migrationStepOverwrite)
+	workflow.TargetInstanceId = "tgt"
+	workflow.WaitTimeoutMinutes = 0.001
+	tgt := targetInstance("tgt", "src-europe-west1", "t1")
+	instances.instances["tgt"] = &tgt
+	ctx := withConfirmation(context.Background())
+
+	// The target never leaves running, so the overwrite was not seen to start
+	runMigrationWorkflow(ctx, deps, workflow)
+	if workflow.Status != stepFailed || stepStatus(workflow, migrationStepWaitOverwrite) != stepFailed {
+		t.Fatalf("status = %s, steps = %+v, want the wait for the overwrite to fail", workflow.Status, workflow.Steps)
+	}
+	if stepStatus(workflow, migrationStepOverwrite) != stepPending {
+		t.Fatalf("overwrite step = %s, want it pending so that it is requested again", stepStatus(workflow, migrationStepOverwrite))
+	}
+
+	// Resuming requests the overwrite again and finishes
+	workflow, err = loadMigrationWorkflow(deps, workflow.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	runMigrationWorkflow(ctx, deps, workflow)
+	if workflow.Status != stepCompleted {
+		t.Fatalf("status = %s, steps = %+v, want the resumed migration to complete", workflow.Status, workflow.Steps)
+	}
+	if changes := instances.Changes(); strings.Join(changes, ",") != "OVERWRITE tgt FROM src" {
+		t.Fatalf("changes = %v, want one overwrite", changes)
+	}
+}
@@ -12,12 +12,15 @@ import (
 // OutcomeRegistry manages all available Outcomes
 type OutcomeRegistry struct {
 	Outcomes map[string]*Outcome
+
+	confirmations *confirmationStore
 }
 
 // NewOutcomeRegistry creates a new Outcome registry with all available Outcomes
 func NewOutcomeRegistry() *OutcomeRegistry {
 	registry := &OutcomeRegistry{
-		Outcomes: make(map[string]*Outcome),
+		Outcomes:      make(map[string]*Outcome),
+		confirmations: newConfirmationStore(),
 	}
 
 	// Register all available Outcomes
@@ -26,6 +29,51 @@ func NewOutcomeRegistry() *OutcomeRegistry {
 	registry.registerGetInstanceDetailsOutcome()
 	registry.registerCreateInstanceOutcome()
 	registry.registerDeleteInstanceOutcome()
+	registry.registerPauseInstanceOutcome()
+	registry.registerResumeInstanceOutcome()
+	registry.registerUpdateInstanceOutcome()
+	registry.registerOverwriteInstanceOutcome()
+	registry.registerCloneInstanceOutcome()
+	registry.registerWaitForInstanceStatusOutcome()
+	registry.registerListSnapshotsOutcome()
+	registry.registerGetSnapshotOutcome()
+	registry.registerCreateSnapshotOutcome()
+	registry.registerListTenantsOutcome()
+	registry.registerGetTenantDetailsOutcome()
+	registry.registerListEncryptionKeysOutcome()
+	registry.registerGetEncryptionKeyOutcome()
+	registry.registerCreateEncryptionKeyOutcome()
+	registry.registerDeleteEncryptionKeyOutcome()
+	registry.registerListGDSSessionsOutcome()
+	registry.registerEstimateGDSSessionOutcome()
+	registry.registerCreateGDSSessionOutcome()
+	registry.registerDeleteGDSSessionOutcome()
+	registry.registerListProfilesOutcome()
+	registry.registerFleetInventoryOutcome()
+	registry.registerCompareInstancesOutcome()
+	registry.registerPlanDesiredStateOutcome()
+	registry.registerApplyDesiredStateOutcome()
+	registry.registerRecordBaselineOutcome()
+	registry.registerDetectDriftOutcome()
+	registry.registerMigrateInstanceOutcome()
+	registry.registerListMigrationsOutcome()
+	registry.registerListExpiringInstancesOutcome()
+	registry.registerExtendTTLOutcome()
+	registry.registerSetInstanceLabelsOutcome()
+	registry.registerGetInstanceLabelsOutcome()
+	registry.registerFindInstancesByLabelOutcome()
+	registry.registerProtectInstanceOutcome()
+	registry.registerUnprotectInstanceOutcome()
+
+	// Every outcome that makes changes can be run as a dry run, and destructive ones need confirming
+	for _, outcome := range registry.Outcomes {
+		if makesChanges(outcome) {
+			outcome.Parameters = append(outcome.Parameters, dryRunParameter)
+		}
+		if isDestructive(outcome) {
+			outcome.Parameters = append(outcome.Parameters, confirmationTokenParameter)
+		}
+	}
 
 	return registry
 }
@@ -61,6 +109,38 @@ func (r *OutcomeRegistry) ExecuteOutcome(ctx context.Context, id string, paramet
 		return mcp.NewToolResultError(err.Error()), nil
 	}
 
+	// Execute the handler associated with this Outcome
+	if Outcome.Handler == nil {
+		return mcp.NewToolResultError(fmt.Sprintf("no handler registered for Outcome: %s", id)), nil
+	}
+
+	// Check the caller has a role that allows this Outcome
+	if err := checkAccess(ctx, Outcome, parameters, deps); err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Cannot execute '%s' Outcome: %v", id, err)), nil
+	}
+
+	// A dry run is asked for with the dry_run parameter, or for every call with DRY_RUN. It is
+	// handled here so handlers never see the parameter
+	dryRun, _ := parameters["dry_run"].(bool)
+	parameters = withoutParameter(parameters, "dry_run")
+	if deps.Config != nil && deps.Config.DryRun {
+		dryRun = true
+	}
+
+	token, _ := parameters["confirmation_token"].(string)
+	parameters = withoutParameter(parameters, "confirmation_token")
+
+	// Check the parameters against the guardrail rules. Dry runs are checked too so they show
+	// whether the call would be allowed
+	if err := checkGuardrails(ctx, Outcome, parameters, deps); err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Cannot execute '%s' Outcome: %v", id, err)), nil
+	}
+
+	// A write operation that is part of a dry run changes nothing so is allowed in read-only mode
+	if makesChanges(Outcome) && (dryRun || dryRunFromContext(ctx) != nil) {
+		return r.executeDryRun(ctx, Outcome, parameters, deps, false)
+	}
+
 	// Check if this is a write operation and we're in read-only mode
 	if !Outcome.ReadOnly && deps.Config != nil && deps.Config.ReadOnly {
 		return mcp.NewToolResultError(fmt.Sprintf(
@@ -69,9 +149,16 @@ func (r *OutcomeRegistry) ExecuteOutcome(ctx context.Context, id string, paramet
 		)), nil
 	}
 
-	// Execute the handler associated with this Outcome
-	if Outcome.Handler == nil {
-		return mcp.NewToolResultError(fmt.Sprintf("no handler registered for Outcome: %s", id)), nil
+	// A destructive outcome is first run as a dry run that issues a confirmation token. Only a
+	// second call with the same parameters and the token goes ahead
+	if isDestructive(Outcome) && !isConfirmed(ctx) {
+		if token == "" {
+			return r.executeDryRun(ctx, Outcome, parameters, deps, true)
+		}
+		if err := r.confirmations.Redeem(token, id, parameters); err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("Cannot execute '%s' Outcome: %v. Call again without 'confirmation_token' to get a new one", id, err)), nil
+		}
+		ctx = withConfirmation(ctx)
 	}
 
 	return Outcome.Handler(ctx, parameters, deps)
@@ -0,0 +1,102 @@
+// =============================================================================
+// These are all of the instance profile related outcomes
+//
+// Profiles are named instance configurations loaded from the file given by
+// PROFILES_FILE. They let create-instance be called with a profile name rather
+// than every parameter
+// =============================================================================
+
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/LackOfMorals/mcp4AuraAPI/internal/config"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// registerListProfilesOutcome registers the list-profiles outcome
+func (r *OutcomeRegistry) registerListProfilesOutcome() {
+	r.Outcomes["list-profiles"] = &Outcome{
+		ID:          "list-profiles",
+		Name:        "List Profiles",
+		Description: "Retrieve the named instance profiles that can be used with create-instance. Each profile holds the tenant, cloud provider, region, memory, type and version for a new instance so only a name and the profile need to be supplied.",
+		Type:        OutcomesTypeList,
+		ReadOnly:    true,
+		Parameters:  []OutcomeParameter{}, // No parameters needed for listing
+		Metadata: map[string]interface{}{
+			"category": "profiles",
+		},
+		Handler: executeListProfiles,
+	}
+}
+
+// executeListProfiles implements the list-profiles outcome
+func executeListProfiles(ctx context.Context, parameters map[string]interface{}, deps *Dependencies) (*mcp.CallToolResult, error) {
+	if deps.Config == nil || len(deps.Config.Profiles) == 0 {
+		return mcp.NewToolResultText("No instance profiles are configured. Set PROFILES_FILE to a JSON file of profiles to use them."), nil
+	}
+
+	type profileSummary struct {
+		Name string `json:"name"`
+		config.InstanceProfile
+	}
+
+	records := make([]profileSummary, 0, len(deps.Config.Profiles))
+	for name, profile := range deps.Config.Profiles {
+		records = append(records, profileSummary{Name: name, InstanceProfile: profile})
+	}
+
+	sort.Slice(records, func(i, j int) bool {
+		return records[i].Name < records[j].Name
+	})
+
+	jsonData, err := json.MarshalIndent(records, "", "  ")
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to serialize results: %v", err)), nil
+	}
+
+	return mcp.NewToolResultText(string(jsonData)), nil
+}
+
+// applyProfile returns a copy of the create-instance parameters with any values missing from them taken
+// from the profile named by the 'profile' parameter. Parameters that were supplied override the profile.
+// The parameters are returned unchanged if no profile was named
+func applyProfile(deps *Dependencies, parameters map[string]interface{}) (map[string]interface{}, error) {
+	profileName, _ := parameters["profile"].(string)
+	if profileName == "" {
+		return parameters, nil
+	}
+
+	var profile config.InstanceProfile
+	ok := false
+	if deps.Config != nil {
+		profile, ok = deps.Config.Profiles[profileName]
+	}
+	if !ok {
+		return nil, fmt.Errorf("Profile '%s' not found. Use list-profiles to see the available profiles", profileName)
+	}
+
+	merged := map[string]interface{}{
+		"tenantId":       profile.TenantId,
+		"cloud_provider": profile.CloudProvider,
+		"region":         profile.Region,
+		"memory":         profile.Memory,
+		"type":           profile.Type,
+	}
+	if profile.Version != "" {
+		merged["version"] = profile.Version
+	}
+
+	for key, value := range parameters {
+		if v, isString := value.(string); isString && v == "" {
+			continue
+		}
+		merged[key] = value
+	}
+
+	return merged, nil
+}
@@ -0,0 +1,50 @@
+// progress.go holds the helpers that let an outcome send MCP progress notifications
+// back to the client that called execute-outcome
+
+package server
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// progressTokenKey is the context key for the progress token of the current tool call
+type progressTokenKey struct{}
+
+// withProgressToken returns a copy of ctx that carries the progress token from the tool call, if any
+func withProgressToken(ctx context.Context, request mcp.CallToolRequest) context.Context {
+	if request.Params.Meta == nil || request.Params.Meta.ProgressToken == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, progressTokenKey{}, request.Params.Meta.ProgressToken)
+}
+
+// sendProgress sends a progress notification to the client. It does nothing if the client
+// did not ask for progress by supplying a progress token. total is ignored if zero
+func sendProgress(ctx context.Context, progress, total float64, message string) {
+	token := ctx.Value(progressTokenKey{})
+	if token == nil {
+		return
+	}
+
+	mcpServer := server.ServerFromContext(ctx)
+	if mcpServer == nil {
+		return
+	}
+
+	params := map[string]any{
+		"progressToken": token,
+		"progress":      progress,
+		"message":       message,
+	}
+	if total > 0 {
+		params["total"] = total
+	}
+
+	if err := mcpServer.SendNotificationToClient(ctx, "notifications/progress", params); err != nil {
+		slog.Debug("Failed to send progress notification", "error", err)
+	}
+}
@@ -0,0 +1,191 @@
+// protection.go holds the list of instances that cannot be deleted or overwritten. It is
+// made of the entries in PROTECTION_FILE, which can only be changed by editing the file, and
+// those added with protect-instance, which are kept in the state directory
+
+package server
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"path"
+	"path/filepath"
+	"slices"
+	"sync"
+
+	"github.com/LackOfMorals/mcp4AuraAPI/internal/config"
+	"github.com/LackOfMorals/mcp4AuraAPI/internal/state"
+)
+
+// ProtectionStore keeps the entries added with protect-instance in a local file
+type ProtectionStore struct {
+	mu         sync.Mutex
+	path       string
+	protection config.DeletionProtection
+	loaded     bool
+}
+
+// NewProtectionStore creates a store kept in protection.json in the state directory
+func NewProtectionStore(stateDir string) *ProtectionStore {
+	return &ProtectionStore{
+		path: filepath.Join(stateDir, "protection.json"),
+	}
+}
+
+// load reads the file the first time the store is used. Must be called with mu held
+func (p *ProtectionStore) load() error {
+	if p.loaded {
+		return nil
+	}
+	if err := state.Load(p.path, &p.protection); err != nil {
+		return err
+	}
+	p.loaded = true
+	return nil
+}
+
+// Get returns the entries added with protect-instance
+func (p *ProtectionStore) Get() (config.DeletionProtection, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if err := p.load(); err != nil {
+		return config.DeletionProtection{}, err
+	}
+	return config.DeletionProtection{
+		InstanceIds:  slices.Clone(p.protection.InstanceIds),
+		NamePatterns: slices.Clone(p.protection.NamePatterns),
+		Labels:       slices.Clone(p.protection.Labels),
+	}, nil
+}
+
+// Update changes the entries added with protect-instance using fn
+func (p *ProtectionStore) Update(fn func(*config.DeletionProtection)) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if err := p.load(); err != nil {
+		return err
+	}
+	fn(&p.protection)
+	return state.Save(p.path, p.protection)
+}
+
+// matchProtection returns a description of the first entry of protection that matches the
+// instance, or an empty string if none do
+func matchProtection(protection config.DeletionProtection, instanceID, name string, labels map[string]string) string {
+	if slices.Contains(protection.InstanceIds, instanceID) {
+		return fmt.Sprintf("instance ID '%s'", instanceID)
+	}
+	for _, pattern := range protection.NamePatterns {
+		if matched, _ := path.Match(pattern, name); matched {
+			return fmt.Sprintf("name pattern '%s'", pattern)
+		}
+	}
+	for _, label := range protection.Labels {
+		selector, err := parseLabelSelector(label)
+		if err != nil {
+			continue
+		}
+		if matchesLabelSelector(labels, selector) {
+			return fmt.Sprintf("label '%s'", label)
+		}
+	}
+	return ""
+}
+
+// checkDeletionProtection returns an error if the instance is protected from deletion and
+// overwrite. It must be called before any API call that would delete or overwrite an instance
+func checkDeletionProtection(deps *Dependencies, instanceID, name string) error {
+	labels, err := deps.Labels.Get(instanceID)
+	if err != nil {
+		return fmt.Errorf("failed to load instance labels to check deletion protection: %w", err)
+	}
+
+	if rule := matchProtection(deps.Config.Protection, instanceID, name, labels.Labels); rule != "" {
+		return fmt.Errorf("instance '%s' (ID: %s) is protected from deletion and overwrite by %s in the protection file. Remove it from %s to allow this", name, instanceID, rule, deps.Config.ProtectionFile)
+	}
+
+	protection, err := deps.Protection.Get()
+	if err != nil {
+		return fmt.Errorf("failed to load deletion protection: %w", err)
+	}
+	if rule := matchProtection(protection, instanceID, name, labels.Labels); rule != "" {
+		return fmt.Errorf("instance '%s' (ID: %s) is protected from deletion and overwrite by %s. It must be removed with unprotect-instance to allow this", name, instanceID, rule)
+	}
+
+	return nil
+}
+
+// checkLabelProtection returns an error if changing the labels of an instance from before to after
+// would remove or change a label that a label entry of the protection list matches on. Entries added
+// with protect-instance can be got round with the unprotect secret; those in the protection file cannot
+func checkLabelProtection(deps *Dependencies, instanceID, name string, before, after map[string]string, secret string) error {
+	if rule := matchChangedLabels(deps.Config.Protection.Labels, before, after); rule != "" {
+		return fmt.Errorf("instance '%s' (ID: %s) is protected by label '%s' in the protection file, so that label cannot be removed or changed. Remove it from %s to allow this", name, instanceID, rule, deps.Config.ProtectionFile)
+	}
+
+	protection, err := deps.Protection.Get()
+	if err != nil {
+		return fmt.Errorf("failed to load deletion protection: %w", err)
+	}
+	rule := matchChangedLabels(protection.Labels, before, after)
+	if rule == "" {
+		return nil
+	}
+	if deps.Config.UnprotectSecret == "" || subtle.ConstantTimeCompare([]byte(secret), []byte(deps.Config.UnprotectSecret)) != 1 {
+		return fmt.Errorf("instance '%s' (ID: %s) is protected by label '%s', so that label can only be removed or changed with the unprotect secret. Ask the user for it", name, instanceID, rule)
+	}
+	return nil
+}
+
+// checkNameProtection returns an error if renaming an instance from before to after would stop a
+// name pattern entry of the protection list matching it. As with labels, entries added with
+// protect-instance can be got round with the unprotect secret; those in the protection file cannot
+func checkNameProtection(deps *Dependencies, instanceID, before, after, secret string) error {
+	if pattern := matchRenamedPattern(deps.Config.Protection.NamePatterns, before, after); pattern != "" {
+		return fmt.Errorf("instance '%s' (ID: %s) is protected by name pattern '%s' in the protection file, so it cannot be renamed to '%s'. Remove it from %s to allow this", before, instanceID, pattern, after, deps.Config.ProtectionFile)
+	}
+
+	protection, err := deps.Protection.Get()
+	if err != nil {
+		return fmt.Errorf("failed to load deletion protection: %w", err)
+	}
+	pattern := matchRenamedPattern(protection.NamePatterns, before, after)
+	if pattern == "" {
+		return nil
+	}
+	if deps.Config.UnprotectSecret == "" || subtle.ConstantTimeCompare([]byte(secret), []byte(deps.Config.UnprotectSecret)) != 1 {
+		return fmt.Errorf("instance '%s' (ID: %s) is protected by name pattern '%s', so it can only be renamed to '%s' with the unprotect secret. Ask the user for it", before, instanceID, pattern, after)
+	}
+	return nil
+}
+
+// matchRenamedPattern returns the first of the name patterns that matches before but not after,
+// or an empty string if there is none
+func matchRenamedPattern(patterns []string, before, after string) string {
+	for _, pattern := range patterns {
+		matchedBefore, _ := path.Match(pattern, before)
+		matchedAfter, _ := path.Match(pattern, after)
+		if matchedBefore && !matchedAfter {
+			return pattern
+		}
+	}
+	return ""
+}
+
+// matchChangedLabels returns the first of the protection label entries that matches before and
+// names a label that is different in after, or an empty string if there is none
+func matchChangedLabels(entries []string, before, after map[string]string) string {
+	for _, label := range entries {
+		selector, err := parseLabelSelector(label)
+		if err != nil || !matchesLabelSelector(before, selector) {
+			continue
+		}
+		for key := range selector {
+			if value, ok := after[key]; !ok || value != before[key] {
+				return label
+			}
+		}
+	}
+	return ""
+}
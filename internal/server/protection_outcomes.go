@@ -0,0 +1,230 @@
+// =============================================================================
+// These are the outcomes that protect instances from deletion and overwrite
+//
+// Anyone can add protection. Removing it needs UNPROTECT_SECRET, which is given
+// to the user out of band, so that an agent cannot remove it on its own.
+// Entries in PROTECTION_FILE can only be removed by editing the file
+// =============================================================================
+
+package server
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"path"
+	"slices"
+
+	"github.com/LackOfMorals/mcp4AuraAPI/internal/config"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// protectionSelectorParameters are the ways to choose what protect-instance and unprotect-instance apply to
+var protectionSelectorParameters = []OutcomeParameter{
+	{
+		Name:        "instance_id",
+		Type:        "string",
+		Description: "The ID of an instance. Give exactly one of 'instance_id', 'name_pattern' or 'label'",
+		Required:    false,
+	},
+	{
+		Name:        "name_pattern",
+		Type:        "string",
+		Description: "A glob pattern matching instance names, such as 'prod-*'",
+		Required:    false,
+	},
+	{
+		Name:        "label",
+		Type:        "string",
+		Description: "A label such as 'env=prod', or a label name on its own to match any value. See set-instance-labels",
+		Required:    false,
+	},
+}
+
+// registerProtectInstanceOutcome registers the protect-instance outcome
+func (r *OutcomeRegistry) registerProtectInstanceOutcome() {
+	r.Outcomes["protect-instance"] = &Outcome{
+		ID:          "protect-instance",
+		Name:        "Protect Instance",
+		Description: "Protect an instance, or every instance whose name or label matches, from deletion and overwrite. Protected instances cannot be deleted or overwritten by any outcome, even with a confirmation token. Returns the full protection list.",
+		Type:        OutcomesTypeUpdate,
+		ReadOnly:    false,
+		Parameters:  protectionSelectorParameters,
+		Metadata: map[string]interface{}{
+			"category": "protection",
+		},
+		Handler: executeProtectInstance,
+	}
+}
+
+// registerUnprotectInstanceOutcome registers the unprotect-instance outcome
+func (r *OutcomeRegistry) registerUnprotectInstanceOutcome() {
+	r.Outcomes["unprotect-instance"] = &Outcome{
+		ID:          "unprotect-instance",
+		Name:        "Unprotect Instance",
+		Description: "Remove protection added with protect-instance. This needs the unprotect secret, which only the user has; ask them for it rather than guessing. Protection from the server's protection file can only be removed by editing that file.",
+		Type:        OutcomesTypeUpdate,
+		ReadOnly:    false,
+		Parameters: append(slices.Clone(protectionSelectorParameters), OutcomeParameter{
+			Name:        "secret",
+			Type:        "string",
+			Description: "The unprotect secret, supplied by the user",
+			Required:    true,
+		}),
+		Metadata: map[string]interface{}{
+			"category": "protection",
+			"warning":  "Removing protection allows the instance to be deleted or overwritten.",
+		},
+		Handler: executeUnprotectInstance,
+	}
+}
+
+// protectionResult reports the full protection list after a change
+type protectionResult struct {
+	Success    bool                      `json:"success"`
+	Message    string                    `json:"message"`
+	FromFile   config.DeletionProtection `json:"from_protection_file"`
+	FromServer config.DeletionProtection `json:"added_with_protect_instance"`
+}
+
+// protectionEntry checks the selector parameters and returns which one was given and its value
+func protectionEntry(parameters map[string]interface{}) (string, string, error) {
+	given := map[string]string{}
+	for _, name := range []string{"instance_id", "name_pattern", "label"} {
+		if value, _ := parameters[name].(string); value != "" {
+			given[name] = value
+		}
+	}
+	if len(given) != 1 {
+		return "", "", fmt.Errorf("give exactly one of 'instance_id', 'name_pattern' or 'label'")
+	}
+
+	for kind, value := range given {
+		switch kind {
+		case "name_pattern":
+			if _, err := path.Match(value, ""); err != nil {
+				return "", "", fmt.Errorf("'%s' is not a valid glob pattern: %v", value, err)
+			}
+		case "label":
+			if _, err := parseLabelSelector(value); err != nil {
+				return "", "", err
+			}
+		}
+		return kind, value, nil
+	}
+	return "", "", nil
+}
+
+// protectionList returns the list in protection that holds entries of kind
+func protectionList(protection *config.DeletionProtection, kind string) *[]string {
+	switch kind {
+	case "instance_id":
+		return &protection.InstanceIds
+	case "name_pattern":
+		return &protection.NamePatterns
+	default:
+		return &protection.Labels
+	}
+}
+
+// newProtectionResult reports the full protection list
+func newProtectionResult(deps *Dependencies, message string) (*mcp.CallToolResult, error) {
+	fromServer, err := deps.Protection.Get()
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to load deletion protection: %v", err)), nil
+	}
+
+	result := protectionResult{
+		Success:    true,
+		Message:    message,
+		FromFile:   deps.Config.Protection,
+		FromServer: fromServer,
+	}
+
+	jsonData, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to serialize results: %v", err)), nil
+	}
+
+	return mcp.NewToolResultText(string(jsonData)), nil
+}
+
+// executeProtectInstance implements the protect-instance outcome
+func executeProtectInstance(ctx context.Context, parameters map[string]interface{}, deps *Dependencies) (*mcp.CallToolResult, error) {
+	kind, value, err := protectionEntry(parameters)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	// Check a single instance exists so that a mistyped ID is not silently protected
+	if kind == "instance_id" {
+		if deps.AClient == nil {
+			return mcp.NewToolResultError("Aura API Client is not initialized"), nil
+		}
+		if _, err := deps.AClient.Instances.Get(value); err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("Failed to retrieve instance: %v", err)), nil
+		}
+	}
+
+	if dry := dryRunFromContext(ctx); dry != nil {
+		dry.localChange("protect instances matching %s '%s' from deletion and overwrite", kind, value)
+		return dry.result(), nil
+	}
+
+	err = deps.Protection.Update(func(p *config.DeletionProtection) {
+		list := protectionList(p, kind)
+		if !slices.Contains(*list, value) {
+			*list = append(*list, value)
+		}
+	})
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to save deletion protection: %v", err)), nil
+	}
+
+	return newProtectionResult(deps, fmt.Sprintf("Instances matching %s '%s' are now protected from deletion and overwrite", kind, value))
+}
+
+// executeUnprotectInstance implements the unprotect-instance outcome
+func executeUnprotectInstance(ctx context.Context, parameters map[string]interface{}, deps *Dependencies) (*mcp.CallToolResult, error) {
+	if deps.Config.UnprotectSecret == "" {
+		return mcp.NewToolResultError("Removing protection is disabled as the server has no UNPROTECT_SECRET. Ask the user to set one and restart the server"), nil
+	}
+
+	secret, _ := parameters["secret"].(string)
+	if subtle.ConstantTimeCompare([]byte(secret), []byte(deps.Config.UnprotectSecret)) != 1 {
+		return mcp.NewToolResultError("The secret is not correct. Ask the user for the unprotect secret"), nil
+	}
+
+	kind, value, err := protectionEntry(parameters)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	fromFile := deps.Config.Protection
+	if slices.Contains(*protectionList(&fromFile, kind), value) {
+		return mcp.NewToolResultError(fmt.Sprintf("%s '%s' is protected by the protection file %s and can only be removed by editing it", kind, value, deps.Config.ProtectionFile)), nil
+	}
+
+	if dry := dryRunFromContext(ctx); dry != nil {
+		dry.localChange("remove protection for %s '%s'", kind, value)
+		return dry.result(), nil
+	}
+
+	removed := false
+	err = deps.Protection.Update(func(p *config.DeletionProtection) {
+		list := protectionList(p, kind)
+		if i := slices.Index(*list, value); i >= 0 {
+			*list = slices.Delete(*list, i, i+1)
+			removed = true
+		}
+	})
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to save deletion protection: %v", err)), nil
+	}
+	if !removed {
+		return mcp.NewToolResultError(fmt.Sprintf("There is no protection for %s '%s'", kind, value)), nil
+	}
+
+	return newProtectionResult(deps, fmt.Sprintf("Protection for %s '%s' has been removed", kind, value))
+}
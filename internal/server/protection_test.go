@@ -0,0 +1,181 @@
+package server
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	aura "github.com/LackOfMorals/aura-client"
+	"github.com/LackOfMorals/mcp4AuraAPI/internal/config"
+)
+
+// newProtectionDependencies returns dependencies with instance a1 named 'prod-db' and labelled
+// env=prod. The protection file protects instance ID f1, name pattern 'file-*' and label
+// tier=gold; protect-instance has protected 'prod-*' and env=prod
+func newProtectionDependencies(t *testing.T) (*Dependencies, *fakeInstances) {
+	t.Helper()
+	instances := newFakeInstances(fakeInstance{GetInstanceData: aura.GetInstanceData{Id: "a1", Name: "prod-db", Status: "running", TenantId: "t1", Memory: "8GB"}})
+	deps := newTestDependencies(t, instances)
+	deps.Config.Protection = config.DeletionProtection{
+		InstanceIds:  []string{"f1"},
+		NamePatterns: []string{"file-*"},
+		Labels:       []string{"tier=gold"},
+	}
+	deps.Config.ProtectionFile = "protection.json"
+	deps.Config.UnprotectSecret = "open-sesame"
+	if err := deps.Protection.Update(func(p *config.DeletionProtection) {
+		p.NamePatterns = append(p.NamePatterns, "prod-*")
+		p.Labels = append(p.Labels, "env=prod")
+	}); err != nil {
+		t.Fatal(err)
+	}
+	return deps, instances
+}
+
+func TestMatchProtection(t *testing.T) {
+	protection := config.DeletionProtection{
+		InstanceIds:  []string{"a1"},
+		NamePatterns: []string{"prod-*", "[invalid"},
+		Labels:       []string{"env=prod", "keep", "=invalid"},
+	}
+
+	tests := []struct {
+		name       string
+		instanceID string
+		instance   string
+		labels     map[string]string
+		want       string
+	}{
+		{name: "instance ID", instanceID: "a1", instance: "dev", want: "instance ID 'a1'"},
+		{name: "name pattern", instanceID: "b1", instance: "prod-db", want: "name pattern 'prod-*'"},
+		{name: "pattern matches whole name", instanceID: "b1", instance: "my-prod-db"},
+		{name: "label value", instanceID: "b1", instance: "db", labels: map[string]string{"env": "prod"}, want: "label 'env=prod'"},
+		{name: "other label value", instanceID: "b1", instance: "db", labels: map[string]string{"env": "dev"}},
+		{name: "label present", instanceID: "b1", instance: "db", labels: map[string]string{"keep": ""}, want: "label 'keep'"},
+		{name: "nothing matches", instanceID: "b1", instance: "db"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := matchProtection(protection, tt.instanceID, tt.instance, tt.labels); got != tt.want {
+				t.Fatalf("matchProtection() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckDeletionProtection(t *testing.T) {
+	tests := []struct {
+		name       string
+		instanceID string
+		instance   string
+		labels     map[string]string
+		wantErr    string
+	}{
+		{name: "not protected", instanceID: "b1", instance: "dev-db"},
+		{name: "file instance ID", instanceID: "f1", instance: "dev-db", wantErr: "in the protection file"},
+		{name: "file name pattern", instanceID: "b1", instance: "file-db", wantErr: "in the protection file"},
+		{name: "file label", instanceID: "b1", instance: "dev-db", labels: map[string]string{"tier": "gold"}, wantErr: "in the protection file"},
+		{name: "protect-instance name pattern", instanceID: "b1", instance: "prod-db", wantErr: "removed with unprotect-instance"},
+		{name: "protect-instance label", instanceID: "b1", instance: "dev-db", labels: map[string]string{"env": "prod"}, wantErr: "removed with unprotect-instance"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			deps, _ := newProtectionDependencies(t)
+			if tt.labels != nil {
+				if _, err := deps.Labels.Update(tt.instanceID, func(l *instanceLabels) { l.Labels = tt.labels }); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			err := checkDeletionProtection(deps, tt.instanceID, tt.instance)
+			if tt.wantErr == "" && err != nil {
+				t.Fatalf("checkDeletionProtection() error = %v, want nil", err)
+			}
+			if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Fatalf("checkDeletionProtection() error = %v, want one containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCheckLabelProtection(t *testing.T) {
+	tests := []struct {
+		name    string
+		before  map[string]string
+		after   map[string]string
+		secret  string
+		wantErr string
+	}{
+		{name: "other label changed", before: map[string]string{"env": "prod", "team": "a"}, after: map[string]string{"env": "prod", "team": "b"}},
+		{name: "protecting label kept", before: map[string]string{"env": "prod"}, after: map[string]string{"env": "prod", "team": "b"}},
+		{name: "protecting label removed", before: map[string]string{"env": "prod"}, after: map[string]string{}, wantErr: "with the unprotect secret"},
+		{name: "protecting label changed", before: map[string]string{"env": "prod"}, after: map[string]string{"env": "dev"}, wantErr: "with the unprotect secret"},
+		{name: "wrong secret", before: map[string]string{"env": "prod"}, after: map[string]string{}, secret: "guess", wantErr: "with the unprotect secret"},
+		{name: "with the secret", before: map[string]string{"env": "prod"}, after: map[string]string{}, secret: "open-sesame"},
+		{name: "label not protecting yet", before: map[string]string{"env": "dev"}, after: map[string]string{"env": "test"}},
+		{name: "file label", before: map[string]string{"tier": "gold"}, after: map[string]string{}, secret: "open-sesame", wantErr: "in the protection file"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			deps, _ := newProtectionDependencies(t)
+			err := checkLabelProtection(deps, "a1", "prod-db", tt.before, tt.after, tt.secret)
+			if tt.wantErr == "" && err != nil {
+				t.Fatalf("checkLabelProtection() error = %v, want nil", err)
+			}
+			if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Fatalf("checkLabelProtection() error = %v, want one containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestUpdateInstanceRenameProtection(t *testing.T) {
+	tests := []struct {
+		name       string
+		instance   string // Name of a1 before the rename
+		newName    string
+		secret     string
+		noSecret   bool // UNPROTECT_SECRET is not set
+		wantChange bool
+		wantErr    string
+	}{
+		{name: "still matches", instance: "prod-db", newName: "prod-db2", wantChange: true},
+		{name: "not protected", instance: "dev-db", newName: "other", wantChange: true},
+		{name: "renamed out of the pattern", instance: "prod-db", newName: "db", wantErr: "with the unprotect secret"},
+		{name: "wrong secret", instance: "prod-db", newName: "db", secret: "guess", wantErr: "with the unprotect secret"},
+		{name: "with the secret", instance: "prod-db", newName: "db", secret: "open-sesame", wantChange: true},
+		{name: "no secret configured", instance: "prod-db", newName: "db", noSecret: true, wantErr: "with the unprotect secret"},
+		{name: "file pattern", instance: "file-db", newName: "db", secret: "open-sesame", wantErr: "in the protection file"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			deps, instances := newProtectionDependencies(t)
+			instances.instances["a1"].Name = tt.instance
+			if tt.noSecret {
+				deps.Config.UnprotectSecret = ""
+			}
+			parameters := map[string]interface{}{"instance_id": "a1", "name": tt.newName}
+			if tt.secret != "" {
+				parameters["secret"] = tt.secret
+			}
+
+			result, err := executeUpdateInstance(context.Background(), parameters, deps)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.wantErr != "" && (!result.IsError || !strings.Contains(toolResultText(result), tt.wantErr)) {
+				t.Fatalf("result = %s, want an error containing %q", toolResultText(result), tt.wantErr)
+			}
+			if tt.wantErr == "" && result.IsError {
+				t.Fatalf("result = %s, want the rename to go ahead", toolResultText(result))
+			}
+			if changed := len(instances.Changes()) == 1; changed != tt.wantChange {
+				t.Fatalf("changes = %v, want a rename %t", instances.Changes(), tt.wantChange)
+			}
+		})
+	}
+}
@@ -1,7 +1,9 @@
 package server
 
 import (
+	"context"
 	"log/slog"
+	"sync"
 	"time"
 
 	
@@ -16,15 +18,29 @@ type Neo4jMCPServer struct {
 	MCPServer *server.MCPServer
 	config    *config.Config
 	aClient   *aura.AuraAPIClient
+	aDirect   auraRequester
 	aOutcomes *OutcomeRegistry
+	tConfigs  *TenantConfigurationCache
+	ttls      *TTLStore
+	labels    *LabelStore
+	protect   *ProtectionStore
 	version   string
+
+	// The background reaper of expired instances
+	reaperCancel context.CancelFunc
+	reaperDone   sync.WaitGroup
 }
 
 // Dependencies contains all dependencies needed to achieve an outcome
 type Dependencies struct {
-	AClient  *aura.AuraAPIClient
-	Config   *config.Config
-	OutComes *OutcomeRegistry
+	AClient       *aura.AuraAPIClient
+	AuraAPI       auraRequester // For the Aura API calls that AClient does not support yet
+	Config        *config.Config
+	OutComes      *OutcomeRegistry
+	TenantConfigs *TenantConfigurationCache
+	TTLs          *TTLStore
+	Labels        *LabelStore
+	Protection    *ProtectionStore
 }
 
 // NewNeo4jMCPServer creates a new MCP server instance
@@ -34,6 +50,7 @@ func NewNeo4jMCPServer(version string, cfg *config.Config) *Neo4jMCPServer {
 		"mcp-aura-api",
 		version,
 		server.WithToolCapabilities(true),
+		server.WithElicitation(),
 		server.WithInstructions("This MCP server provides tools for interacting with Neo4j Aura API "),
 	)
 
@@ -46,13 +63,26 @@ func NewNeo4jMCPServer(version string, cfg *config.Config) *Neo4jMCPServer {
 	// Register outcomes
 	auraOutcomes := NewOutcomeRegistry()
 
-	return &Neo4jMCPServer{
+	s := &Neo4jMCPServer{
 		MCPServer: mcpServer,
 		config:    cfg,
 		version:   version,
 		aClient:   auraClient,
 		aOutcomes: auraOutcomes,
+		tConfigs:  NewTenantConfigurationCache(),
+		ttls:      NewTTLStore(cfg.StateDir),
+		labels:    NewLabelStore(cfg.StateDir),
+		protect:   NewProtectionStore(cfg.StateDir),
+	}
+
+	// Create the client for the Aura API calls the Aura API client does not support yet
+	if directClient, err := newDirectAuraClient(cfg.URI, cfg.ClientId, cfg.ClientSecret, 120*time.Second); err == nil {
+		s.aDirect = directClient
+	} else {
+		slog.Warn("Outcomes that call the Aura API directly are not available", "error", err)
 	}
+
+	return s
 }
 
 // Start initializes and starts the MCP server using stdio transport
@@ -65,14 +95,28 @@ func (s *Neo4jMCPServer) Start() error {
 
 	// Dependencies needed by all outcomes
 	outcomeDependencies := Dependencies{
-		AClient:  s.aClient,
-		OutComes: s.aOutcomes,
-		Config:   s.config,
+		AClient:       s.aClient,
+		AuraAPI:       s.aDirect,
+		OutComes:      s.aOutcomes,
+		Config:        s.config,
+		TenantConfigs: s.tConfigs,
+		TTLs:          s.ttls,
+		Labels:        s.labels,
+		Protection:    s.protect,
 	}
 
 	// Register tools
 	s.registerTools(&outcomeDependencies)
 
+	// Start the reaper of expired instances
+	reaperCtx, cancel := context.WithCancel(context.Background())
+	s.reaperCancel = cancel
+	s.reaperDone.Add(1)
+	go func() {
+		defer s.reaperDone.Done()
+		runReaper(reaperCtx, &outcomeDependencies, s.config.ReaperInterval)
+	}()
+
 	slog.Info("Started MCP Aura API Server. Now listening for input...")
 	// Note: ServeStdio handles its own signal management for graceful shutdown
 	return server.ServeStdio(s.MCPServer)
@@ -112,6 +156,13 @@ func GetAllTools(deps *Dependencies) []server.ServerTool {
 // Stop gracefully stops the server
 func (s *Neo4jMCPServer) Stop() error {
 	slog.Info("Stopping MCP Aura API Server...")
-	// Currently no cleanup needed - the MCP server handles its own lifecycle
+
+	// Stop the reaper, waiting for anything it is part way through, such as a deletion, to finish
+	if s.reaperCancel != nil {
+		s.reaperCancel()
+		s.reaperDone.Wait()
+	}
+
+	// The MCP server handles its own lifecycle
 	return nil
 }
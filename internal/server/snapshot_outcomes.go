@@ -0,0 +1,283 @@
+// =============================================================================
+// These are all of the snapshot related outcomes
+// =============================================================================
+
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/LackOfMorals/aura-client"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// registerListSnapshotsOutcome registers the list-snapshots outcome
+func (r *OutcomeRegistry) registerListSnapshotsOutcome() {
+	r.Outcomes["list-snapshots"] = &Outcome{
+		ID:          "list-snapshots",
+		Name:        "List Snapshots",
+		Description: "Retrieve the snapshots (backups) of a Neo4j Aura database instance, newest first. Optionally filter to a single day. Also reports the most recent completed snapshot so you can check a recent backup exists before deleting or overwriting an instance.",
+		Type:        OutcomesTypeList,
+		ReadOnly:    true,
+		Parameters: []OutcomeParameter{
+			{
+				Name:        "instance_id",
+				Type:        "string",
+				Description: "The ID of the instance to list snapshots for",
+				Required:    true,
+			},
+			{
+				Name:        "date",
+				Type:        "string",
+				Description: "Only return snapshots taken on this date. Format is YYYY-MM-DD",
+				Required:    false,
+			},
+		},
+		Metadata: map[string]interface{}{
+			"category": "snapshots",
+		},
+		Handler: executeListSnapshots,
+	}
+}
+
+// executeListSnapshots implements the list-snapshots outcome
+func executeListSnapshots(ctx context.Context, parameters map[string]interface{}, deps *Dependencies) (*mcp.CallToolResult, error) {
+	if deps.AClient == nil {
+		return mcp.NewToolResultError("Aura API Client is not initialized"), nil
+	}
+
+	// Validate and extract parameters
+	instanceID, ok := parameters["instance_id"].(string)
+	if !ok || instanceID == "" {
+		return mcp.NewToolResultError("'instance_id' parameter is required and must be a non-empty string"), nil
+	}
+
+	date, _ := parameters["date"].(string)
+
+	// Get the list of snapshots
+	snapshots, err := deps.AClient.Snapshots.List(instanceID, date)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to list snapshots: %v", err)), nil
+	}
+
+	type snapshotList struct {
+		InstanceId           string                 `json:"instance_id"`
+		Count                int                    `json:"count"`
+		LatestCompletedId    string                 `json:"latest_completed_snapshot_id,omitempty"`
+		LatestCompletedTaken string                 `json:"latest_completed_timestamp,omitempty"`
+		Snapshots            []aura.GetSnapshotData `json:"snapshots"`
+	}
+
+	records := snapshots.Data
+
+	// Timestamps are RFC3339 so sorting the strings gives newest first
+	sort.Slice(records, func(i, j int) bool {
+		return records[i].Timestamp > records[j].Timestamp
+	})
+
+	result := snapshotList{
+		InstanceId: instanceID,
+		Count:      len(records),
+		Snapshots:  records,
+	}
+
+	for _, snap := range records {
+		if snap.Status == "Completed" {
+			result.LatestCompletedId = snap.SnapshotId
+			result.LatestCompletedTaken = snap.Timestamp
+			break
+		}
+	}
+
+	jsonData, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to serialize snapshots: %v", err)), nil
+	}
+
+	return mcp.NewToolResultText(string(jsonData)), nil
+}
+
+// registerGetSnapshotOutcome registers the get-snapshot outcome
+func (r *OutcomeRegistry) registerGetSnapshotOutcome() {
+	r.Outcomes["get-snapshot"] = &Outcome{
+		ID:          "get-snapshot",
+		Name:        "Get Snapshot",
+		Description: "Retrieve the details of a single snapshot of a Neo4j Aura database instance including its status, profile and when it was taken.",
+		Type:        OutcomesTypeRead,
+		ReadOnly:    true,
+		Parameters: []OutcomeParameter{
+			{
+				Name:        "instance_id",
+				Type:        "string",
+				Description: "The ID of the instance the snapshot belongs to",
+				Required:    true,
+			},
+			{
+				Name:        "snapshot_id",
+				Type:        "string",
+				Description: "The ID of the snapshot to retrieve details for",
+				Required:    true,
+			},
+		},
+		Metadata: map[string]interface{}{
+			"category": "snapshots",
+		},
+		Handler: executeGetSnapshot,
+	}
+}
+
+// executeGetSnapshot implements the get-snapshot outcome
+func executeGetSnapshot(ctx context.Context, parameters map[string]interface{}, deps *Dependencies) (*mcp.CallToolResult, error) {
+	if deps.AClient == nil {
+		return mcp.NewToolResultError("Aura API Client is not initialized"), nil
+	}
+
+	// Validate and extract required parameters
+	instanceID, ok := parameters["instance_id"].(string)
+	if !ok || instanceID == "" {
+		return mcp.NewToolResultError("'instance_id' parameter is required and must be a non-empty string"), nil
+	}
+
+	snapshotID, ok := parameters["snapshot_id"].(string)
+	if !ok || snapshotID == "" {
+		return mcp.NewToolResultError("'snapshot_id' parameter is required and must be a non-empty string"), nil
+	}
+
+	snapshot, err := findSnapshot(deps, instanceID, snapshotID)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	jsonData, err := json.MarshalIndent(snapshot, "", "  ")
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to serialize snapshot details: %v", err)), nil
+	}
+
+	return mcp.NewToolResultText(string(jsonData)), nil
+}
+
+// registerCreateSnapshotOutcome registers the create-snapshot outcome
+func (r *OutcomeRegistry) registerCreateSnapshotOutcome() {
+	r.Outcomes["create-snapshot"] = &Outcome{
+		ID:          "create-snapshot",
+		Name:        "Create Snapshot",
+		Description: "Take an on-demand snapshot (backup) of a Neo4j Aura database instance. Returns the ID of the new snapshot which can be checked with get-snapshot.",
+		Type:        OutcomesTypeCreate,
+		ReadOnly:    false,
+		Parameters: []OutcomeParameter{
+			{
+				Name:        "instance_id",
+				Type:        "string",
+				Description: "The ID of the instance to snapshot",
+				Required:    true,
+			},
+		},
+		Metadata: map[string]interface{}{
+			"category": "snapshots",
+		},
+		Handler: executeCreateSnapshot,
+	}
+}
+
+// executeCreateSnapshot implements the create-snapshot outcome
+func executeCreateSnapshot(ctx context.Context, parameters map[string]interface{}, deps *Dependencies) (*mcp.CallToolResult, error) {
+	if deps.AClient == nil {
+		return mcp.NewToolResultError("Aura API Client is not initialized"), nil
+	}
+
+	// Validate and extract required parameter
+	instanceID, ok := parameters["instance_id"].(string)
+	if !ok || instanceID == "" {
+		return mcp.NewToolResultError("'instance_id' parameter is required and must be a non-empty string"), nil
+	}
+
+	// Check the instance exists first to give a clear message if it does not
+	instanceInfo, err := deps.AClient.Instances.Get(instanceID)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to retrieve instance details before snapshot: %v. The instance may not exist or you may not have access to it.", err)), nil
+	}
+
+	snapshot, err := deps.AClient.Snapshots.Create(instanceID)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to create snapshot: %v", err)), nil
+	}
+
+	// Format the response
+	type createResult struct {
+		Success      bool   `json:"success"`
+		Message      string `json:"message"`
+		SnapshotId   string `json:"snapshot_id"`
+		InstanceId   string `json:"instance_id"`
+		InstanceName string `json:"instance_name"`
+	}
+
+	result := createResult{
+		Success:      true,
+		Message:      fmt.Sprintf("Snapshot of instance '%s' (ID: %s) has been requested", instanceInfo.Data.Name, instanceID),
+		SnapshotId:   snapshot.Data.SnapshotId,
+		InstanceId:   instanceID,
+		InstanceName: instanceInfo.Data.Name,
+	}
+
+	jsonData, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to serialize results: %v", err)), nil
+	}
+
+	return mcp.NewToolResultText(string(jsonData)), nil
+}
+
+// findSnapshot looks up a single snapshot of an instance. The Aura API only lists snapshots
+// so the list is searched for the one that is wanted
+func findSnapshot(deps *Dependencies, instanceID, snapshotID string) (*aura.GetSnapshotData, error) {
+	snapshots, err := deps.AClient.Snapshots.List(instanceID, "")
+	if err != nil {
+		return nil, fmt.Errorf("Failed to list snapshots: %v", err)
+	}
+
+	for _, snap := range snapshots.Data {
+		if snap.SnapshotId == snapshotID {
+			return &snap, nil
+		}
+	}
+
+	return nil, fmt.Errorf("Snapshot '%s' not found for instance '%s'", snapshotID, instanceID)
+}
+
+// waitForSnapshot polls a snapshot until it has completed, it fails, the timeout passes or ctx is
+// cancelled. The interval between polls is the same as when waiting for an instance
+func waitForSnapshot(ctx context.Context, deps *Dependencies, instanceID, snapshotID string, timeout time.Duration) (*aura.GetSnapshotData, error) {
+	deadline := time.Now().Add(timeout)
+	interval := waitInitialInterval
+
+	for {
+		snapshot, err := findSnapshot(deps, instanceID, snapshotID)
+		if err != nil {
+			return nil, err
+		}
+
+		switch snapshot.Status {
+		case "Completed":
+			return snapshot, nil
+		case "Failed":
+			return snapshot, fmt.Errorf("snapshot %s of instance %s failed", snapshotID, instanceID)
+		}
+
+		remaining := time.Until(deadline)
+		if remaining <= 0 {
+			return snapshot, fmt.Errorf("timed out after %s waiting for snapshot %s to complete, last status was '%s'", timeout, snapshotID, snapshot.Status)
+		}
+
+		select {
+		case <-ctx.Done():
+			return snapshot, ctx.Err()
+		case <-time.After(min(interval, remaining)):
+		}
+
+		interval = min(interval*2, waitMaxInterval)
+	}
+}
@@ -0,0 +1,163 @@
+// =============================================================================
+// This is a short lived cache of the instance configurations each tenant allows. It is
+// used to check an instance definition before it is sent to the Aura API
+// =============================================================================
+
+package server
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/LackOfMorals/aura-client"
+)
+
+// tenantConfigurationsTTL is how long the instance configurations of a tenant are cached for
+const tenantConfigurationsTTL = 5 * time.Minute
+
+// maxSuggestedConfigurations is how many valid configurations are suggested when one is rejected
+const maxSuggestedConfigurations = 5
+
+// TenantConfigurationCache caches the instance configurations allowed by each tenant
+type TenantConfigurationCache struct {
+	mu      sync.Mutex
+	ttl     time.Duration
+	entries map[string]tenantConfigurationEntry
+}
+
+type tenantConfigurationEntry struct {
+	configurations []aura.TenantInstanceConfiguration
+	fetched        time.Time
+}
+
+// NewTenantConfigurationCache creates an empty cache
+func NewTenantConfigurationCache() *TenantConfigurationCache {
+	return &TenantConfigurationCache{
+		ttl:     tenantConfigurationsTTL,
+		entries: make(map[string]tenantConfigurationEntry),
+	}
+}
+
+// Get returns the instance configurations of a tenant, fetching them from the Aura API if they are
+// not cached or have expired. A nil cache always fetches
+func (c *TenantConfigurationCache) Get(client *aura.AuraAPIClient, tenantID string) ([]aura.TenantInstanceConfiguration, error) {
+	if c != nil {
+		c.mu.Lock()
+		entry, ok := c.entries[tenantID]
+		c.mu.Unlock()
+		if ok && time.Since(entry.fetched) < c.ttl {
+			return entry.configurations, nil
+		}
+	}
+
+	tenantInfo, err := client.Tenants.Get(tenantID)
+	if err != nil {
+		return nil, err
+	}
+
+	if c != nil {
+		c.mu.Lock()
+		c.entries[tenantID] = tenantConfigurationEntry{
+			configurations: tenantInfo.Data.InstanceConfigurations,
+			fetched:        time.Now(),
+		}
+		c.mu.Unlock()
+	}
+
+	return tenantInfo.Data.InstanceConfigurations, nil
+}
+
+// validateInstanceDefinition checks that the combination of cloud provider, region, memory, type and version
+// in the definition is one that the tenant allows. If it is not, the error lists the closest valid combinations
+func validateInstanceDefinition(deps *Dependencies, definition *aura.CreateInstanceConfigData) error {
+	configurations, err := deps.TenantConfigs.Get(deps.AClient, definition.TenantId)
+	if err != nil {
+		return fmt.Errorf("Failed to retrieve the instance configurations allowed by tenant '%s': %v. Use list-tenants to find a valid tenant", definition.TenantId, err)
+	}
+
+	for _, cfg := range configurations {
+		if configurationMatches(cfg, definition) {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("The combination cloud_provider '%s', region '%s', memory '%s', type '%s', version '%s' is not allowed in tenant '%s'. Closest valid combinations are:\n%s",
+		definition.CloudProvider, definition.Region, definition.Memory, definition.Type, definition.Version, definition.TenantId,
+		formatConfigurations(closestConfigurations(configurations, definition, maxSuggestedConfigurations)))
+}
+
+// configurationMatches returns true if a tenant configuration allows the instance definition
+func configurationMatches(cfg aura.TenantInstanceConfiguration, definition *aura.CreateInstanceConfigData) bool {
+	return cfg.CloudProvider == definition.CloudProvider &&
+		cfg.Region == definition.Region &&
+		cfg.Memory == definition.Memory &&
+		cfg.Type == definition.Type &&
+		cfg.Version == definition.Version
+}
+
+// closestConfigurations returns up to limit configurations ordered by how many fields they share with
+// the definition. Ties are broken by how close the memory size is
+func closestConfigurations(configurations []aura.TenantInstanceConfiguration, definition *aura.CreateInstanceConfigData, limit int) []aura.TenantInstanceConfiguration {
+	type scored struct {
+		cfg        aura.TenantInstanceConfiguration
+		matches    int
+		memoryDist int
+	}
+
+	wantedMemory := slices.Index(supportedMemory, definition.Memory)
+
+	candidates := make([]scored, 0, len(configurations))
+	for _, cfg := range configurations {
+		s := scored{cfg: cfg}
+		for _, same := range []bool{
+			cfg.CloudProvider == definition.CloudProvider,
+			cfg.Region == definition.Region,
+			cfg.Memory == definition.Memory,
+			cfg.Type == definition.Type,
+			cfg.Version == definition.Version,
+		} {
+			if same {
+				s.matches++
+			}
+		}
+		s.memoryDist = len(supportedMemory)
+		if idx := slices.Index(supportedMemory, cfg.Memory); idx >= 0 && wantedMemory >= 0 {
+			s.memoryDist = max(idx-wantedMemory, wantedMemory-idx)
+		}
+		candidates = append(candidates, s)
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		if candidates[i].matches != candidates[j].matches {
+			return candidates[i].matches > candidates[j].matches
+		}
+		return candidates[i].memoryDist < candidates[j].memoryDist
+	})
+
+	result := make([]aura.TenantInstanceConfiguration, 0, limit)
+	for _, c := range candidates {
+		if len(result) == limit {
+			break
+		}
+		result = append(result, c.cfg)
+	}
+	return result
+}
+
+// formatConfigurations formats configurations one per line for use in an error message
+func formatConfigurations(configurations []aura.TenantInstanceConfiguration) string {
+	if len(configurations) == 0 {
+		return "  none - the tenant does not allow any instance configurations"
+	}
+
+	lines := make([]string, 0, len(configurations))
+	for _, cfg := range configurations {
+		lines = append(lines, fmt.Sprintf("  cloud_provider '%s', region '%s', memory '%s', type '%s', version '%s'",
+			cfg.CloudProvider, cfg.Region, cfg.Memory, cfg.Type, cfg.Version))
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,128 @@
+package server
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	aura "github.com/LackOfMorals/aura-client"
+)
+
+// testConfigurations are the instance configurations allowed by the fake tenant
+var testConfigurations = []aura.TenantInstanceConfiguration{
+	{CloudProvider: "gcp", Region: "europe-west1", Memory: "2GB", Type: "professional-db", Version: "5"},
+	{CloudProvider: "gcp", Region: "europe-west1", Memory: "8GB", Type: "professional-db", Version: "5"},
+	{CloudProvider: "gcp", Region: "europe-west1", Memory: "32GB", Type: "professional-db", Version: "5"},
+	{CloudProvider: "aws", Region: "us-east-1", Memory: "8GB", Type: "enterprise-db", Version: "5"},
+}
+
+func TestClosestConfigurations(t *testing.T) {
+	tests := []struct {
+		name       string
+		definition aura.CreateInstanceConfigData
+		limit      int
+		wantMemory []string // Memory of the configurations returned, in order
+	}{
+		{
+			name:       "nearest memory first",
+			definition: aura.CreateInstanceConfigData{CloudProvider: "gcp", Region: "europe-west1", Memory: "4GB", Type: "professional-db", Version: "5"},
+			limit:      3,
+			wantMemory: []string{"2GB", "8GB", "32GB"},
+		},
+		{
+			name:       "most fields in common first",
+			definition: aura.CreateInstanceConfigData{CloudProvider: "aws", Region: "us-east-1", Memory: "16GB", Type: "enterprise-db", Version: "5"},
+			limit:      2,
+			wantMemory: []string{"8GB", "8GB"},
+		},
+		{
+			name:       "limited",
+			definition: aura.CreateInstanceConfigData{CloudProvider: "gcp", Region: "europe-west1", Memory: "32GB", Type: "professional-db", Version: "4"},
+			limit:      1,
+			wantMemory: []string{"32GB"},
+		},
+		{
+			name:       "unknown memory keeps the order",
+			definition: aura.CreateInstanceConfigData{CloudProvider: "gcp", Region: "europe-west1", Memory: "lots", Type: "professional-db", Version: "5"},
+			limit:      5,
+			wantMemory: []string{"2GB", "8GB", "32GB", "8GB"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := closestConfigurations(testConfigurations, &tt.definition, tt.limit)
+			var memory []string
+			for _, cfg := range got {
+				memory = append(memory, cfg.Memory)
+			}
+			if strings.Join(memory, ",") != strings.Join(tt.wantMemory, ",") {
+				t.Fatalf("closestConfigurations() memory = %v, want %v", memory, tt.wantMemory)
+			}
+		})
+	}
+}
+
+func TestTenantConfigurationCache(t *testing.T) {
+	tests := []struct {
+		name     string
+		cache    *TenantConfigurationCache
+		expire   bool // The cached entry is older than the TTL before the second call
+		tenants  []string
+		wantGets int
+	}{
+		{name: "cached", cache: NewTenantConfigurationCache(), tenants: []string{"t1", "t1"}, wantGets: 1},
+		{name: "each tenant", cache: NewTenantConfigurationCache(), tenants: []string{"t1", "t2", "t1"}, wantGets: 2},
+		{name: "expired", cache: NewTenantConfigurationCache(), expire: true, tenants: []string{"t1", "t1"}, wantGets: 2},
+		{name: "nil cache", tenants: []string{"t1", "t1"}, wantGets: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tenants := &fakeTenants{configurations: testConfigurations}
+			client := &aura.AuraAPIClient{Tenants: tenants}
+
+			for i, tenantID := range tt.tenants {
+				if i == 1 && tt.expire {
+					entry := tt.cache.entries[tt.tenants[0]]
+					entry.fetched = time.Now().Add(-tenantConfigurationsTTL)
+					tt.cache.entries[tt.tenants[0]] = entry
+				}
+				configurations, err := tt.cache.Get(client, tenantID)
+				if err != nil || len(configurations) != len(testConfigurations) {
+					t.Fatalf("Get(%s) = %d configurations, %v", tenantID, len(configurations), err)
+				}
+			}
+			if tenants.gets != tt.wantGets {
+				t.Fatalf("tenant fetched %d times, want %d", tenants.gets, tt.wantGets)
+			}
+		})
+	}
+}
+
+func TestValidateInstanceDefinition(t *testing.T) {
+	tests := []struct {
+		name       string
+		definition aura.CreateInstanceConfigData
+		wantErr    string
+	}{
+		{name: "allowed", definition: aura.CreateInstanceConfigData{TenantId: "t1", CloudProvider: "gcp", Region: "europe-west1", Memory: "8GB", Type: "professional-db", Version: "5"}},
+		{name: "not allowed", definition: aura.CreateInstanceConfigData{TenantId: "t1", CloudProvider: "gcp", Region: "europe-west1", Memory: "4GB", Type: "professional-db", Version: "5"}, wantErr: "memory '2GB'"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			deps := newTestDependencies(t, newFakeInstances())
+			deps.AClient.Tenants = &fakeTenants{configurations: testConfigurations}
+			deps.TenantConfigs = NewTenantConfigurationCache()
+
+			err := validateInstanceDefinition(deps, &tt.definition)
+			if tt.wantErr == "" && err != nil {
+				t.Fatalf("validateInstanceDefinition() error = %v, want nil", err)
+			}
+			if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), "Closest valid combinations") || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Fatalf("validateInstanceDefinition() error = %v, want the closest combinations including %q", err, tt.wantErr)
+			}
+		})
+	}
+}
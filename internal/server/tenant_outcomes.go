@@ -0,0 +1,116 @@
+// =============================================================================
+// These are all of the tenant ( project ) related outcomes
+// =============================================================================
+
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/LackOfMorals/aura-client"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// registerListTenantsOutcome registers the list-tenants outcome
+func (r *OutcomeRegistry) registerListTenantsOutcome() {
+	r.Outcomes["list-tenants"] = &Outcome{
+		ID:          "list-tenants",
+		Name:        "List Tenants",
+		Description: "Retrieve a list of all tenants ( projects ) that instances can be created in. Returns the name and ID of each tenant. Use the ID as the tenantId when creating an instance.",
+		Type:        OutcomesTypeList,
+		ReadOnly:    true,
+		Parameters:  []OutcomeParameter{}, // No parameters needed for listing
+		Metadata: map[string]interface{}{
+			"category": "tenants",
+		},
+		Handler: executeListTenants,
+	}
+}
+
+// executeListTenants implements the list-tenants outcome
+func executeListTenants(ctx context.Context, parameters map[string]interface{}, deps *Dependencies) (*mcp.CallToolResult, error) {
+	if deps.AClient == nil {
+		return mcp.NewToolResultError("Aura API Client is not initialized"), nil
+	}
+
+	// Get the list of tenants
+	tenants, err := deps.AClient.Tenants.List()
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to list tenants: %v", err)), nil
+	}
+
+	if len(tenants.Data) == 0 {
+		return mcp.NewToolResultText("No tenants found or user does not have access to any tenants."), nil
+	}
+
+	jsonData, err := json.Marshal(tenants.Data)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to serialize results: %v", err)), nil
+	}
+
+	return mcp.NewToolResultText(string(jsonData)), nil
+}
+
+// registerGetTenantDetailsOutcome registers the get-tenant-details outcome
+func (r *OutcomeRegistry) registerGetTenantDetailsOutcome() {
+	r.Outcomes["get-tenant-details"] = &Outcome{
+		ID:          "get-tenant-details",
+		Name:        "Get Tenant Details",
+		Description: "Retrieve detailed information about a tenant ( project ) including the instance configurations it allows. Each configuration is a combination of cloud provider, region, memory, storage, type and version that can be used when creating an instance in the tenant.",
+		Type:        OutcomesTypeRead,
+		ReadOnly:    true,
+		Parameters: []OutcomeParameter{
+			{
+				Name:        "tenant_id",
+				Type:        "string",
+				Description: "The ID of the tenant to retrieve details for",
+				Required:    true,
+			},
+		},
+		Metadata: map[string]interface{}{
+			"category": "tenants",
+		},
+		Handler: executeGetTenantDetails,
+	}
+}
+
+// executeGetTenantDetails implements the get-tenant-details outcome
+func executeGetTenantDetails(ctx context.Context, parameters map[string]interface{}, deps *Dependencies) (*mcp.CallToolResult, error) {
+	if deps.AClient == nil {
+		return mcp.NewToolResultError("Aura API Client is not initialized"), nil
+	}
+
+	// Validate and extract required parameter
+	tenantID, ok := parameters["tenant_id"].(string)
+	if !ok || tenantID == "" {
+		return mcp.NewToolResultError("'tenant_id' parameter is required and must be a non-empty string"), nil
+	}
+
+	// Get the tenant details from Aura API
+	tenantInfo, err := deps.AClient.Tenants.Get(tenantID)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to retrieve tenant details: %v. The tenant may not exist or you may not have access to it.", err)), nil
+	}
+
+	// Format the response with all relevant details
+	type tenantDetails struct {
+		Id                     string                             `json:"id"`
+		Name                   string                             `json:"name"`
+		InstanceConfigurations []aura.TenantInstanceConfiguration `json:"instance_configurations"`
+	}
+
+	details := tenantDetails{
+		Id:                     tenantInfo.Data.Id,
+		Name:                   tenantInfo.Data.Name,
+		InstanceConfigurations: tenantInfo.Data.InstanceConfigurations,
+	}
+
+	jsonData, err := json.MarshalIndent(details, "", "  ")
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to serialize tenant details: %v", err)), nil
+	}
+
+	return mcp.NewToolResultText(string(jsonData)), nil
+}
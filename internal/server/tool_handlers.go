@@ -22,7 +22,13 @@ import (
 // ListOutcomesHandler returns a handler function for listing all available Outcomes
 func ListOutcomesHandler(deps *Dependencies) func(context.Context, mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-		summaries := deps.OutComes.GetAllSummaries()
+		// Only list the Outcomes the caller may run
+		summaries := []OutcomeSummary{}
+		for _, summary := range deps.OutComes.GetAllSummaries() {
+			if mayListOutcome(ctx, deps.OutComes.Outcomes[summary.ID], deps) {
+				summaries = append(summaries, summary)
+			}
+		}
 
 		jsonData, err := json.MarshalIndent(summaries, "", "  ")
 		if err != nil {
@@ -48,11 +54,15 @@ func GetOutcomeDetailsHandler(deps *Dependencies) func(context.Context, mcp.Call
 			return mcp.NewToolResultError("Outcome_id parameter is required and must be a string"), nil
 		}
 
-		// Get the Outcome details
+		// Get the Outcome details. An Outcome the caller may not run is reported as not found, as it
+		// is not listed for them either
 		Outcome, err := deps.OutComes.GetOutcome(OutcomeID)
 		if err != nil {
 			return mcp.NewToolResultError(err.Error()), nil
 		}
+		if !mayListOutcome(ctx, Outcome, deps) {
+			return mcp.NewToolResultError(fmt.Sprintf("Outcome with ID '%s' not found", OutcomeID)), nil
+		}
 
 		jsonData, err := json.MarshalIndent(Outcome, "", "  ")
 		if err != nil {
@@ -90,6 +100,18 @@ func ExecuteOutcomeHandler(deps *Dependencies) func(context.Context, mcp.CallToo
 			parameters = make(map[string]interface{})
 		}
 
+		// Make the progress token, if any, available to long running Outcomes
+		ctx = withProgressToken(ctx, request)
+
+		// Ask the user before running a destructive Outcome
+		if Outcome, err := deps.OutComes.GetOutcome(OutcomeID); err == nil && needsUserConfirmation(Outcome, parameters, deps) {
+			var refusal *mcp.CallToolResult
+			ctx, refusal = confirmWithUser(ctx, Outcome, parameters, deps)
+			if refusal != nil {
+				return refusal, nil
+			}
+		}
+
 		// Execute the Outcome
 		return deps.OutComes.ExecuteOutcome(ctx, OutcomeID, parameters, deps)
 	}
@@ -0,0 +1,214 @@
+// =============================================================================
+// These are the outcomes for ephemeral instances, those created with a TTL
+//
+// The expiry of each one is kept in a local file. A background reaper pauses or
+// deletes an instance once its TTL has passed; see ttl_store.go
+// =============================================================================
+
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// registerListExpiringInstancesOutcome registers the list-expiring-instances outcome
+func (r *OutcomeRegistry) registerListExpiringInstancesOutcome() {
+	r.Outcomes["list-expiring-instances"] = &Outcome{
+		ID:          "list-expiring-instances",
+		Name:        "List Expiring Instances",
+		Description: "List the instances that were created with a TTL, soonest to expire first, with what will happen to each one when it expires. Instances that have expired and been paused are included.",
+		Type:        OutcomesTypeList,
+		ReadOnly:    true,
+		Parameters: []OutcomeParameter{
+			{
+				Name:        "within",
+				Type:        "string",
+				Description: "Only list instances that expire within this long, such as '1h' or '2d'. Expired instances are always listed",
+				Required:    false,
+			},
+		},
+		Metadata: map[string]interface{}{
+			"category": "instances",
+		},
+		Handler: executeListExpiringInstances,
+	}
+}
+
+// registerExtendTTLOutcome registers the extend-ttl outcome
+func (r *OutcomeRegistry) registerExtendTTLOutcome() {
+	r.Outcomes["extend-ttl"] = &Outcome{
+		ID:          "extend-ttl",
+		Name:        "Extend TTL",
+		Description: "Give an instance more time before it is paused or deleted, either by adding to its current expiry or by setting a new TTL from now. An instance that has already been paused on expiry will be paused again when the new TTL passes; resume it with resume-instance.",
+		Type:        OutcomesTypeUpdate,
+		ReadOnly:    false,
+		Parameters: []OutcomeParameter{
+			{
+				Name:        "instance_id",
+				Type:        "string",
+				Description: "The ID of the instance",
+				Required:    true,
+			},
+			{
+				Name:        "extend_by",
+				Type:        "string",
+				Description: "How long to add to the current expiry, such as '2h' or '1d'. Give this or 'ttl'",
+				Required:    false,
+			},
+			{
+				Name:        "ttl",
+				Type:        "string",
+				Description: "A new TTL counted from now, such as '4h'. Give this or 'extend_by'",
+				Required:    false,
+			},
+		},
+		Metadata: map[string]interface{}{
+			"category": "instances",
+		},
+		Handler: executeExtendTTL,
+	}
+}
+
+// expiringInstance is how an instance with a TTL is reported
+type expiringInstance struct {
+	InstanceId string     `json:"instance_id"`
+	Name       string     `json:"name"`
+	ExpiresAt  time.Time  `json:"expires_at"`
+	ExpiresIn  string     `json:"expires_in"`
+	Expired    bool       `json:"expired"`
+	Action     string     `json:"action"`
+	ReapedAt   *time.Time `json:"reaped_at,omitempty"`
+}
+
+// newExpiringInstance reports entry, filling in the server's policy if it does not have its own action
+func newExpiringInstance(deps *Dependencies, entry instanceTTL, now time.Time) expiringInstance {
+	action := entry.Action
+	if action == "" {
+		action = deps.Config.ReaperPolicy
+	}
+
+	remaining := entry.ExpiresAt.Sub(now)
+	expiresIn := "expired"
+	if remaining > 0 {
+		expiresIn = remaining.Round(time.Minute).String()
+	}
+
+	return expiringInstance{
+		InstanceId: entry.InstanceId,
+		Name:       entry.Name,
+		ExpiresAt:  entry.ExpiresAt,
+		ExpiresIn:  expiresIn,
+		Expired:    remaining <= 0,
+		Action:     action,
+		ReapedAt:   entry.ReapedAt,
+	}
+}
+
+// executeListExpiringInstances implements the list-expiring-instances outcome
+func executeListExpiringInstances(ctx context.Context, parameters map[string]interface{}, deps *Dependencies) (*mcp.CallToolResult, error) {
+	var within time.Duration
+	if value, ok := parameters["within"].(string); ok && value != "" {
+		d, err := parseTTL(value)
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("Invalid 'within' parameter: %v", err)), nil
+		}
+		within = d
+	}
+
+	entries, err := deps.TTLs.All()
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to load instance TTLs: %v", err)), nil
+	}
+
+	now := time.Now()
+	instances := []expiringInstance{}
+	for _, entry := range entries {
+		if within > 0 && entry.ExpiresAt.After(now.Add(within)) {
+			continue
+		}
+		instances = append(instances, newExpiringInstance(deps, entry, now))
+	}
+
+	if len(instances) == 0 {
+		return mcp.NewToolResultText("No expiring instances found."), nil
+	}
+
+	jsonData, err := json.MarshalIndent(instances, "", "  ")
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to serialize results: %v", err)), nil
+	}
+
+	return mcp.NewToolResultText(string(jsonData)), nil
+}
+
+// executeExtendTTL implements the extend-ttl outcome
+func executeExtendTTL(ctx context.Context, parameters map[string]interface{}, deps *Dependencies) (*mcp.CallToolResult, error) {
+	instanceID, ok := parameters["instance_id"].(string)
+	if !ok || instanceID == "" {
+		return mcp.NewToolResultError("instance_id parameter is required"), nil
+	}
+
+	extendBy, _ := parameters["extend_by"].(string)
+	newTTL, _ := parameters["ttl"].(string)
+	if (extendBy == "") == (newTTL == "") {
+		return mcp.NewToolResultError("Give exactly one of 'extend_by' or 'ttl'"), nil
+	}
+
+	var value string
+	if extendBy != "" {
+		value = extendBy
+	} else {
+		value = newTTL
+	}
+	d, err := parseTTL(value)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	entry, found, err := deps.TTLs.Get(instanceID)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to load instance TTLs: %v", err)), nil
+	}
+	if !found {
+		return mcp.NewToolResultError(fmt.Sprintf("Instance %s does not have a TTL. Only instances created with 'ttl' can be extended", instanceID)), nil
+	}
+
+	if dry := dryRunFromContext(ctx); dry != nil {
+		if extendBy != "" {
+			dry.localChange("extend the TTL of instance %s, which expires at %s, by %s", instanceID, entry.ExpiresAt.Format(time.RFC3339), extendBy)
+		} else {
+			dry.localChange("set instance %s to expire %s from now", instanceID, newTTL)
+		}
+		return dry.result(), nil
+	}
+
+	now := time.Now().UTC()
+	err = deps.TTLs.Update(instanceID, func(e *instanceTTL) {
+		if extendBy != "" {
+			// Extending an instance that has already expired counts from now
+			if e.ExpiresAt.Before(now) {
+				e.ExpiresAt = now
+			}
+			e.ExpiresAt = e.ExpiresAt.Add(d)
+		} else {
+			e.ExpiresAt = now.Add(d)
+		}
+		e.ReapedAt = nil
+		entry = *e
+	})
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to save instance TTL: %v", err)), nil
+	}
+
+	jsonData, err := json.MarshalIndent(newExpiringInstance(deps, entry, now), "", "  ")
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to serialize results: %v", err)), nil
+	}
+
+	return mcp.NewToolResultText(string(jsonData)), nil
+}
@@ -0,0 +1,314 @@
+// ttl_store.go holds the local record of when ephemeral instances expire and the
+// background reaper that pauses or deletes them once they have
+
+package server
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/LackOfMorals/mcp4AuraAPI/internal/state"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// The actions the reaper can take on an expired instance
+const (
+	ttlActionPause  = "pause"
+	ttlActionDelete = "delete"
+)
+
+// instanceTTL is the expiry of one ephemeral instance
+type instanceTTL struct {
+	InstanceId string     `json:"instance_id"`
+	Name       string     `json:"name"`
+	CreatedAt  time.Time  `json:"created_at"`
+	ExpiresAt  time.Time  `json:"expires_at"`
+	Action     string     `json:"action"`
+	ReapedAt   *time.Time `json:"reaped_at,omitempty"`
+}
+
+// TTLStore keeps the expiry of ephemeral instances in a local file
+type TTLStore struct {
+	mu      sync.Mutex
+	path    string
+	entries map[string]*instanceTTL
+	loaded  bool
+}
+
+// NewTTLStore creates a store kept in ttl.json in the state directory
+func NewTTLStore(stateDir string) *TTLStore {
+	return &TTLStore{
+		path:    filepath.Join(stateDir, "ttl.json"),
+		entries: make(map[string]*instanceTTL),
+	}
+}
+
+// load reads the file the first time the store is used. Must be called with mu held
+func (t *TTLStore) load() error {
+	if t.loaded {
+		return nil
+	}
+	if err := state.Load(t.path, &t.entries); err != nil {
+		return err
+	}
+	if t.entries == nil {
+		t.entries = make(map[string]*instanceTTL)
+	}
+	t.loaded = true
+	return nil
+}
+
+// Set records the expiry of an instance, replacing any earlier one
+func (t *TTLStore) Set(entry instanceTTL) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if err := t.load(); err != nil {
+		return err
+	}
+	t.entries[entry.InstanceId] = &entry
+	return state.Save(t.path, t.entries)
+}
+
+// Get returns the expiry of an instance, if it has one
+func (t *TTLStore) Get(instanceID string) (instanceTTL, bool, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if err := t.load(); err != nil {
+		return instanceTTL{}, false, err
+	}
+	entry, ok := t.entries[instanceID]
+	if !ok {
+		return instanceTTL{}, false, nil
+	}
+	return *entry, true, nil
+}
+
+// All returns every recorded expiry, soonest first
+func (t *TTLStore) All() ([]instanceTTL, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if err := t.load(); err != nil {
+		return nil, err
+	}
+
+	result := make([]instanceTTL, 0, len(t.entries))
+	for _, entry := range t.entries {
+		result = append(result, *entry)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].ExpiresAt.Before(result[j].ExpiresAt)
+	})
+	return result, nil
+}
+
+// Update changes the recorded expiry of an instance using fn
+func (t *TTLStore) Update(instanceID string, fn func(*instanceTTL)) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if err := t.load(); err != nil {
+		return err
+	}
+	entry, ok := t.entries[instanceID]
+	if !ok {
+		return fmt.Errorf("instance %s does not have a TTL", instanceID)
+	}
+	fn(entry)
+	return state.Save(t.path, t.entries)
+}
+
+// Remove forgets the expiry of an instance
+func (t *TTLStore) Remove(instanceID string) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if err := t.load(); err != nil {
+		return err
+	}
+	if _, ok := t.entries[instanceID]; !ok {
+		return nil
+	}
+	delete(t.entries, instanceID)
+	return state.Save(t.path, t.entries)
+}
+
+// parseTTL parses a duration such as '30m', '4h' or '2d'. Days are not understood by
+// time.ParseDuration so are handled here
+func parseTTL(value string) (time.Duration, error) {
+	if days, found := strings.CutSuffix(value, "d"); found {
+		n, err := strconv.Atoi(days)
+		if err != nil || n <= 0 {
+			return 0, fmt.Errorf("'%s' is not a valid TTL. Use a duration such as '30m', '4h' or '2d'", value)
+		}
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+
+	d, err := time.ParseDuration(value)
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("'%s' is not a valid TTL. Use a duration such as '30m', '4h' or '2d'", value)
+	}
+	return d, nil
+}
+
+// ttlFromParameters reads the ttl and ttl_action parameters of an outcome that creates an instance.
+// A ttl of 0 means the instance does not expire
+func ttlFromParameters(parameters map[string]interface{}) (time.Duration, string, error) {
+	var ttl time.Duration
+	if value, ok := parameters["ttl"].(string); ok && value != "" {
+		var err error
+		ttl, err = parseTTL(value)
+		if err != nil {
+			return 0, "", err
+		}
+	}
+	ttlAction, _ := parameters["ttl_action"].(string)
+	if ttlAction != "" && ttlAction != ttlActionPause && ttlAction != ttlActionDelete {
+		return 0, "", fmt.Errorf("ttl_action must be '%s' or '%s'", ttlActionPause, ttlActionDelete)
+	}
+	return ttl, ttlAction, nil
+}
+
+// recordTTL records that an instance created now expires once ttl has passed, and returns when that is
+func recordTTL(deps *Dependencies, instanceID, name string, ttl time.Duration, action string) (time.Time, error) {
+	now := time.Now().UTC()
+	expiry := now.Add(ttl)
+	err := deps.TTLs.Set(instanceTTL{
+		InstanceId: instanceID,
+		Name:       name,
+		CreatedAt:  now,
+		ExpiresAt:  expiry,
+		Action:     action,
+	})
+	return expiry, err
+}
+
+// runReaper checks for expired instances every interval until ctx is cancelled. An action that
+// has started is always allowed to finish so a deletion is never left half done
+func runReaper(ctx context.Context, deps *Dependencies, interval time.Duration) {
+	slog.Info("Started TTL reaper", "interval", interval, "policy", deps.Config.ReaperPolicy)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		reapExpiredInstances(ctx, deps)
+
+		select {
+		case <-ctx.Done():
+			slog.Info("Stopped TTL reaper")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// reapExpiredInstances pauses or deletes every instance whose TTL has passed
+func reapExpiredInstances(ctx context.Context, deps *Dependencies) {
+	entries, err := deps.TTLs.All()
+	if err != nil {
+		slog.Error("TTL reaper failed to load expiries", "error", err)
+		return
+	}
+
+	now := time.Now()
+	for _, entry := range entries {
+		// Stop between instances, never part way through one
+		if ctx.Err() != nil {
+			return
+		}
+		if entry.ExpiresAt.After(now) || entry.ReapedAt != nil {
+			continue
+		}
+
+		if deps.Config.ReadOnly || deps.Config.DryRun {
+			slog.Warn("Instance TTL has expired but the server is in read-only or dry run mode so it has been left alone", "instance_id", entry.InstanceId, "name", entry.Name, "expired_at", entry.ExpiresAt)
+			continue
+		}
+
+		reapInstance(deps, entry)
+	}
+}
+
+// reapInstance takes the expiry action on one instance through the usual outcomes so that
+// every check they make still applies. A background context is used so that it is not cut
+// short when the server stops. It is run by the server rather than a caller, and a deletion
+// needs no confirmation token, as both were agreed to when the instance was created with a TTL
+func reapInstance(deps *Dependencies, entry instanceTTL) {
+	ctx := withServerCaller(withConfirmation(context.Background()))
+
+	instanceInfo, err := deps.AClient.Instances.Get(entry.InstanceId)
+	if err != nil {
+		if isNotFound(err) {
+			slog.Info("Expired instance no longer exists, forgetting its TTL", "instance_id", entry.InstanceId, "name", entry.Name)
+			if err := deps.TTLs.Remove(entry.InstanceId); err != nil {
+				slog.Error("TTL reaper failed to update expiries", "error", err)
+			}
+			return
+		}
+		slog.Error("TTL reaper failed to retrieve instance", "instance_id", entry.InstanceId, "error", err)
+		return
+	}
+
+	action := entry.Action
+	if action == "" {
+		action = deps.Config.ReaperPolicy
+	}
+
+	switch action {
+	case ttlActionDelete:
+		result, err := deps.OutComes.ExecuteOutcome(ctx, "delete-instance", map[string]interface{}{
+			"instance_id": entry.InstanceId,
+		}, deps)
+		if err != nil || result.IsError {
+			slog.Error("TTL reaper failed to delete expired instance", "instance_id", entry.InstanceId, "name", entry.Name, "error", reaperError(result, err))
+			return
+		}
+		slog.Info("TTL reaper deleted expired instance", "instance_id", entry.InstanceId, "name", entry.Name)
+		if err := deps.TTLs.Remove(entry.InstanceId); err != nil {
+			slog.Error("TTL reaper failed to update expiries", "error", err)
+		}
+
+	default:
+		switch instanceInfo.Data.Status {
+		case "paused":
+			// Already paused, nothing to do
+		case "running":
+			result, err := deps.OutComes.ExecuteOutcome(ctx, "pause-instance", map[string]interface{}{
+				"instance_id": entry.InstanceId,
+			}, deps)
+			if err != nil || result.IsError {
+				slog.Error("TTL reaper failed to pause expired instance", "instance_id", entry.InstanceId, "name", entry.Name, "error", reaperError(result, err))
+				return
+			}
+			slog.Info("TTL reaper paused expired instance", "instance_id", entry.InstanceId, "name", entry.Name)
+		default:
+			// Wait for it to settle and try again next time
+			return
+		}
+		err := deps.TTLs.Update(entry.InstanceId, func(e *instanceTTL) {
+			now := time.Now().UTC()
+			e.ReapedAt = &now
+		})
+		if err != nil {
+			slog.Error("TTL reaper failed to update expiries", "error", err)
+		}
+	}
+}
+
+// reaperError returns the reason an outcome run by the reaper failed
+func reaperError(result *mcp.CallToolResult, err error) string {
+	if err != nil {
+		return err.Error()
+	}
+	return toolResultText(result)
+}
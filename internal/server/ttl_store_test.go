@@ -0,0 +1,77 @@
+package server
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	aura "github.com/LackOfMorals/aura-client"
+	"github.com/LackOfMorals/mcp4AuraAPI/internal/config"
+)
+
+func TestReapExpiredInstances(t *testing.T) {
+	expired := time.Now().Add(-time.Minute)
+	reaped := time.Now().Add(-time.Second)
+
+	tests := []struct {
+		name        string
+		status      string // Status of instance a1. Empty if it no longer exists
+		entry       instanceTTL
+		policy      string
+		readOnly    bool
+		protected   bool // a1 is protected by the protection file
+		noAccess    bool // Access control gives the caller no roles at all
+		wantChanges []string
+		wantTTL     bool // The TTL of a1 is still recorded
+		wantReaped  bool // The TTL of a1 is marked as reaped
+	}{
+		{name: "not expired", status: "running", entry: instanceTTL{ExpiresAt: time.Now().Add(time.Hour)}, policy: ttlActionDelete, wantTTL: true},
+		{name: "deleted by policy", status: "running", entry: instanceTTL{ExpiresAt: expired}, policy: ttlActionDelete, wantChanges: []string{"DELETE a1"}},
+		{name: "deleted by its own action", status: "running", entry: instanceTTL{ExpiresAt: expired, Action: ttlActionDelete}, policy: ttlActionPause, wantChanges: []string{"DELETE a1"}},
+		{name: "paused", status: "running", entry: instanceTTL{ExpiresAt: expired}, policy: ttlActionPause, wantChanges: []string{"PAUSE a1"}, wantTTL: true, wantReaped: true},
+		{name: "already paused", status: "paused", entry: instanceTTL{ExpiresAt: expired}, policy: ttlActionPause, wantTTL: true, wantReaped: true},
+		{name: "still starting", status: "creating", entry: instanceTTL{ExpiresAt: expired}, policy: ttlActionPause, wantTTL: true},
+		{name: "already reaped", status: "running", entry: instanceTTL{ExpiresAt: expired, ReapedAt: &reaped}, policy: ttlActionPause, wantTTL: true, wantReaped: true},
+		{name: "no longer exists", entry: instanceTTL{ExpiresAt: expired}, policy: ttlActionDelete},
+		{name: "read-only", status: "running", entry: instanceTTL{ExpiresAt: expired}, policy: ttlActionDelete, readOnly: true, wantTTL: true},
+		{name: "protected", status: "running", entry: instanceTTL{ExpiresAt: expired}, policy: ttlActionDelete, protected: true, wantTTL: true},
+		{name: "not limited by access control", status: "running", entry: instanceTTL{ExpiresAt: expired}, policy: ttlActionDelete, noAccess: true, wantChanges: []string{"DELETE a1"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			instances := newFakeInstances()
+			if tt.status != "" {
+				instances = newFakeInstances(fakeInstance{GetInstanceData: aura.GetInstanceData{Id: "a1", Name: "temp", Status: tt.status, TenantId: "t1"}})
+			}
+			deps := newTestDependencies(t, instances)
+			deps.Config.ReaperPolicy = tt.policy
+			deps.Config.ReadOnly = tt.readOnly
+			if tt.protected {
+				deps.Config.Protection = config.DeletionProtection{InstanceIds: []string{"a1"}}
+			}
+			if tt.noAccess {
+				deps.Config.AccessControl = &config.AccessControl{Roles: map[string]config.Role{}, Identities: map[string][]string{}}
+			}
+			entry := tt.entry
+			entry.InstanceId, entry.Name = "a1", "temp"
+			if err := deps.TTLs.Set(entry); err != nil {
+				t.Fatal(err)
+			}
+
+			reapExpiredInstances(context.Background(), deps)
+
+			if strings.Join(instances.Changes(), ",") != strings.Join(tt.wantChanges, ",") {
+				t.Fatalf("changes = %v, want %v", instances.Changes(), tt.wantChanges)
+			}
+			got, ok, err := deps.TTLs.Get("a1")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if ok != tt.wantTTL || (got.ReapedAt != nil) != tt.wantReaped {
+				t.Fatalf("TTL = %+v, recorded %t, want recorded %t and reaped %t", got, ok, tt.wantTTL, tt.wantReaped)
+			}
+		})
+	}
+}